@@ -0,0 +1,162 @@
+package horus
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ethanbaker/horus/utils/types"
+	openai "github.com/sashabaranov/go-openai"
+	"gorm.io/gorm"
+)
+
+// Attachment is a file sent with a message. Every attachment is kept with its message, so images
+// can be given to the model again when the conversation is loaded
+type Attachment struct {
+	gorm.Model
+
+	MessageID   uint   // The message the file was sent with
+	Filename    string // The file's name
+	ContentType string // The file's MIME type
+	Kind        string // How the file is given to the model (text, image or file)
+	Size        int    // The file's size in bytes
+	Content     []byte // The file's content (empty if the file was too large to keep)
+}
+
+// newAttachments classifies the files sent with an input. Images are only given to the model as
+// images if it accepts them, otherwise they are referenced like other files
+func newAttachments(files []types.Attachment, images bool) []Attachment {
+	attachments := []Attachment{}
+	for _, f := range files {
+		a := Attachment{
+			Filename:    f.Filename,
+			ContentType: f.ContentType,
+			Size:        len(f.Content),
+			Content:     f.Content,
+		}
+		if f.Size > a.Size {
+			a.Size = f.Size
+		}
+		if a.ContentType == "" {
+			a.ContentType = http.DetectContentType(f.Content)
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(a.ContentType)
+		switch {
+		case a.Size > ATTACHMENT_MAX_SIZE:
+			a.Kind = ATTACHMENT_FILE
+			a.Content = nil
+
+		case images && isAllowed(IMAGE_TYPES, mediaType):
+			a.Kind = ATTACHMENT_IMAGE
+
+		case (strings.HasPrefix(mediaType, "text/") || isAllowed(TEXT_TYPES, mediaType)) && utf8.Valid(f.Content):
+			a.Kind = ATTACHMENT_TEXT
+
+		default:
+			a.Kind = ATTACHMENT_FILE
+		}
+
+		attachments = append(attachments, a)
+	}
+
+	return attachments
+}
+
+// withAttachments adds attachments to a message's content. Text files are inlined and other
+// files are referenced, while images are left for the message's content parts
+func withAttachments(content string, attachments []Attachment) string {
+	var sb strings.Builder
+	sb.WriteString(content)
+
+	for _, a := range attachments {
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+
+		switch {
+		case a.Kind == ATTACHMENT_IMAGE:
+			fmt.Fprintf(&sb, "[Attached image: %v]", a.Filename)
+
+		case a.Kind == ATTACHMENT_TEXT:
+			text := string(a.Content)
+			if runes := []rune(text); len(runes) > ATTACHMENT_MAX_INLINE {
+				text = string(runes[:ATTACHMENT_MAX_INLINE]) + "\n... (truncated)"
+			}
+			fmt.Fprintf(&sb, "[Attached file: %v]\n```\n%v\n```", a.Filename, strings.TrimRight(text, "\n"))
+
+		case a.Size > ATTACHMENT_MAX_SIZE:
+			fmt.Fprintf(&sb, "[Attached file: %v (%v, %v bytes), too large to keep]", a.Filename, a.ContentType, a.Size)
+
+		default:
+			fmt.Fprintf(&sb, "[Attached file: %v (%v, %v bytes), kept with the message but its content can't be read]", a.Filename, a.ContentType, a.Size)
+		}
+	}
+
+	return sb.String()
+}
+
+// supportsImages returns whether a model accepts images
+func supportsImages(model string) bool {
+	for _, prefix := range VISION_MODELS {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// imageParts returns the content parts of a message with image attachments, or nil if it has none
+func imageParts(content string, attachments []Attachment) []openai.ChatMessagePart {
+	parts := []openai.ChatMessagePart{{Type: openai.ChatMessagePartTypeText, Text: content}}
+	for _, a := range attachments {
+		if a.Kind != ATTACHMENT_IMAGE {
+			continue
+		}
+
+		parts = append(parts, openai.ChatMessagePart{
+			Type: openai.ChatMessagePartTypeImageURL,
+			ImageURL: &openai.ChatMessageImageURL{
+				URL: "data:" + a.ContentType + ";base64," + base64.StdEncoding.EncodeToString(a.Content),
+			},
+		})
+	}
+
+	if len(parts) == 1 {
+		return nil
+	}
+	return parts
+}
+
+// loadContent loads the content of attachments of the given kinds. Bots are loaded without their
+// attachments' content, so it is only read when a message needs it
+func loadContent(attachments []Attachment, kinds ...string) error {
+	for i := range attachments {
+		a := &attachments[i]
+		if a.Content != nil || a.Model.ID == 0 || !isAllowed(kinds, a.Kind) {
+			continue
+		}
+
+		stored := Attachment{}
+		if err := db.Select("content").First(&stored, a.Model.ID).Error; err != nil {
+			return err
+		}
+		a.Content = stored.Content
+	}
+
+	return nil
+}
+
+// copyAttachments copies attachments so they can be saved with another message
+func copyAttachments(attachments []Attachment) []Attachment {
+	copies := []Attachment{}
+	for _, a := range attachments {
+		a.Model = gorm.Model{}
+		a.MessageID = 0
+		copies = append(copies, a)
+	}
+	return copies
+}
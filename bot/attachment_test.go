@@ -0,0 +1,139 @@
+package horus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethanbaker/horus/utils/types"
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+// png is the start of a PNG file, enough for its type to be detected
+var png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestNewAttachments(t *testing.T) {
+	assert := assert.New(t)
+
+	files := []types.Attachment{
+		{Filename: "notes.txt", ContentType: "text/plain; charset=utf-8", Content: []byte("hello")},
+		{Filename: "config.json", ContentType: "application/json", Content: []byte(`{"a": 1}`)},
+		{Filename: "photo.png", Content: png},
+		{Filename: "broken.txt", ContentType: "text/plain", Content: []byte{0xff, 0xfe, 0xfd}},
+		{Filename: "report.pdf", ContentType: "application/pdf", Content: []byte("%PDF-1.4")},
+		{Filename: "video.mp4", ContentType: "video/mp4", Size: ATTACHMENT_MAX_SIZE + 1},
+	}
+
+	// Files are classified by their type, with types detected when they're missing
+	attachments := newAttachments(files, true)
+	assert.Len(attachments, len(files))
+	assert.Equal(ATTACHMENT_TEXT, attachments[0].Kind)
+	assert.Equal(ATTACHMENT_TEXT, attachments[1].Kind)
+	assert.Equal(ATTACHMENT_IMAGE, attachments[2].Kind)
+	assert.Equal("image/png", attachments[2].ContentType)
+	assert.Equal(len(png), attachments[2].Size)
+
+	// Text files that aren't valid UTF-8 and other types are kept as files
+	assert.Equal(ATTACHMENT_FILE, attachments[3].Kind)
+	assert.Equal(ATTACHMENT_FILE, attachments[4].Kind)
+	assert.Equal([]byte("%PDF-1.4"), attachments[4].Content)
+
+	// Files that are too large are referenced without their content
+	assert.Equal(ATTACHMENT_FILE, attachments[5].Kind)
+	assert.Equal(ATTACHMENT_MAX_SIZE+1, attachments[5].Size)
+	assert.Nil(attachments[5].Content)
+
+	// Images are kept as files for models that don't accept them
+	attachments = newAttachments(files, false)
+	assert.Equal(ATTACHMENT_FILE, attachments[2].Kind)
+	assert.Equal(png, attachments[2].Content)
+	assert.Equal(ATTACHMENT_TEXT, attachments[0].Kind)
+}
+
+func TestWithAttachments(t *testing.T) {
+	assert := assert.New(t)
+
+	attachments := []Attachment{
+		{Filename: "notes.txt", ContentType: "text/plain", Kind: ATTACHMENT_TEXT, Size: 6, Content: []byte("hello\n")},
+		{Filename: "photo.png", ContentType: "image/png", Kind: ATTACHMENT_IMAGE, Size: len(png), Content: png},
+		{Filename: "report.pdf", ContentType: "application/pdf", Kind: ATTACHMENT_FILE, Size: 8, Content: []byte("%PDF-1.4")},
+		{Filename: "video.mp4", ContentType: "video/mp4", Kind: ATTACHMENT_FILE, Size: ATTACHMENT_MAX_SIZE + 1},
+	}
+
+	// Text files are inlined, images are named and other files are described
+	assert.Equal("Look at these\n\n"+
+		"[Attached file: notes.txt]\n```\nhello\n```\n\n"+
+		"[Attached image: photo.png]\n\n"+
+		"[Attached file: report.pdf (application/pdf, 8 bytes), kept with the message but its content can't be read]\n\n"+
+		"[Attached file: video.mp4 (video/mp4, 8388609 bytes), too large to keep]",
+		withAttachments("Look at these", attachments))
+
+	// Messages without content start with their attachments
+	assert.Equal("[Attached image: photo.png]", withAttachments("", attachments[1:2]))
+	assert.Equal("hello", withAttachments("hello", nil))
+
+	// Long text files are truncated on runes
+	long := []Attachment{{Filename: "long.txt", Kind: ATTACHMENT_TEXT, Content: []byte(strings.Repeat("é", ATTACHMENT_MAX_INLINE+10))}}
+	assert.Equal("[Attached file: long.txt]\n```\n"+strings.Repeat("é", ATTACHMENT_MAX_INLINE)+"\n... (truncated)\n```", withAttachments("", long))
+}
+
+func TestImageParts(t *testing.T) {
+	assert := assert.New(t)
+
+	// Messages without images have no content parts
+	assert.Nil(imageParts("hello", nil))
+	assert.Nil(imageParts("hello", []Attachment{{Filename: "notes.txt", Kind: ATTACHMENT_TEXT, Content: []byte("hi")}}))
+
+	// Images follow the message's text as data URLs
+	parts := imageParts("hello", []Attachment{
+		{Filename: "notes.txt", Kind: ATTACHMENT_TEXT, Content: []byte("hi")},
+		{Filename: "photo.png", ContentType: "image/png", Kind: ATTACHMENT_IMAGE, Content: []byte("image")},
+	})
+	assert.Equal([]openai.ChatMessagePart{
+		{Type: openai.ChatMessagePartTypeText, Text: "hello"},
+		{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "data:image/png;base64,aW1hZ2U="}},
+	}, parts)
+
+	assert.True(supportsImages("gpt-4o-mini"))
+	assert.False(supportsImages(openai.GPT3Dot5Turbo))
+}
+
+func TestAttachmentsLoadedLazily(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBot(t)
+	b.Setup(newFakeModel(t, func(request openai.ChatCompletionRequest) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "Nice picture"}
+	}))
+	assert.Nil(b.AddConversation("main"))
+	b.getConversation("main").request.Model = "gpt-4o"
+
+	_, err := b.SendMessage("main", &types.Input{
+		Message:     "Look at this",
+		Permissions: PERMISSIONS_ALL,
+		Attachments: []types.Attachment{{Filename: "photo.png", Content: png}, {Filename: "notes.txt", Content: []byte("hello")}},
+	})
+	assert.Nil(err)
+
+	// Loaded bots don't read their attachments' content
+	loaded, err := GetBotByName("horus-test")
+	assert.Nil(err)
+	message := loaded.getConversation("main").Messages[1]
+	assert.Len(message.Attachments, 2)
+	for _, a := range message.Attachments {
+		assert.Nil(a.Content)
+	}
+
+	// Images are read when their message is given to the model again
+	loaded.Setup(b.client)
+	request := loaded.getConversation("main").request
+	assert.Equal(imageParts(message.Content, newAttachments([]types.Attachment{{Filename: "photo.png", Content: png}}, true)), request.Messages[1].MultiContent)
+	assert.Nil(message.Attachments[1].Content)
+
+	// Forked conversations copy every attachment's content
+	assert.Nil(loaded.ForkConversation("main", "copy"))
+	copied := []Attachment{}
+	assert.Nil(db.Joins("JOIN messages ON messages.id = attachments.message_id").Where("messages.conversation_id = ?", loaded.getConversation("copy").Model.ID).Find(&copied).Error)
+	assert.Len(copied, 2)
+	assert.Equal(png, copied[0].Content)
+	assert.Equal([]byte("hello"), copied[1].Content)
+}
@@ -0,0 +1,220 @@
+package horus
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/types"
+	"gorm.io/gorm"
+)
+
+// Argument keys that have their values redacted in the audit log
+var auditSecretKeys = regexp.MustCompile(`(?i)pass(word|wd|phrase)?|secret|token|api[_-]?key|credential|private`)
+
+// ToolAudit is a record of a single tool execution
+type ToolAudit struct {
+	gorm.Model
+
+	BotID        uint          // The bot that executed the tool
+	Conversation string        // The conversation the tool was executed in
+	ToolCallID   string        // The ID of the model's tool call (empty for queued functions)
+	Module       string        // The module that handled the call
+	Function     string        // The name of the function that was called
+	Arguments    string        // The call's arguments with secrets redacted
+	ResultSize   int           // The size of the result in bytes
+	Error        string        // The error returned by the tool, if any
+	Latency      time.Duration // How long the tool took to run
+	Caller       string        // Who sent the input that triggered the call
+	Decision     string        // The user's decision for side-effecting tools (CONFIRMATION_APPROVED or CONFIRMATION_DENIED)
+}
+
+// AuditQuery filters tool audit records
+type AuditQuery struct {
+	BotID        uint      // Only return records for this bot (0 returns every bot)
+	Conversation string    // Only return records from this conversation
+	Module       string    // Only return records handled by this module
+	Function     string    // Only return records for this function
+	Caller       string    // Only return records triggered by this caller
+	ErrorsOnly   bool      // Only return records where the tool returned an error
+	After        time.Time // Only return records created after this time
+	Before       time.Time // Only return records created before this time
+	Limit        int       // The maximum amount of records to return (0 returns every record)
+}
+
+// ToolAudits returns the bot's audit records, newest first. The query's BotID is always set to this bot
+func (b *Bot) ToolAudits(query AuditQuery) ([]ToolAudit, error) {
+	query.BotID = b.Model.ID
+	return GetToolAudits(query)
+}
+
+// SetAuditRetention sets how long tool audit records are kept (0 keeps them forever)
+func (b *Bot) SetAuditRetention(retention time.Duration) {
+	b.auditRetention = retention
+}
+
+// PruneToolAudits deletes the bot's audit records that are older than the audit retention
+func (b *Bot) PruneToolAudits() error {
+	if b.auditRetention <= 0 {
+		return nil
+	}
+
+	cutoff := time.Now().UTC().Add(-b.auditRetention)
+	return db.Unscoped().Where("bot_id = ? AND created_at < ?", b.Model.ID, cutoff).Delete(&ToolAudit{}).Error
+}
+
+// GetToolAudits returns audit records matching a query, newest first
+func GetToolAudits(query AuditQuery) ([]ToolAudit, error) {
+	audits := []ToolAudit{}
+
+	tx := db.Model(&ToolAudit{})
+	if query.BotID != 0 {
+		tx = tx.Where("bot_id = ?", query.BotID)
+	}
+	if query.Conversation != "" {
+		tx = tx.Where("conversation = ?", query.Conversation)
+	}
+	if query.Module != "" {
+		tx = tx.Where("module = ?", query.Module)
+	}
+	if query.Function != "" {
+		tx = tx.Where("function = ?", query.Function)
+	}
+	if query.Caller != "" {
+		tx = tx.Where("caller = ?", query.Caller)
+	}
+	if query.ErrorsOnly {
+		tx = tx.Where("error <> ''")
+	}
+	if !query.After.IsZero() {
+		tx = tx.Where("created_at >= ?", query.After)
+	}
+	if !query.Before.IsZero() {
+		tx = tx.Where("created_at <= ?", query.Before)
+	}
+	if query.Limit > 0 {
+		tx = tx.Limit(query.Limit)
+	}
+
+	return audits, tx.Order("created_at DESC").Find(&audits).Error
+}
+
+// auditTool records the execution of a tool called by the model
+func (b *Bot) auditTool(conversation string, callID string, function string, arguments string, input *types.Input, result any, latency time.Duration, decision string) error {
+	audit := ToolAudit{
+		BotID:        b.Model.ID,
+		Conversation: conversation,
+		ToolCallID:   callID,
+		Module:       b.moduleOf(function),
+		Function:     function,
+		Arguments:    redactArguments(arguments),
+		Latency:      latency,
+		Caller:       input.Caller,
+		Decision:     decision,
+	}
+	audit.ResultSize, audit.Error = describeResult(result)
+
+	return db.Create(&audit).Error
+}
+
+// auditQueuedFunction records the execution of a queued function. The user's message is never
+// recorded since queued functions often collect secrets (such as passwords)
+func (b *Bot) auditQueuedFunction(conversation string, f func(bot *Bot, input *types.Input) *types.Output, input *types.Input, result *types.Output, latency time.Duration) error {
+	// Find the name of the queued function
+	name := runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+	module := "queued"
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i != -1 {
+		module, name = name[:i], name[i+1:]
+	}
+
+	audit := ToolAudit{
+		BotID:        b.Model.ID,
+		Conversation: conversation,
+		Module:       module,
+		Function:     name,
+		Arguments:    `"[REDACTED]"`,
+		Latency:      latency,
+		Caller:       input.Caller,
+	}
+	audit.ResultSize, audit.Error = describeResult(result)
+
+	return db.Create(&audit).Error
+}
+
+// moduleOf finds the name of the module a function was registered by
+func (b *Bot) moduleOf(function string) string {
+	for key, def := range b.functionDefinitions {
+		if def.Name == function {
+			module, _, _ := strings.Cut(key, "-")
+			return module
+		}
+	}
+
+	return ""
+}
+
+// describeResult returns the size of a tool's result and the error it reported, if any
+func describeResult(result any) (int, string) {
+	switch val := result.(type) {
+	case nil:
+		return 0, "no module handled the function"
+
+	case error:
+		return len(val.Error()), val.Error()
+
+	case *types.Output:
+		if val.Error != nil {
+			return len(val.Message), val.Error.Error()
+		}
+		return len(val.Message), ""
+	}
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return 0, err.Error()
+	}
+	return len(raw), ""
+}
+
+// redactArguments replaces the values of secret-looking keys in a JSON argument string
+func redactArguments(arguments string) string {
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(arguments), &parsed); err != nil || parsed == nil {
+		// Arguments that are not a JSON object have no keys to tell secrets apart, so they
+		// cannot be safely redacted
+		return `"[REDACTED]"`
+	}
+
+	raw, err := json.Marshal(redactValue(parsed))
+	if err != nil {
+		return `"[REDACTED]"`
+	}
+	return string(raw)
+}
+
+// redactValue recursively redacts secret-looking keys in a parsed JSON value
+func redactValue(value any) any {
+	switch val := value.(type) {
+	case map[string]any:
+		for k, v := range val {
+			if auditSecretKeys.MatchString(k) {
+				val[k] = "[REDACTED]"
+			} else {
+				val[k] = redactValue(v)
+			}
+		}
+
+	case []any:
+		for i := range val {
+			val[i] = redactValue(val[i])
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,192 @@
+package horus
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactArguments(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		arguments string
+		expected  string
+	}{
+		// Secret-looking keys are redacted in any case
+		{`{"password": "hunter2", "name": "github"}`, `{"name":"github","password":"[REDACTED]"}`},
+		{`{"passwd": "hunter2"}`, `{"passwd":"[REDACTED]"}`},
+		{`{"api_key": "sk-1", "apiKey": "sk-2", "API-KEY": "sk-3"}`, `{"API-KEY":"[REDACTED]","apiKey":"[REDACTED]","api_key":"[REDACTED]"}`},
+		{`{"token": "abc", "refreshToken": "def"}`, `{"refreshToken":"[REDACTED]","token":"[REDACTED]"}`},
+		{`{"private_key": "-----BEGIN-----"}`, `{"private_key":"[REDACTED]"}`},
+
+		// Secrets are redacted in nested objects and arrays, and whole secret objects are redacted
+		{`{"entry": {"title": "mail", "password": "hunter2"}}`, `{"entry":{"password":"[REDACTED]","title":"mail"}}`},
+		{`{"entries": [{"password": "a"}, {"password": "b", "tags": ["x"]}]}`, `{"entries":[{"password":"[REDACTED]"},{"password":"[REDACTED]","tags":["x"]}]}`},
+		{`{"credentials": {"user": "me", "pin": 1234}}`, `{"credentials":"[REDACTED]"}`},
+
+		// Arguments without secrets are kept
+		{`{"location": "Paris", "days": 3, "metric": true, "extra": null}`, `{"days":3,"extra":null,"location":"Paris","metric":true}`},
+		{`{}`, `{}`},
+
+		// Arguments that aren't a JSON object are never stored
+		{`password=hunter2`, `"[REDACTED]"`},
+		{`{"password": "hunter2"`, `"[REDACTED]"`},
+		{`"my password is hunter2"`, `"[REDACTED]"`},
+		{`["hunter2"]`, `"[REDACTED]"`},
+		{`null`, `"[REDACTED]"`},
+		{``, `"[REDACTED]"`},
+	}
+
+	for _, test := range tests {
+		assert.Equal(test.expected, redactArguments(test.arguments), test.arguments)
+	}
+}
+
+func TestRedactValue(t *testing.T) {
+	assert := assert.New(t)
+
+	// Values are redacted in place at any depth
+	value := map[string]any{
+		"list": []any{
+			[]any{map[string]any{"secret": "a", "id": float64(1)}},
+			"plain",
+		},
+	}
+	assert.Equal(map[string]any{
+		"list": []any{
+			[]any{map[string]any{"secret": "[REDACTED]", "id": float64(1)}},
+			"plain",
+		},
+	}, redactValue(value))
+
+	// Values without keys are kept
+	assert.Equal("hunter2", redactValue("hunter2"))
+	assert.Nil(redactValue(nil))
+}
+
+func TestAuditToolRedacts(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBot(t)
+
+	// Stored records never contain secrets from the arguments
+	input := &types.Input{Caller: "user"}
+	assert.Nil(b.auditTool("main", "call-1", "add_entry", `{"title": "mail", "password": "hunter2"}`, input, "ok", 0, ""))
+	assert.Nil(b.auditTool("main", "call-2", "add_entry", `title=mail password=hunter2`, input, "ok", 0, ""))
+
+	audits, err := b.ToolAudits(AuditQuery{})
+	assert.Nil(err)
+	assert.Len(audits, 2)
+	for _, audit := range audits {
+		assert.NotContains(audit.Arguments, "hunter2")
+	}
+}
+
+// auditAt records a tool execution and dates it
+func auditAt(t *testing.T, b *Bot, conversation string, function string, caller string, result any, age time.Duration) {
+	t.Helper()
+
+	callID := fmt.Sprintf("%v-%v-%v", b.Name, function, age)
+	if err := b.auditTool(conversation, callID, function, `{}`, &types.Input{Caller: caller}, result, 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&ToolAudit{}).Where("tool_call_id = ?", callID).Update("created_at", time.Now().UTC().Add(-age)).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestToolAudits(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBot(t)
+	addTestModule(b, PERMISSIONS_PRVMODULES)
+	other, err := NewBot("horus-other", PERMISSIONS_ALL)
+	assert.Nil(err)
+
+	auditAt(t, b, "main", "set_value", "alice", "ok", 3*time.Hour)
+	auditAt(t, b, "main", "get_value", "bob", errors.New("failed"), 2*time.Hour)
+	auditAt(t, b, "thread", "set_value", "bob", "ok", time.Hour)
+	auditAt(t, other, "main", "set_value", "alice", "ok", 0)
+
+	now := time.Now().UTC()
+	tests := []struct {
+		name     string
+		query    AuditQuery
+		expected []string // The expected call IDs, newest first
+	}{
+		{"all", AuditQuery{}, []string{"horus-other-set_value-0s", "horus-test-set_value-1h0m0s", "horus-test-get_value-2h0m0s", "horus-test-set_value-3h0m0s"}},
+		{"bot", AuditQuery{BotID: other.Model.ID}, []string{"horus-other-set_value-0s"}},
+		{"conversation", AuditQuery{BotID: b.Model.ID, Conversation: "main"}, []string{"horus-test-get_value-2h0m0s", "horus-test-set_value-3h0m0s"}},
+		{"function", AuditQuery{Function: "get_value"}, []string{"horus-test-get_value-2h0m0s"}},
+		{"module", AuditQuery{Module: "test"}, []string{"horus-test-set_value-1h0m0s", "horus-test-set_value-3h0m0s"}},
+		{"caller", AuditQuery{BotID: b.Model.ID, Caller: "alice"}, []string{"horus-test-set_value-3h0m0s"}},
+		{"errors", AuditQuery{ErrorsOnly: true}, []string{"horus-test-get_value-2h0m0s"}},
+		{"after", AuditQuery{BotID: b.Model.ID, After: now.Add(-150 * time.Minute)}, []string{"horus-test-set_value-1h0m0s", "horus-test-get_value-2h0m0s"}},
+		{"before", AuditQuery{Before: now.Add(-90 * time.Minute)}, []string{"horus-test-get_value-2h0m0s", "horus-test-set_value-3h0m0s"}},
+		{"range", AuditQuery{After: now.Add(-150 * time.Minute), Before: now.Add(-90 * time.Minute)}, []string{"horus-test-get_value-2h0m0s"}},
+		{"limit", AuditQuery{Limit: 2}, []string{"horus-other-set_value-0s", "horus-test-set_value-1h0m0s"}},
+		{"combined", AuditQuery{Function: "set_value", Caller: "bob", Limit: 5}, []string{"horus-test-set_value-1h0m0s"}},
+		{"none", AuditQuery{Function: "missing"}, []string{}},
+	}
+
+	for _, test := range tests {
+		audits, err := GetToolAudits(test.query)
+		assert.Nil(err, test.name)
+
+		ids := []string{}
+		for _, audit := range audits {
+			ids = append(ids, audit.ToolCallID)
+		}
+		assert.Equal(test.expected, ids, test.name)
+	}
+
+	// Bots only see their own records
+	audits, err := other.ToolAudits(AuditQuery{BotID: b.Model.ID})
+	assert.Nil(err)
+	assert.Len(audits, 1)
+	assert.Equal(other.Model.ID, audits[0].BotID)
+
+	// Records describe the call
+	audits, err = b.ToolAudits(AuditQuery{Function: "get_value"})
+	assert.Nil(err)
+	assert.Equal("failed", audits[0].Error)
+	assert.Equal("", audits[0].Module)
+	assert.Equal(len("failed"), audits[0].ResultSize)
+}
+
+func TestPruneToolAudits(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBot(t)
+	other, err := NewBot("horus-other", PERMISSIONS_ALL)
+	assert.Nil(err)
+
+	auditAt(t, b, "main", "set_value", "alice", "ok", 48*time.Hour)
+	auditAt(t, b, "main", "set_value", "alice", "ok", time.Hour)
+	auditAt(t, other, "main", "set_value", "alice", "ok", 48*time.Hour)
+
+	// Without a retention, records are kept forever
+	assert.Nil(b.PruneToolAudits())
+	audits, err := GetToolAudits(AuditQuery{})
+	assert.Nil(err)
+	assert.Len(audits, 3)
+
+	// Records past the retention are deleted for good, and only for the bot
+	b.SetAuditRetention(24 * time.Hour)
+	assert.Nil(b.PruneToolAudits())
+
+	audits, err = b.ToolAudits(AuditQuery{})
+	assert.Nil(err)
+	assert.Len(audits, 1)
+	assert.Equal("horus-test-set_value-1h0m0s", audits[0].ToolCallID)
+
+	audits, err = other.ToolAudits(AuditQuery{})
+	assert.Nil(err)
+	assert.Len(audits, 1)
+
+	var count int64
+	assert.Nil(db.Unscoped().Model(&ToolAudit{}).Where("bot_id = ?", b.Model.ID).Count(&count).Error)
+	assert.Equal(int64(1), count)
+}
@@ -3,7 +3,11 @@ package horus
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/ethanbaker/horus/utils/schema"
 	"github.com/ethanbaker/horus/utils/types"
 	openai "github.com/sashabaranov/go-openai"
 	"github.com/stretchr/objx"
@@ -23,10 +27,13 @@ type Bot struct {
 	client              *openai.Client                                  `gorm:"-"` // The OpenAI client
 	functionDefinitions map[string]openai.FunctionDefinition            `gorm:"-"` // Function definitions to plug into GPT prompts
 	handlers            []func(function string, input *types.Input) any `gorm:"-"` // A list of handlers from associated modules
+	sideEffects         map[string]bool                                 `gorm:"-"` // Functions that need the user's confirmation before they run
 
 	// Dynamic variables (can change after creation)
-	functionQueue []func(bot *Bot, input *types.Input) *types.Output `gorm:"-"` // Incoming functions to run instead of delegating to OpenAI
-	variables     map[string]any                                     `gorm:"-"` // Any variables used by functions
+	functionQueue  []func(bot *Bot, input *types.Input) *types.Output `gorm:"-"` // Incoming functions to run instead of delegating to OpenAI
+	variables      map[string]any                                     `gorm:"-"` // Any variables used by functions
+	sessionPolicy  SessionPolicy                                      `gorm:"-"` // How channel sessions are resolved and cleaned up
+	auditRetention time.Duration                                      `gorm:"-"` // How long tool audit records are kept
 }
 
 // AddConversation adds a new conversation to the bot
@@ -72,6 +79,70 @@ func (b *Bot) DeleteConversation(key string) error {
 	return fmt.Errorf("conversation with given key does not exist")
 }
 
+// RenameConversation changes the key of a conversation. Sessions using the conversation keep
+// using it under its new key
+func (b *Bot) RenameConversation(key string, newKey string) error {
+	conversation := b.getConversation(key)
+	if conversation == nil {
+		return fmt.Errorf("conversation with key '%s' does not exist", key)
+	}
+	if newKey == "" {
+		return fmt.Errorf("conversation key cannot be empty")
+	}
+	if b.IsConversation(newKey) {
+		return fmt.Errorf("cannot rename conversation to duplicate key '%s'", newKey)
+	}
+
+	if err := db.Model(conversation).Update("name", newKey).Error; err != nil {
+		return err
+	}
+	conversation.Name = newKey
+
+	return db.Model(&Session{}).Where(map[string]any{"bot_id": b.Model.ID, "conversation": key}).Update("conversation", newKey).Error
+}
+
+// ForkConversation copies a conversation and all of its messages into a new conversation, so
+// the copy can continue independently of the original
+func (b *Bot) ForkConversation(key string, newKey string) error {
+	original := b.getConversation(key)
+	if original == nil {
+		return fmt.Errorf("conversation with key '%s' does not exist", key)
+	}
+	if newKey == "" {
+		return fmt.Errorf("conversation key cannot be empty")
+	}
+	if b.IsConversation(newKey) {
+		return fmt.Errorf("cannot add conversation with duplicate key '%s'", newKey)
+	}
+
+	// Create the copy without the default setup message since it is copied from the original
+	c := Conversation{BotID: b.Model.ID, Name: newKey}
+	if err := db.Create(&c).Error; err != nil {
+		return err
+	}
+
+	for _, m := range original.Messages {
+		if err := loadContent(m.Attachments); err != nil {
+			return err
+		}
+		ccm := m.chatCompletionMessage()
+
+		copied, err := newMessage(c.Model.ID, uint(len(c.Messages)), &ccm, copyAttachments(m.Attachments)...)
+		if err != nil {
+			return err
+		}
+		if err := c.appendMessage(copied); err != nil {
+			return err
+		}
+	}
+	c.setup(b.client, &b.functionDefinitions)
+
+	// Add the conversation to the bot
+	b.Conversations = append(b.Conversations, c)
+
+	return db.Save(&b).Error
+}
+
 // IsConversation returns true if the conversation exists
 func (b *Bot) IsConversation(key string) bool {
 	for _, c := range b.Conversations {
@@ -87,36 +158,56 @@ func (b *Bot) IsConversation(key string) bool {
 func (b *Bot) SendMessage(key string, input *types.Input) (*types.Output, error) {
 	output := types.Output{}
 
+	// Inputs can only use the permissions the bot has
 	input.Permissions = input.Permissions & b.Permissions
 
-	// Continue only if GPT functionality is enabled
-	if b.Permissions|PERMISSIONS_GPT == 0 {
+	// Continue only if GPT functionality is enabled for both the bot and the input
+	if input.Permissions&PERMISSIONS_GPT == 0 {
 		return nil, fmt.Errorf("gpt functionality is not enabled")
 	}
 
 	// Find the conversation
-	var conversation *Conversation
-	for i, c := range b.Conversations {
-		if c.Name == key {
-			conversation = &b.Conversations[i]
-			break
-		}
-	}
+	conversation := b.getConversation(key)
 
-	// If the conversation does not exist, return error
-	if conversation.Name == "" {
+	// If the conversation does not exist or is read-only, return error
+	if conversation == nil {
 		return nil, fmt.Errorf("conversation with key '%s' does not exist", key)
 	}
+	if conversation.ArchivedAt != nil {
+		return nil, fmt.Errorf("conversation with key '%s' is archived", key)
+	}
+
+	// Only offer the model the modules the conversation can use
+	conversation.request.Tools = b.toolsFor(key)
+
+	// Stream the model's replies if the caller asked for them
+	conversation.onToken = input.OnToken
+	defer func() { conversation.onToken = nil }()
+
+	// If a side-effecting tool is waiting for confirmation, the input is the user's decision
+	if conversation.PendingCalls != "" {
+		return b.resolveConfirmation(key, conversation, input)
+	}
 
 	// If there is a queued function, run it
 	if qf := b.nextQueuedFunction(); qf != nil {
 		// A function is queued; get the response directly from the function
+		start := time.Now()
 		output = *qf(b, input)
+
+		if err := b.auditQueuedFunction(key, qf, input, &output, time.Since(start)); err != nil {
+			return nil, err
+		}
 		return &output, output.Error
 	}
 
+	// Form answers are only meant for queued functions, and can hold secrets the model must not see
+	if _, ok := input.Data.(types.FormResponse); ok {
+		return nil, fmt.Errorf("the form is no longer waiting for answers")
+	}
+
 	// Get the GPT response
-	resp, err := conversation.SendMessage(openai.ChatMessageRoleUser, "user", input.Message)
+	resp, err := conversation.SendMessage(openai.ChatMessageRoleUser, "user", input.Message, input.Attachments...)
 	if err != nil {
 		return nil, err
 	}
@@ -134,79 +225,171 @@ func (b *Bot) SendMessage(key string, input *types.Input) (*types.Output, error)
 		}
 		resp.Choices[0].Message.ToolCalls = calls
 
-		// Go through each unique call
-		for _, call := range calls {
-			// Add the call to the conversation
-			if err = conversation.AddFunctionCall(&resp.Choices[0].Message); err != nil {
+		// Add the calls to the conversation
+		if err = conversation.AddFunctionCall(&resp.Choices[0].Message); err != nil {
+			return nil, err
+		}
+
+		// Run each unique call
+		return b.runToolCalls(key, conversation, calls, input, "")
+	}
+
+	output.Message = resp.Choices[0].Message.Content
+
+	// Save any memory changes that may have taken place
+	return &output, db.Save(&b.Memory).Error
+}
+
+// runToolCalls runs a model's tool calls in order and gets a new response from the model. If a
+// side-effecting tool is reached, the calls are paused until the user confirms it. The decision
+// applies to the first call in the list, and is empty if the user has not made one
+func (b *Bot) runToolCalls(key string, conversation *Conversation, calls []openai.ToolCall, input *types.Input, decision string) (*types.Output, error) {
+	var err error
+
+	// Go through each call
+	for i, call := range calls {
+		// Validate the arguments before any module is called. Invalid arguments are sent back
+		// to the model as the tool result so it can retry
+		if verr := b.validateArguments(call.Function.Name, call.Function.Arguments); verr != nil {
+			if err = b.auditTool(key, call.ID, call.Function.Name, call.Function.Arguments, input, verr, 0, ""); err != nil {
 				return nil, err
 			}
 
-			// A function call is present, parse the arguments
-			input.Parameters, err = objx.FromJSON(call.Function.Arguments)
-			if err != nil {
+			if err = conversation.addToolResult(call, validationResult(verr)); err != nil {
 				return nil, err
 			}
 
-			// Call associated module handlers
-			for _, f := range b.handlers {
-				// Only continue for functions that return an output
-				if output := f(call.Function.Name, input); output != nil {
-					// Check if output matches the output type. If so, return
-					val, ok := output.(*types.Output)
-					if ok {
-						return val, val.Error
-					}
-
-					// Marshal the output into a string
-					message, err := json.Marshal(output)
-					if err != nil {
-						return nil, err
-					}
-
-					// Send the output of the function call to the OpenAI model and get a new response
-					callMessage := openai.ChatCompletionMessage{
-						Role:       openai.ChatMessageRoleTool,
-						Name:       call.Function.Name,
-						Content:    string(message),
-						ToolCallID: call.ID,
-					}
-					if err = conversation.AddFunctionCall(&callMessage); err != nil {
-						return nil, err
-					}
-
-					break
+			continue
+		}
+
+		// Calls to modules the conversation can't use are refused
+		if !b.moduleAllowed(key, b.moduleOf(call.Function.Name)) {
+			if err = b.auditTool(key, call.ID, call.Function.Name, call.Function.Arguments, input, errNotAllowed, 0, ""); err != nil {
+				return nil, err
+			}
+
+			if err = conversation.addToolResult(call, `{"error": "this function is not allowed in this channel"}`); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		// Side-effecting tools wait for the user's confirmation
+		callDecision := ""
+		if i == 0 {
+			callDecision = decision
+		}
+		if b.sideEffects[call.Function.Name] {
+			if callDecision == "" {
+				return b.requestConfirmation(conversation, calls[i:])
+			}
+
+			// Let the model know the call was declined
+			if callDecision == CONFIRMATION_DENIED {
+				if err = b.auditTool(key, call.ID, call.Function.Name, call.Function.Arguments, input, errDeclined, 0, callDecision); err != nil {
+					return nil, err
 				}
+
+				if err = conversation.addToolResult(call, `{"error": "the user declined to run this function"}`); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 		}
 
-		// Send the function calls for a new response
-		resp, err = conversation.SendFunctionCalls()
+		// A function call is present, parse the arguments
+		input.Parameters, err = objx.FromJSON(call.Function.Arguments)
 		if err != nil {
 			return nil, err
 		}
+
+		// Call associated module handlers until one returns an output
+		start := time.Now()
+		var output any
+		for _, f := range b.handlers {
+			if output = f(call.Function.Name, input); output != nil {
+				break
+			}
+		}
+
+		// Record the execution in the audit log
+		if err = b.auditTool(key, call.ID, call.Function.Name, call.Function.Arguments, input, output, time.Since(start), callDecision); err != nil {
+			return nil, err
+		}
+
+		// Let the model know when no module handled the call, such as when the input doesn't
+		// have the module's permissions
+		if output == nil {
+			if err = conversation.addToolResult(call, `{"error": "this function is not available"}`); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		// Check if output matches the output type. If so, return
+		val, ok := output.(*types.Output)
+		if ok {
+			return val, val.Error
+		}
+
+		// Marshal the output into a string
+		message, err := json.Marshal(output)
+		if err != nil {
+			return nil, err
+		}
+
+		// Send the output of the function call to the OpenAI model
+		if err = conversation.addToolResult(call, string(message)); err != nil {
+			return nil, err
+		}
 	}
 
-	output.Message = resp.Choices[0].Message.Content
+	// Send the function calls for a new response
+	resp, err := conversation.SendFunctionCalls()
+	if err != nil {
+		return nil, err
+	}
 
 	// Save any memory changes that may have taken place
-	return &output, db.Save(&b.Memory).Error
+	return &types.Output{Message: resp.Choices[0].Message.Content}, db.Save(&b.Memory).Error
+}
+
+// validationResult creates the tool result sent to the model when its arguments are invalid
+func validationResult(err error) string {
+	result := struct {
+		Error   string                  `json:"error"`
+		Details schema.ValidationErrors `json:"details"`
+		Message string                  `json:"message"`
+	}{
+		Error:   "invalid arguments",
+		Message: "The function was not called. Fix the arguments to match the function's parameters and call it again.",
+	}
+
+	if verrs, ok := err.(schema.ValidationErrors); ok {
+		result.Details = verrs
+	} else {
+		result.Details = schema.ValidationErrors{{Message: err.Error()}}
+	}
+
+	raw, _ := json.Marshal(result)
+	return string(raw)
 }
 
 // Add a message to a conversation
 func (b *Bot) AddMessage(key string, role string, name string, content string) error {
 	// Find the conversation
-	var conversation *Conversation
-	for i, c := range b.Conversations {
-		if c.Name == key {
-			conversation = &b.Conversations[i]
-			break
-		}
-	}
+	conversation := b.getConversation(key)
 
-	// If the conversation does not exist, return error
-	if conversation.Name == "" {
+	// If the conversation does not exist or is read-only, return error
+	if conversation == nil {
 		return fmt.Errorf("conversation with key '%s' does not exist", key)
 	}
+	if conversation.ArchivedAt != nil {
+		return fmt.Errorf("conversation with key '%s' is archived", key)
+	}
 
 	// Add the message to the conversation
 	if err := conversation.AddMessage(role, name, content); err != nil {
@@ -216,6 +399,60 @@ func (b *Bot) AddMessage(key string, role string, name string, content string) e
 	return db.Save(&b.Memory).Error
 }
 
+// toolsFor returns the tools a conversation can use, sorted by name
+func (b *Bot) toolsFor(key string) []openai.Tool {
+	allowed := b.allowedModules(key)
+
+	names := []string{}
+	for name := range b.functionDefinitions {
+		module, _, _ := strings.Cut(name, "-")
+		if isAllowed(allowed, module) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var tools []openai.Tool
+	for _, name := range names {
+		def := b.functionDefinitions[name]
+		tools = append(tools, openai.Tool{
+			Type:     openai.ToolTypeFunction,
+			Function: &def,
+		})
+	}
+	return tools
+}
+
+// Get a pointer to a conversation by name, or nil if it does not exist
+func (b *Bot) getConversation(key string) *Conversation {
+	for i := range b.Conversations {
+		if b.Conversations[i].Name == key {
+			return &b.Conversations[i]
+		}
+	}
+
+	return nil
+}
+
+// Validate a function call's arguments against the function's registered schema definition
+func (b *Bot) validateArguments(function string, arguments string) error {
+	for _, def := range b.functionDefinitions {
+		if def.Name != function {
+			continue
+		}
+
+		switch params := def.Parameters.(type) {
+		case schema.Definition:
+			return params.ValidateJSON(arguments)
+		case *schema.Definition:
+			return params.ValidateJSON(arguments)
+		}
+		return nil
+	}
+
+	return nil
+}
+
 // Get the next queued function
 func (b *Bot) nextQueuedFunction() func(bot *Bot, input *types.Input) *types.Output {
 	if len(b.functionQueue) == 0 {
@@ -238,6 +475,31 @@ func (b *Bot) AddHandlers(handlers ...func(function string, input *types.Input)
 	b.handlers = append(b.handlers, handlers...)
 }
 
+// Adds tools to the bot's function definitions. Side-effecting tools are registered to need
+// the user's confirmation before they run
+func (b *Bot) AddTools(name string, tools []Tool) {
+	definitions := ToolDefinitions(tools)
+	b.AddDefinitions(name, &definitions)
+
+	for _, t := range tools {
+		if t.SideEffects {
+			b.AddSideEffects(t.Definition.Name)
+		}
+	}
+}
+
+// Adds functions that need the user's confirmation before they run. Functions must be added to
+// the bot's definitions first, and it panics if a name matches no definition so a typo cannot
+// silently skip the confirmation
+func (b *Bot) AddSideEffects(functions ...string) {
+	for _, f := range functions {
+		if b.moduleOf(f) == "" {
+			panic(fmt.Sprintf("side-effecting function %v matches no function definition", f))
+		}
+		b.sideEffects[f] = true
+	}
+}
+
 // Adds definitions to the bot's function definitions
 func (b *Bot) AddDefinitions(name string, definitions *map[string]openai.FunctionDefinition) {
 	for key, f := range *definitions {
@@ -275,6 +537,7 @@ func NewBot(name string, permissions byte) (*Bot, error) {
 		Conversations:       []Conversation{},
 		functionDefinitions: map[string]openai.FunctionDefinition{},
 		handlers:            []func(function string, input *types.Input) any{},
+		sideEffects:         map[string]bool{},
 		functionQueue:       []func(bot *Bot, input *types.Input) *types.Output{},
 		variables:           map[string]any{},
 	}
@@ -0,0 +1,148 @@
+package horus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethanbaker/horus/utils/schema"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/glebarez/sqlite"
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupDB connects the package to a fresh in-memory SQLite database, which uses the fallback
+// search index
+func setupDB(t *testing.T) {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%v?mode=memory&cache=shared", t.Name())
+	if err := InitDB(sqlite.Open(dsn)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestBot creates a bot with every permission in a fresh database
+func newTestBot(t *testing.T) *Bot {
+	t.Helper()
+	setupDB(t)
+
+	b, err := NewBot("horus-test", PERMISSIONS_ALL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// newFakeModel creates a client for a fake OpenAI server. Each chat completion request is answered
+// with the message returned by reply
+func newFakeModel(t *testing.T, reply func(request openai.ChatCompletionRequest) openai.ChatCompletionMessage) *openai.Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := openai.ChatCompletionRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: reply(request)}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	config := openai.DefaultConfig("test")
+	config.BaseURL = server.URL + "/v1"
+	return openai.NewClientWithConfig(config)
+}
+
+// callOnce replies with a call to a function until the model is given its result. Each call has
+// a new ID, since IDs are unique across conversations
+func callOnce(function string, arguments string) func(request openai.ChatCompletionRequest) openai.ChatCompletionMessage {
+	count := 0
+	return func(request openai.ChatCompletionRequest) openai.ChatCompletionMessage {
+		if last := request.Messages[len(request.Messages)-1]; last.Role == openai.ChatMessageRoleTool {
+			return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "result: " + last.Content}
+		}
+
+		count++
+		return openai.ChatCompletionMessage{
+			Role: openai.ChatMessageRoleAssistant,
+			ToolCalls: []openai.ToolCall{{
+				ID:       fmt.Sprintf("call-%v", count),
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: function, Arguments: arguments},
+			}},
+		}
+	}
+}
+
+// addTestModule adds a module with a single function, set_value, to a bot. Each call is recorded
+// in the returned list
+func addTestModule(b *Bot, permissions byte) *[]string {
+	calls := []string{}
+
+	b.AddDefinitions("test", &map[string]openai.FunctionDefinition{
+		"set_value": {
+			Name:        "set_value",
+			Description: "Set a value",
+			Parameters: schema.Definition{
+				Type:       schema.Object,
+				Properties: map[string]schema.Definition{"value": {Type: schema.String}},
+				Required:   []string{"value"},
+			},
+		},
+	})
+
+	b.AddHandlers(func(function string, input *types.Input) any {
+		if function != "set_value" || input.Permissions&permissions == 0 {
+			return nil
+		}
+
+		value, _ := input.GetString("value", "")
+		calls = append(calls, value)
+		return map[string]string{"message": "value set"}
+	})
+
+	return &calls
+}
+
+func TestSendMessagePermissions(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBot(t)
+	calls := addTestModule(b, PERMISSIONS_PRVMODULES)
+	b.Setup(newFakeModel(t, callOnce("set_value", `{"value": "blue"}`)))
+	assert.Nil(b.AddConversation("main"))
+
+	// Inputs without the GPT permission are refused
+	_, err := b.SendMessage("main", &types.Input{Message: "Set the value", Permissions: PERMISSIONS_ALLMODULES})
+	assert.EqualError(err, "gpt functionality is not enabled")
+
+	// Inputs without the module's permission can't call it, and the model is told
+	output, err := b.SendMessage("main", &types.Input{Message: "Set the value", Permissions: PERMISSIONS_GPT | PERMISSIONS_PUBMODULES})
+	assert.Nil(err)
+	assert.Equal(`result: {"error": "this function is not available"}`, output.Message)
+	assert.Empty(*calls)
+
+	// Inputs with both permissions call the module
+	output, err = b.SendMessage("main", &types.Input{Message: "Set the value", Permissions: PERMISSIONS_GPT | PERMISSIONS_PRVMODULES})
+	assert.Nil(err)
+	assert.Equal(`result: {"message":"value set"}`, output.Message)
+	assert.Equal([]string{"blue"}, *calls)
+
+	// Inputs can't use permissions the bot doesn't have
+	b.Permissions = PERMISSIONS_GPT | PERMISSIONS_PUBMODULES
+	output, err = b.SendMessage("main", &types.Input{Message: "Set the value", Permissions: PERMISSIONS_ALL})
+	assert.Nil(err)
+	assert.Equal(`result: {"error": "this function is not available"}`, output.Message)
+	assert.Len(*calls, 1)
+
+	b.Permissions = PERMISSIONS_ALLMODULES
+	_, err = b.SendMessage("main", &types.Input{Message: "Set the value", Permissions: PERMISSIONS_ALL})
+	assert.EqualError(err, "gpt functionality is not enabled")
+}
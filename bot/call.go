@@ -0,0 +1,150 @@
+package horus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/format"
+	"github.com/ethanbaker/horus/utils/types"
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/objx"
+)
+
+// Functions returns the definitions of every function the bot's modules provide, sorted by name.
+// Implementations use them to call functions directly (ex: as slash commands)
+func (b *Bot) Functions() []openai.FunctionDefinition {
+	functions := make([]openai.FunctionDefinition, 0, len(b.functionDefinitions))
+	for _, f := range b.functionDefinitions {
+		functions = append(functions, f)
+	}
+
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].Name < functions[j].Name
+	})
+	return functions
+}
+
+// Modules returns the names of the bot's modules that provide functions, sorted by name
+func (b *Bot) Modules() []string {
+	modules := []string{}
+	for key := range b.functionDefinitions {
+		if module, _, _ := strings.Cut(key, "-"); !isAllowed(modules, module) {
+			modules = append(modules, module)
+		}
+	}
+
+	sort.Strings(modules)
+	return modules
+}
+
+// CallFunction calls a function directly with JSON arguments instead of asking the model. The
+// call is validated and audited like the model's calls, and the conversation key decides which
+// modules can be used. Calling a function directly is the user's confirmation, so side-effecting
+// functions run immediately. Results that aren't outputs are returned as a card
+func (b *Bot) CallFunction(key string, function string, arguments string, input *types.Input) (*types.Output, error) {
+	input.Permissions = input.Permissions & b.Permissions
+
+	// Make sure the function exists and its arguments are valid
+	if b.moduleOf(function) == "" {
+		return nil, fmt.Errorf("function '%s' does not exist", function)
+	}
+	if !b.moduleAllowed(key, b.moduleOf(function)) {
+		return nil, fmt.Errorf("function '%s' is not allowed in this channel", function)
+	}
+	if err := b.validateArguments(function, arguments); err != nil {
+		return nil, fmt.Errorf("invalid arguments for '%s': %w", function, err)
+	}
+
+	var err error
+	input.Parameters, err = objx.FromJSON(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Call associated module handlers until one returns an output
+	start := time.Now()
+	var result any
+	for _, f := range b.handlers {
+		if result = f(function, input); result != nil {
+			break
+		}
+	}
+
+	decision := ""
+	if b.sideEffects[function] {
+		decision = CONFIRMATION_APPROVED
+	}
+	if err = b.auditTool(key, "", function, arguments, input, result, time.Since(start), decision); err != nil {
+		return nil, err
+	}
+
+	switch val := result.(type) {
+	case nil:
+		return nil, fmt.Errorf("no module handled function '%s'", function)
+
+	case *types.Output:
+		return val, val.Error
+
+	case error:
+		return nil, val
+	}
+
+	block, err := resultCard(function, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save any memory changes that may have taken place
+	return &types.Output{Data: result, Blocks: []types.Block{block}}, db.Save(&b.Memory).Error
+}
+
+// resultCard shows a function's result as a card. The fields of objects keep their order, and
+// other values are shown in a single field
+func resultCard(function string, result any) (types.Block, error) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return types.Block{}, err
+	}
+	title := strings.ReplaceAll(function, "_", " ")
+
+	// Read the object's fields in order
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return types.Card(title, types.Field{Name: "Result", Value: format.Escape(string(raw))}), nil
+	}
+
+	fields := []types.Field{}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return types.Block{}, err
+		}
+
+		var value json.RawMessage
+		if err = dec.Decode(&value); err != nil {
+			return types.Block{}, err
+		}
+
+		// Show strings without their quotes
+		text := string(value)
+		var s string
+		if json.Unmarshal(value, &s) == nil {
+			text = s
+		}
+		if text == "" {
+			text = "-"
+		}
+
+		fields = append(fields, types.Field{
+			Name:   format.Escape(strings.ReplaceAll(t.(string), "_", " ")),
+			Value:  format.Escape(text),
+			Inline: true,
+		})
+	}
+
+	return types.Card(title, fields...), nil
+}
@@ -0,0 +1,109 @@
+package horus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/ethanbaker/horus/utils/validation"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// The error recorded for side-effecting calls the user declined
+var errDeclined = errors.New("declined by the user")
+
+// errNotAllowed is recorded in the audit log when a function's module can't be used in a channel
+var errNotAllowed = errors.New("module not allowed in this channel")
+
+// requestConfirmation pauses a list of tool calls and asks the user to confirm the first one. The
+// calls are saved with the conversation, so only its own reply decides them and they keep waiting
+// across restarts
+func (b *Bot) requestConfirmation(conversation *Conversation, calls []openai.ToolCall) (*types.Output, error) {
+	raw, err := json.Marshal(calls)
+	if err != nil {
+		return nil, err
+	}
+	if err = conversation.setPendingCalls(string(raw)); err != nil {
+		return nil, err
+	}
+
+	call := calls[0]
+	confirmation := types.Confirmation{
+		Function:  call.Function.Name,
+		Module:    b.moduleOf(call.Function.Name),
+		Arguments: map[string]any{},
+	}
+
+	// Secrets are redacted so they are never echoed back to the user's channel
+	_ = json.Unmarshal([]byte(redactArguments(call.Function.Arguments)), &confirmation.Arguments)
+	confirmation.Summary = confirmationSummary(confirmation)
+
+	return &types.Output{
+		Message: confirmation.Summary,
+		Data:    confirmation,
+		Actions: types.ConfirmActions,
+	}, nil
+}
+
+// resolveConfirmation uses the user's reply to approve or decline the conversation's paused tool
+// call, then continues running the remaining calls
+func (b *Bot) resolveConfirmation(key string, conversation *Conversation, input *types.Input) (*types.Output, error) {
+	calls := []openai.ToolCall{}
+	if err := json.Unmarshal([]byte(conversation.PendingCalls), &calls); err != nil {
+		return nil, err
+	}
+	if err := conversation.setPendingCalls(""); err != nil {
+		return nil, err
+	}
+
+	decision := CONFIRMATION_DENIED
+	if validation.ValidateStrictConfirmation(input.Message) {
+		decision = CONFIRMATION_APPROVED
+	}
+
+	output, err := b.runToolCalls(key, conversation, calls, input, decision)
+	if err != nil {
+		return nil, err
+	}
+
+	return output, output.Error
+}
+
+// confirmationSummary describes a tool call to the user
+func confirmationSummary(c types.Confirmation) string {
+	var sb strings.Builder
+
+	if c.Module != "" {
+		sb.WriteString(fmt.Sprintf("I'd like to run <STRONG>%v<STRONG> from the %v module", c.Function, c.Module))
+	} else {
+		sb.WriteString(fmt.Sprintf("I'd like to run <STRONG>%v<STRONG>", c.Function))
+	}
+
+	if len(c.Arguments) == 0 {
+		sb.WriteString(".\n")
+	} else {
+		sb.WriteString(" with:\n")
+
+		keys := make([]string, 0, len(c.Arguments))
+		for k := range c.Arguments {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			value := fmt.Sprint(c.Arguments[k])
+			if _, ok := c.Arguments[k].(string); !ok {
+				raw, _ := json.Marshal(c.Arguments[k])
+				value = string(raw)
+			}
+
+			sb.WriteString(fmt.Sprintf("<STRONG>%v<STRONG>: %v\n", k, value))
+		}
+	}
+
+	sb.WriteString("\nShould I go ahead? (yes/no)")
+	return sb.String()
+}
@@ -0,0 +1,129 @@
+package horus
+
+import (
+	"testing"
+
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupConfirmation creates a bot whose model calls a side-effecting function, and sends a
+// message so the call waits for confirmation
+func setupConfirmation(t *testing.T) (*Bot, *[]string) {
+	assert := assert.New(t)
+
+	b := newTestBot(t)
+	calls := addTestModule(b, PERMISSIONS_PRVMODULES)
+	b.AddSideEffects("set_value")
+	b.Setup(newFakeModel(t, callOnce("set_value", `{"value": "blue", "password": "hunter2"}`)))
+	assert.Nil(b.AddConversation("main"))
+
+	output, err := b.SendMessage("main", &types.Input{Message: "Set the value to blue", Permissions: PERMISSIONS_ALL})
+	assert.Nil(err)
+	assert.Equal(types.ConfirmActions, output.Actions)
+	assert.Equal(types.Confirmation{
+		Function:  "set_value",
+		Module:    "test",
+		Arguments: map[string]any{"value": "blue", "password": "[REDACTED]"},
+		Summary:   output.Message,
+	}, output.Data)
+	assert.NotContains(output.Message, "hunter2")
+
+	// The call waits for the user's decision
+	assert.NotEmpty(b.getConversation("main").PendingCalls)
+	assert.Empty(*calls)
+
+	return b, calls
+}
+
+func TestConfirmationApproved(t *testing.T) {
+	assert := assert.New(t)
+	b, calls := setupConfirmation(t)
+
+	// Approving runs the call and continues the conversation
+	output, err := b.SendMessage("main", &types.Input{Message: "Yes!", Permissions: PERMISSIONS_ALL})
+	assert.Nil(err)
+	assert.Equal(`result: {"message":"value set"}`, output.Message)
+	assert.Equal([]string{"blue"}, *calls)
+	assert.Empty(b.getConversation("main").PendingCalls)
+
+	audits, err := b.ToolAudits(AuditQuery{Function: "set_value"})
+	assert.Nil(err)
+	assert.Len(audits, 1)
+	assert.Equal(CONFIRMATION_APPROVED, audits[0].Decision)
+}
+
+func TestConfirmationDenied(t *testing.T) {
+	assert := assert.New(t)
+	b, calls := setupConfirmation(t)
+
+	// Anything other than a clear approval declines the call
+	output, err := b.SendMessage("main", &types.Input{Message: "yes but not now", Permissions: PERMISSIONS_ALL})
+	assert.Nil(err)
+	assert.Equal(`result: {"error": "the user declined to run this function"}`, output.Message)
+	assert.Empty(*calls)
+	assert.Empty(b.getConversation("main").PendingCalls)
+
+	audits, err := b.ToolAudits(AuditQuery{Function: "set_value"})
+	assert.Nil(err)
+	assert.Len(audits, 1)
+	assert.Equal(CONFIRMATION_DENIED, audits[0].Decision)
+	assert.Equal(errDeclined.Error(), audits[0].Error)
+
+	// The next message goes to the model instead of the cleared confirmation
+	output, err = b.SendMessage("main", &types.Input{Message: "yes", Permissions: PERMISSIONS_ALL})
+	assert.Nil(err)
+	assert.Equal(types.ConfirmActions, output.Actions)
+	assert.Empty(*calls)
+}
+
+func TestConfirmationPerConversation(t *testing.T) {
+	assert := assert.New(t)
+	b, calls := setupConfirmation(t)
+	assert.Nil(b.AddConversation("other"))
+
+	// A call in another conversation waits separately, and its reply can't approve the first call
+	output, err := b.SendMessage("other", &types.Input{Message: "yes", Permissions: PERMISSIONS_ALL})
+	assert.Nil(err)
+	assert.Equal(types.ConfirmActions, output.Actions)
+	assert.Empty(*calls)
+	assert.NotEmpty(b.getConversation("main").PendingCalls)
+	assert.NotEmpty(b.getConversation("other").PendingCalls)
+
+	// Each conversation decides its own call
+	output, err = b.SendMessage("main", &types.Input{Message: "yes", Permissions: PERMISSIONS_ALL})
+	assert.Nil(err)
+	assert.Equal(`result: {"message":"value set"}`, output.Message)
+	assert.Equal([]string{"blue"}, *calls)
+	assert.Empty(b.getConversation("main").PendingCalls)
+	assert.NotEmpty(b.getConversation("other").PendingCalls)
+}
+
+func TestConfirmationRestart(t *testing.T) {
+	assert := assert.New(t)
+	b, _ := setupConfirmation(t)
+
+	// Loading the bot again keeps the call waiting
+	loaded, err := GetBotByName(b.Name)
+	assert.Nil(err)
+	assert.Equal(b.getConversation("main").PendingCalls, loaded.getConversation("main").PendingCalls)
+
+	calls := addTestModule(loaded, PERMISSIONS_PRVMODULES)
+	loaded.AddSideEffects("set_value")
+	loaded.Setup(b.client)
+
+	output, err := loaded.SendMessage("main", &types.Input{Message: "yes", Permissions: PERMISSIONS_ALL})
+	assert.Nil(err)
+	assert.Equal(`result: {"message":"value set"}`, output.Message)
+	assert.Equal([]string{"blue"}, *calls)
+}
+
+func TestAddSideEffectsUnknown(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBot(t)
+	addTestModule(b, PERMISSIONS_PRVMODULES)
+
+	// Names that match no function fail instead of silently skipping the confirmation
+	assert.Panics(func() { b.AddSideEffects("set_valeu") })
+	assert.NotPanics(func() { b.AddSideEffects("set_value") })
+}
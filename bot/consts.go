@@ -14,6 +14,16 @@ const (
 	PERMISSIONS_ALL        = 0b11111111 // All custom modules and GPT functionality is enabled
 )
 
+// The names of the permissions in configs, which can be combined in a comma separated list
+var PERMISSION_NAMES = map[string]byte{
+	"none":    PERMISSIONS_NONE,
+	"gpt":     PERMISSIONS_GPT,
+	"private": PERMISSIONS_PRVMODULES,
+	"public":  PERMISSIONS_PUBMODULES,
+	"modules": PERMISSIONS_ALLMODULES,
+	"all":     PERMISSIONS_ALL,
+}
+
 /* ---- OPENAI CONSTANTS ---- */
 
 const (
@@ -22,3 +32,39 @@ const (
 	OPENAI_MAXTOKENS = 500                                                 // What is the maximum amount of tokens that can be sent to the model? (Tokens = Words / 0.75)
 	OPENAI_SYSPROMPT = `You are a helpful personal assistant named Horus.` // System Prompt for the OpenAI model
 )
+
+/* ---- SEARCH CONSTANTS ---- */
+
+const (
+	SEARCH_LIMIT          = 20          // The default maximum number of search results
+	SEARCH_SNIPPET_RADIUS = 60          // How many characters of context surround a match in a snippet
+	SEARCH_SOURCE_CONTENT = "content"   // Matches found in the content of a message
+	SEARCH_SOURCE_TOOL    = "tool_call" // Matches found in the arguments of a tool call
+)
+
+/* ---- CONFIRMATION CONSTANTS ---- */
+
+const (
+	CONFIRMATION_APPROVED = "approved" // The user approved a side-effecting tool call
+	CONFIRMATION_DENIED   = "denied"   // The user declined a side-effecting tool call
+)
+
+/* ---- ATTACHMENT CONSTANTS ---- */
+
+const (
+	ATTACHMENT_TEXT  = "text"  // Text files, inlined in the message's content
+	ATTACHMENT_IMAGE = "image" // Images, given to the model as content parts
+	ATTACHMENT_FILE  = "file"  // Other files, kept with the message and referenced in its content
+
+	ATTACHMENT_MAX_SIZE   = 8 << 20 // The largest attachment kept, in bytes
+	ATTACHMENT_MAX_INLINE = 16000   // How many characters of a text file are inlined
+)
+
+// Prefixes of the models that accept images
+var VISION_MODELS = []string{"gpt-4o", "gpt-4-turbo", "gpt-4-vision"}
+
+// The image types models accept
+var IMAGE_TYPES = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// Types of text files that don't start with text/
+var TEXT_TYPES = []string{"application/json", "application/xml", "application/yaml", "application/x-yaml", "application/toml", "application/javascript", "application/x-sh"}
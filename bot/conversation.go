@@ -2,7 +2,12 @@ package horus
 
 import (
 	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
 
+	"github.com/ethanbaker/horus/utils/types"
 	"github.com/sashabaranov/go-openai"
 	"gorm.io/gorm"
 )
@@ -11,12 +16,16 @@ import (
 type Conversation struct {
 	gorm.Model
 
-	BotID    uint      // The foreign key to relate the conversation to a bot
-	Name     string    // A unique identifying key for the converesation
-	Messages []Message // A list of messages in the conversation
+	BotID      uint       // The foreign key to relate the conversation to a bot
+	Name       string     // A unique identifying key for the converesation
+	Messages   []Message  // A list of messages in the conversation
+	ArchivedAt *time.Time // When the conversation was archived (archived conversations are read-only)
+
+	PendingCalls string // Tool calls waiting for the user's confirmation, as JSON (empty if there are none)
 
 	client  *openai.Client               `gorm:"-"` // The OpenAI client the conversation is attached to
 	request openai.ChatCompletionRequest `gorm:"-"` // The OpenAI request this conversation is emulating
+	onToken func(token string)           `gorm:"-"` // Receives the model's reply as it is streamed (nil when not streaming)
 }
 
 // Delete a conversation and all associated messages
@@ -45,6 +54,12 @@ func (c *Conversation) appendMessage(m Message) error {
 	return db.Save(&c).Error
 }
 
+// setPendingCalls saves the tool calls waiting for the user's confirmation
+func (c *Conversation) setPendingCalls(calls string) error {
+	c.PendingCalls = calls
+	return db.Model(c).Update("pending_calls", calls).Error
+}
+
 // Add function call to the conversation
 func (c *Conversation) AddFunctionCall(message *openai.ChatCompletionMessage) error {
 	m, err := newMessage(c.Model.ID, uint(len(c.Messages)), message)
@@ -55,10 +70,20 @@ func (c *Conversation) AddFunctionCall(message *openai.ChatCompletionMessage) er
 	return c.appendMessage(m)
 }
 
+// Add the result of a tool call to the conversation
+func (c *Conversation) addToolResult(call openai.ToolCall, content string) error {
+	return c.AddFunctionCall(&openai.ChatCompletionMessage{
+		Role:       openai.ChatMessageRoleTool,
+		Name:       call.Function.Name,
+		Content:    content,
+		ToolCallID: call.ID,
+	})
+}
+
 // SendFunctionCalls gets a new response with added function calls
 func (c *Conversation) SendFunctionCalls() (*openai.ChatCompletionResponse, error) {
 	// Get the chat completion
-	resp, err := c.client.CreateChatCompletion(context.Background(), c.request)
+	resp, err := c.complete()
 	if err != nil {
 		return nil, err
 	}
@@ -72,17 +97,19 @@ func (c *Conversation) SendFunctionCalls() (*openai.ChatCompletionResponse, erro
 	return &resp, c.appendMessage(m)
 }
 
-// SendMessage sends a message to OpenAI
-func (c *Conversation) SendMessage(role string, name string, content string) (*openai.ChatCompletionResponse, error) {
+// SendMessage sends a message to OpenAI along with any files attached to it
+func (c *Conversation) SendMessage(role string, name string, content string, files ...types.Attachment) (*openai.ChatCompletionResponse, error) {
+	attachments := newAttachments(files, supportsImages(c.request.Model))
+
 	// Add the message to the chat completion request
 	chatCompletionMessage := openai.ChatCompletionMessage{
 		Role:    role,
 		Name:    name,
-		Content: content,
+		Content: withAttachments(content, attachments),
 	}
 
 	// Create a new message from the user
-	m, err := newMessage(c.Model.ID, uint(len(c.Messages)), &chatCompletionMessage)
+	m, err := newMessage(c.Model.ID, uint(len(c.Messages)), &chatCompletionMessage, attachments...)
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +120,7 @@ func (c *Conversation) SendMessage(role string, name string, content string) (*o
 	}
 
 	// Get the chat completion
-	resp, err := c.client.CreateChatCompletion(context.Background(), c.request)
+	resp, err := c.complete()
 	if err != nil {
 		return nil, err
 	}
@@ -112,6 +139,80 @@ func (c *Conversation) SendMessage(role string, name string, content string) (*o
 	return &resp, c.appendMessage(m)
 }
 
+// complete gets a chat completion for the conversation. When the conversation is streaming, the
+// reply is passed to onToken as it arrives and assembled into a single response
+func (c *Conversation) complete() (openai.ChatCompletionResponse, error) {
+	if c.onToken == nil {
+		return c.client.CreateChatCompletion(context.Background(), c.request)
+	}
+
+	request := c.request
+	request.Stream = true
+
+	stream, err := c.client.CreateChatCompletionStream(context.Background(), request)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	defer stream.Close()
+
+	message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	var content strings.Builder
+	var finishReason openai.FinishReason
+
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return openai.ChatCompletionResponse{}, err
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			finishReason = choice.FinishReason
+		}
+
+		// Pass on content as it arrives
+		if choice.Delta.Content != "" {
+			content.WriteString(choice.Delta.Content)
+			c.onToken(choice.Delta.Content)
+		}
+
+		// Tool calls arrive in pieces, identified by their index
+		for _, delta := range choice.Delta.ToolCalls {
+			i := len(message.ToolCalls) - 1
+			if delta.Index != nil {
+				i = *delta.Index
+			}
+			for i >= len(message.ToolCalls) {
+				message.ToolCalls = append(message.ToolCalls, openai.ToolCall{})
+			}
+
+			call := &message.ToolCalls[i]
+			if delta.ID != "" {
+				call.ID = delta.ID
+			}
+			if delta.Type != "" {
+				call.Type = delta.Type
+			}
+			call.Function.Name += delta.Function.Name
+			call.Function.Arguments += delta.Function.Arguments
+		}
+	}
+
+	message.Content = content.String()
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{
+			Message:      message,
+			FinishReason: finishReason,
+		}},
+	}, nil
+}
+
 // Add a message to the conversation without sending it
 func (c *Conversation) AddMessage(role string, name string, content string) error {
 	// Add the message to the chat completion request
@@ -147,27 +248,7 @@ func (c *Conversation) setup(client *openai.Client, functions *map[string]openai
 
 	// Add existing messages to the request
 	for _, m := range c.Messages {
-		// Create the chat completion message
-		ccm := openai.ChatCompletionMessage{
-			Role:       m.Role,
-			Name:       m.Name,
-			Content:    m.Content,
-			ToolCallID: m.ToolCallID,
-		}
-
-		// Add tool calls
-		for _, call := range m.ToolCalls {
-			ccm.ToolCalls = append(ccm.ToolCalls, openai.ToolCall{
-				ID:   call.ID,
-				Type: openai.ToolType(call.Type),
-				Function: openai.FunctionCall{
-					Name:      call.CallName,
-					Arguments: call.CallArguments,
-				},
-			})
-		}
-
-		c.request.Messages = append(c.request.Messages, ccm)
+		c.request.Messages = append(c.request.Messages, m.chatCompletionMessage())
 	}
 
 	// Setup the function calls/tools
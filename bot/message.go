@@ -30,6 +30,9 @@ type Message struct {
 	ToolCallID string
 	ToolCalls  []ToolCall
 
+	// Files sent with the message
+	Attachments []Attachment
+
 	// The openAI message this message is representing
 	ChatCompletionMessage *openai.ChatCompletionMessage `gorm:"-"`
 }
@@ -43,21 +46,74 @@ func (m *Message) Delete() error {
 		}
 	}
 
+	// Delete attachments
+	if err := db.Where("message_id = ?", m.Model.ID).Delete(&Attachment{}).Error; err != nil {
+		return err
+	}
+	m.Attachments = []Attachment{}
+
+	// Remove the message from the search index
+	if err := unindexMessage(m); err != nil {
+		return err
+	}
+
 	m.ToolCalls = []ToolCall{}
 	return db.Delete(m).Error
 }
 
-// newMessage creates a new message
-func newMessage(conversationID uint, index uint, message *openai.ChatCompletionMessage) (Message, error) {
+// chatCompletionMessage rebuilds the OpenAI message this message represents
+func (m *Message) chatCompletionMessage() openai.ChatCompletionMessage {
+	ccm := openai.ChatCompletionMessage{
+		Role:       m.Role,
+		Name:       m.Name,
+		Content:    m.Content,
+		ToolCallID: m.ToolCallID,
+	}
+
+	// Give images to the model along with the content. Images that can't be loaded are left
+	// referenced in the content
+	if err := loadContent(m.Attachments, ATTACHMENT_IMAGE); err == nil {
+		if parts := imageParts(m.Content, m.Attachments); parts != nil {
+			ccm.Content = ""
+			ccm.MultiContent = parts
+		}
+	}
+
+	// Add tool calls
+	for _, call := range m.ToolCalls {
+		ccm.ToolCalls = append(ccm.ToolCalls, openai.ToolCall{
+			ID:   call.ID,
+			Type: openai.ToolType(call.Type),
+			Function: openai.FunctionCall{
+				Name:      call.CallName,
+				Arguments: call.CallArguments,
+			},
+		})
+	}
+
+	return ccm
+}
+
+// newMessage creates a new message along with the files sent with it
+func newMessage(conversationID uint, index uint, message *openai.ChatCompletionMessage, attachments ...Attachment) (Message, error) {
+	// Messages with content parts keep their text as their content
+	content := message.Content
+	for _, part := range message.MultiContent {
+		if part.Type == openai.ChatMessagePartTypeText {
+			content += part.Text
+		}
+	}
+
 	// Create the new message
 	m := Message{
 		ConversationID:        conversationID,
 		Idx:                   index,
 		Role:                  message.Role,
 		Name:                  message.Name,
-		Content:               message.Content,
+		Content:               content,
 		ToolCallID:            message.ToolCallID,
 		ToolCalls:             []ToolCall{},
+		Attachments:           attachments,
 		ChatCompletionMessage: message,
 	}
 
@@ -78,6 +134,17 @@ func newMessage(conversationID uint, index uint, message *openai.ChatCompletionM
 		m.ToolCalls = append(m.ToolCalls, c)
 	}
 
-	// Save the message to the SQL database and return
-	return m, db.Create(&m).Error
+	// Save the message to the SQL database
+	if err := db.Create(&m).Error; err != nil {
+		return m, err
+	}
+
+	// Give the model the message's images
+	if parts := imageParts(m.Content, m.Attachments); parts != nil {
+		ccm := m.chatCompletionMessage()
+		m.ChatCompletionMessage = &ccm
+	}
+
+	// Add the message to the search index and return
+	return m, indexMessage(&m)
 }
@@ -2,38 +2,15 @@
 package module_ambient
 
 import (
-	"github.com/ethanbaker/horus/utils/schema"
-	openai "github.com/sashabaranov/go-openai"
+	horus "github.com/ethanbaker/horus/bot"
 )
 
-// A map of each OpenAI function declaration present in this module
-var functionDefinitions = map[string]openai.FunctionDefinition{
+// A list of each tool present in this module. Function declarations are derived from the
+// argument type of each function
+var tools = []horus.Tool{
 	// Get the current time
-	"get_current_time": {
-		Name:        "get_current_time",
-		Description: "Get the current time",
-		Parameters: schema.Definition{
-			Type:       schema.Object,
-			Properties: map[string]schema.Definition{},
-		},
-	},
+	horus.MustTool("get_current_time", "Get the current time", get_time),
 
 	// Get the current weather
-	"get_current_weather": {
-		Name:        "get_current_weather",
-		Description: "Get the current weather in a given location",
-		Parameters: schema.Definition{
-			Type: schema.Object,
-			Properties: map[string]schema.Definition{
-				"location": {
-					Type:        schema.String,
-					Description: "The city (ex: 'Raleigh'). Do not include any state codes.",
-				},
-				"unit": {
-					Type: schema.String,
-					Enum: []string{"celsius", "fahrenheit"},
-				},
-			},
-		},
-	},
+	horus.MustTool("get_current_weather", "Get the current weather in a given location", get_weather),
 }
@@ -1,6 +1,7 @@
 package module_ambient
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,32 +11,53 @@ import (
 	"time"
 
 	horus "github.com/ethanbaker/horus/bot"
-	"github.com/ethanbaker/horus/utils/types"
 )
 
-// A list of all enabled functions in the module
-var functions = map[string]func(bot *horus.Bot, input *types.Input) any{
-	"get_current_time":    get_time,
-	"get_current_weather": get_weather,
+/* ---- TYPES ---- */
+
+// Arguments for get_time
+type TimeArgs struct{}
+
+// Time information returned by get_time
+type TimeResult struct {
+	Year    string `json:"year"`
+	Month   string `json:"month"`
+	Day     string `json:"day"`
+	Weekday string `json:"weekday"`
+	Hour    string `json:"hour"`
+	Minute  string `json:"minute"`
+	Second  string `json:"second"`
+}
+
+// Arguments for get_weather
+type WeatherArgs struct {
+	Location string `json:"location" description:"The city (ex: 'Raleigh'). Do not include any state codes."`
+	Unit     string `json:"unit" enum:"celsius,fahrenheit"`
 }
 
+// Weather data returned by get_weather
+type WeatherResult struct {
+	Overview    string  `json:"overview"`
+	Description string  `json:"description"`
+	Temperature float64 `json:"temperature"`
+	FeelsLike   float64 `json:"feels_like"`
+	MaxTemp     float64 `json:"max_temperature"`
+	MinTemp     float64 `json:"min_temperature"`
+	Humidity    int     `json:"humidity_percent"`
+	WindSpeed   float64 `json:"wind_speed"`
+	Cloudiness  int     `json:"cloud_cover_percent"`
+}
+
+/* ---- FUNCTIONS ---- */
+
 // Get the current time
-func get_time(bot *horus.Bot, input *types.Input) any {
-	// Type to hold time information
-	var timeInformation struct {
-		Year    string `json:"year"`
-		Month   string `json:"month"`
-		Day     string `json:"day"`
-		Weekday string `json:"weekday"`
-		Hour    string `json:"hour"`
-		Minute  string `json:"minute"`
-		Second  string `json:"second"`
-	}
+func get_time(ctx context.Context, bot *horus.Bot, args TimeArgs) (TimeResult, error) {
+	var timeInformation TimeResult
 
 	// Get the user's timezone
 	loc, err := time.LoadLocation(bot.Memory.Timezone)
 	if err != nil {
-		return `{"error": "could not load timezone"}`
+		return timeInformation, errors.New("could not load timezone")
 	}
 
 	t := time.Now().In(loc)
@@ -48,11 +70,11 @@ func get_time(bot *horus.Bot, input *types.Input) any {
 	timeInformation.Minute = fmt.Sprint(t.Minute())
 	timeInformation.Second = fmt.Sprint(t.Second())
 
-	return timeInformation
+	return timeInformation, nil
 }
 
 // Get the current weather
-func get_weather(bot *horus.Bot, input *types.Input) any {
+func get_weather(ctx context.Context, bot *horus.Bot, args WeatherArgs) (WeatherResult, error) {
 	// Openweather map request data
 	type openweathermapData struct {
 		Coord struct {
@@ -118,22 +140,9 @@ func get_weather(bot *horus.Bot, input *types.Input) any {
 		Cod      int    `json:"cod"`
 	}
 
-	// Weather data to send
-	type weatherData struct {
-		Overview    string  `json:"overview"`
-		Description string  `json:"description"`
-		Temperature float64 `json:"temperature"`
-		FeelsLike   float64 `json:"feels_like"`
-		MaxTemp     float64 `json:"max_temperature"`
-		MinTemp     float64 `json:"min_temperature"`
-		Humidity    int     `json:"humidity_percent"`
-		WindSpeed   float64 `json:"wind_speed"`
-		Cloudiness  int     `json:"cloud_cover_percent"`
-	}
-
 	// Get the parameters
-	location, _ := input.GetString("location", "")
-	unit, _ := input.GetString("unit", "")
+	location := args.Location
+	unit := args.Unit
 
 	if location == "" {
 		location = bot.Memory.City
@@ -149,33 +158,38 @@ func get_weather(bot *horus.Bot, input *types.Input) any {
 	url := fmt.Sprintf("%s/data/2.5/weather?q=%s&appid=%s", os.Getenv("WEATHER_BASE_URL"), location, os.Getenv("WEATHER_TOKEN"))
 
 	// Send the request
-	resp, err := client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return WeatherResult{}, errors.New("could not create weather request")
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf(`{"error": "could not access weather database"}`)
+		return WeatherResult{}, errors.New("could not access weather database")
 	}
 	defer resp.Body.Close()
 
 	// Check for errors
 	if resp.StatusCode == http.StatusNotFound {
-		return fmt.Errorf(`{"error": "could not find location '%v'"}`, location)
+		return WeatherResult{}, fmt.Errorf("could not find location '%v'", location)
 	} else if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf(`{"error": "unexpected response status %v"}`, resp.Status)
+		return WeatherResult{}, fmt.Errorf("unexpected response status %v", resp.Status)
 	}
 
 	// Get the data into a struct
 	raw, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf(`{"error": "response from weather database is unreadable"}`)
+		return WeatherResult{}, errors.New("response from weather database is unreadable")
 	}
 
 	var data openweathermapData
 	if err = json.Unmarshal(raw, &data); err != nil {
-		return fmt.Errorf(`{"error": "response from weather database is not formatted correctly"}`)
+		return WeatherResult{}, errors.New("response from weather database is not formatted correctly")
 	}
 
 	// Check for errors
 	if len(data.Weather) < 1 {
-		return errors.New(`{"error": "no weather elements available for location"}`)
+		return WeatherResult{}, errors.New("no weather elements available for location")
 	}
 
 	// Setup a conversion factor based on the requested unit
@@ -188,7 +202,7 @@ func get_weather(bot *horus.Bot, input *types.Input) any {
 		}
 	}
 
-	conditions := weatherData{
+	conditions := WeatherResult{
 		Overview:    data.Weather[0].Main,
 		Description: data.Weather[0].Description,
 		Temperature: tempConversion(data.Main.Temp),
@@ -200,5 +214,5 @@ func get_weather(bot *horus.Bot, input *types.Input) any {
 		Cloudiness:  data.Clouds.CoverPercent,
 	}
 
-	return conditions
+	return conditions, nil
 }
@@ -23,7 +23,7 @@ func (m *Module) Name() string {
 // Handle a function call
 func (m *Module) Handler(function string, input *types.Input) any {
 	// Check for permissions
-	if input.Permissions|m.Permissions == 0 {
+	if input.Permissions&m.Permissions == 0 {
 		return nil
 	}
 
@@ -43,10 +43,10 @@ func NewModule(bot *horus.Bot, enabled bool) {
 	var m Module
 	m.Enabled = enabled
 	m.Permissions = horus.PERMISSIONS_PUBMODULES
-	m.Functions = functions
+	m.Functions = horus.ToolFunctions(tools)
 	m.bot = bot
 
-	// Add the module's handler and function definitions to the library
+	// Add the module's handler and tools to the library
 	bot.AddHandlers(m.Handler)
-	bot.AddDefinitions(m.Name(), &functionDefinitions)
+	bot.AddTools(m.Name(), tools)
 }
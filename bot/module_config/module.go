@@ -25,7 +25,7 @@ func (m *Module) Name() string {
 // Handle a function call
 func (m *Module) Handler(function string, input *types.Input) any {
 	// Check for permissions
-	if input.Permissions|m.Permissions == 0 {
+	if input.Permissions&m.Permissions == 0 {
 		return nil
 	}
 
@@ -51,4 +51,9 @@ func NewModule(bot *horus.Bot, enabled bool) {
 	// Add the module's handler and function definitions to the bot
 	bot.AddHandlers(m.Handler)
 	bot.AddDefinitions(m.Name(), &functionDefinitions)
+
+	// Every function changes the user's settings, so each needs their confirmation
+	for name := range functions {
+		bot.AddSideEffects(name)
+	}
 }
@@ -0,0 +1,29 @@
+package module_config
+
+import (
+	"testing"
+
+	horus "github.com/ethanbaker/horus/bot"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerPermissions(t *testing.T) {
+	assert := assert.New(t)
+
+	bot := &horus.Bot{}
+	m := Module{Enabled: true, Permissions: horus.PERMISSIONS_PRVMODULES, Functions: functions, bot: bot}
+	input := func(permissions byte) *types.Input {
+		return &types.Input{Permissions: permissions, Parameters: objx.Map{"city": "Raleigh"}}
+	}
+
+	// Inputs without the module's permission are refused, even with other permissions
+	assert.Nil(m.Handler("set_city", input(horus.PERMISSIONS_NONE)))
+	assert.Nil(m.Handler("set_city", input(horus.PERMISSIONS_GPT|horus.PERMISSIONS_PUBMODULES)))
+	assert.Empty(bot.Memory.City)
+
+	// Inputs with the module's permission are handled
+	assert.Equal(`{"message": "successfully saved city"}`, m.Handler("set_city", input(horus.PERMISSIONS_GPT|horus.PERMISSIONS_PRVMODULES)))
+	assert.Equal("Raleigh", bot.Memory.City)
+}
@@ -45,7 +45,7 @@ const CONFIRM_MESSAGE = `Value saved successfully.
 <STRONG>Title<STRONG>: %v
 <STRONG>Path<STRONG>: %v
 <STRONG>Username<STRONG>: %v
-<STRONG>Password<STRONG>: <SPOILER>%v<SPOILER>
+<STRONG>Password<STRONG>: %v
 <STRONG>URL<STRONG>: %v
 <STRONG>Notes<STRONG>:
 %v
@@ -55,6 +55,22 @@ Is this the profile you want to save?`
 // Step names to update
 var stepNames []string = []string{"path", "username", "password", "URL", "notes (type 'none' if empty)"}
 
+// The ID of the profile form
+const PROFILE_FORM = "keepass_profile"
+
+// A form that asks for every value of a profile after its title at once
+var profileForm = types.Form{
+	ID:    PROFILE_FORM,
+	Title: "Password profile",
+	Fields: []types.FormField{
+		{Name: "path", Label: "Path", Placeholder: "/personal/email", Required: true},
+		{Name: "username", Label: "Username"},
+		{Name: "password", Label: "Password", Secret: true},
+		{Name: "url", Label: "URL"},
+		{Name: "notes", Label: "Notes", Multiline: true},
+	},
+}
+
 // Regex testing
 var titleRegex = regexp.MustCompile(`\W`)
 var pathRegex = regexp.MustCompile(`^(/[a-z-]+)+/?$`)
@@ -94,7 +110,7 @@ func get_keepass(bot *horus.Bot, input *types.Input) any {
 	// Send the output to the user
 	output := types.Output{}
 	output.Message = "File successfully sent!"
-	output.Data = types.FileOutput{Filename: "database.kdbx", Content: body}
+	output.Attachments = []types.FileOutput{{Filename: "database.kdbx", ContentType: "application/x-keepass2", Content: body}}
 
 	return &output
 }
@@ -130,6 +146,20 @@ func create_keepass_step(bot *horus.Bot, input *types.Input) *types.Output {
 		return &output
 	}
 
+	// Fill in the rest of the profile at once if the user answered the form
+	if form, ok := input.Data.(types.FormResponse); ok && form.ID == PROFILE_FORM && idx > 0 {
+		if err := applyForm(&profile, form.Values); err != nil {
+			return retryForm(bot, create_keepass_step, idx, err)
+		}
+
+		bot.EditVariable("keepass_profile", profile)
+		bot.AddQueuedFunctions(create_keepass_confirm)
+
+		output.Message = confirmMessage(profile)
+		output.Actions = types.ConfirmActions
+		return &output
+	}
+
 	// Update the correlaing field
 	switch idx {
 
@@ -182,13 +212,20 @@ func create_keepass_step(bot *horus.Bot, input *types.Input) *types.Output {
 		bot.AddQueuedFunctions(create_keepass_step)
 
 		output.Message = fmt.Sprintf(`Value saved successfully. Please enter the %v:`, stepNames[idx])
+
+		// Offer the rest of the profile as a form once the title is known
+		if idx == 0 {
+			form := profileForm
+			output.Form = &form
+		}
 		return &output
 	}
 
 	// Otherwise, ask the user for confirmation
 	bot.AddQueuedFunctions(create_keepass_confirm)
 
-	output.Message = fmt.Sprintf(CONFIRM_MESSAGE, profile.Title, profile.Path, profile.Username, profile.Password, profile.Url, profile.Notes)
+	output.Message = confirmMessage(profile)
+	output.Actions = types.ConfirmActions
 	return &output
 }
 
@@ -197,7 +234,7 @@ func create_keepass_confirm(bot *horus.Bot, input *types.Input) *types.Output {
 	output := types.Output{}
 
 	// Check for a yes
-	if !validation.ValidateConfirmation(input.Message) {
+	if !validation.ValidateStrictConfirmation(input.Message) {
 		output.Message = "Password profile creation abandoned."
 		return &output
 	}
@@ -252,6 +289,7 @@ func create_keepass_confirm(bot *horus.Bot, input *types.Input) *types.Output {
 		bot.AddQueuedFunctions(create_keepass_confirm)
 
 		output.Message = "There was an error saving your password. Try again?"
+		output.Actions = types.ConfirmActions
 		output.Error = errors.New(e.Message)
 
 		return &output
@@ -272,7 +310,7 @@ func update_keepass(bot *horus.Bot, input *types.Input) any {
 	bot.AddQueuedFunctions(update_keepass_step)
 
 	// Return a success message
-	return types.Output{Message: "Updated password profile started. Please enter the title: "}
+	return &types.Output{Message: "Updated password profile started. Please enter the title: "}
 }
 
 // Helper method to repeatedly get information from a password
@@ -293,6 +331,20 @@ func update_keepass_step(bot *horus.Bot, input *types.Input) *types.Output {
 		return &output
 	}
 
+	// Fill in the rest of the profile at once if the user answered the form
+	if form, ok := input.Data.(types.FormResponse); ok && form.ID == PROFILE_FORM && idx > 0 {
+		if err := applyForm(&profile, form.Values); err != nil {
+			return retryForm(bot, update_keepass_step, idx, err)
+		}
+
+		bot.EditVariable("keepass_profile", profile)
+		bot.AddQueuedFunctions(update_keepass_confirm)
+
+		output.Message = confirmMessage(profile)
+		output.Actions = types.ConfirmActions
+		return &output
+	}
+
 	// Update the correlaing field
 	switch idx {
 
@@ -345,13 +397,20 @@ func update_keepass_step(bot *horus.Bot, input *types.Input) *types.Output {
 		bot.AddQueuedFunctions(update_keepass_step)
 
 		output.Message = fmt.Sprintf(`Value saved successfully. Please enter the %v:`, stepNames[idx])
+
+		// Offer the rest of the profile as a form once the title is known
+		if idx == 0 {
+			form := profileForm
+			output.Form = &form
+		}
 		return &output
 	}
 
 	// Otherwise, ask the user for confirmation
 	bot.AddQueuedFunctions(update_keepass_confirm)
 
-	output.Message = fmt.Sprintf(CONFIRM_MESSAGE, profile.Title, profile.Path, profile.Username, profile.Password, profile.Url, profile.Notes)
+	output.Message = confirmMessage(profile)
+	output.Actions = types.ConfirmActions
 	return &output
 }
 
@@ -360,7 +419,7 @@ func update_keepass_confirm(bot *horus.Bot, input *types.Input) *types.Output {
 	output := types.Output{}
 
 	// Check for a yes
-	if !validation.ValidateConfirmation(input.Message) {
+	if !validation.ValidateStrictConfirmation(input.Message) {
 		output.Message = "Password profile creation abandoned."
 		return &output
 	}
@@ -415,6 +474,7 @@ func update_keepass_confirm(bot *horus.Bot, input *types.Input) *types.Output {
 		bot.AddQueuedFunctions(update_keepass_confirm)
 
 		output.Message = "There was an error saving your password. Try again?"
+		output.Actions = types.ConfirmActions
 		output.Error = errors.New(e.Message)
 
 		return &output
@@ -435,7 +495,7 @@ func delete_keepass(bot *horus.Bot, input *types.Input) any {
 	bot.AddQueuedFunctions(delete_keepass_step)
 
 	// Return a success message
-	return types.Output{Message: "Delete password profile started. Please enter the title: "}
+	return &types.Output{Message: "Delete password profile started. Please enter the title: "}
 }
 
 // Helper method to repeatedly get information from a password
@@ -495,6 +555,7 @@ func delete_keepass_step(bot *horus.Bot, input *types.Input) *types.Output {
 	bot.AddQueuedFunctions(delete_keepass_confirm)
 
 	output.Message = fmt.Sprintf(`Are you sure you want to delete <STRONG>%v<STRONG>?`, profile.Title)
+	output.Actions = types.ConfirmActions
 	return &output
 }
 
@@ -503,7 +564,7 @@ func delete_keepass_confirm(bot *horus.Bot, input *types.Input) *types.Output {
 	output := types.Output{}
 
 	// Check for a yes
-	if !validation.ValidateConfirmation(input.Message) {
+	if !validation.ValidateStrictConfirmation(input.Message) {
 		output.Message = "Password profile creation abandoned."
 		return &output
 	}
@@ -558,6 +619,7 @@ func delete_keepass_confirm(bot *horus.Bot, input *types.Input) *types.Output {
 		bot.AddQueuedFunctions(delete_keepass_confirm)
 
 		output.Message = "There was an error deleting your password. Try again?"
+		output.Actions = types.ConfirmActions
 		output.Error = errors.New(e.Message)
 
 		return &output
@@ -567,3 +629,41 @@ func delete_keepass_confirm(bot *horus.Bot, input *types.Input) *types.Output {
 	output.Message = "Password profile deleted successfully!"
 	return &output
 }
+
+/* ---- HELPERS ---- */
+
+// applyForm fills in a profile from the answers to the profile form
+func applyForm(profile *Profile, values map[string]string) error {
+	if !pathRegex.MatchString(values["path"]) {
+		return errors.New("invalid path")
+	}
+
+	profile.Path = values["path"]
+	profile.Username = values["username"]
+	profile.Password = values["password"]
+	profile.Url = values["url"]
+	profile.Notes = values["notes"]
+	return nil
+}
+
+// retryForm asks for the profile form again after its answers were rejected, so the user can fix
+// them. The step waiting for the answers is queued again
+func retryForm(bot *horus.Bot, step func(bot *horus.Bot, input *types.Input) *types.Output, idx int, err error) *types.Output {
+	bot.AddQueuedFunctions(step)
+
+	form := profileForm
+	return &types.Output{
+		Message: fmt.Sprintf("The form could not be saved (%v). Please fix it, or enter the %v:", err, stepNames[idx-1]),
+		Form:    &form,
+	}
+}
+
+// confirmMessage asks the user to confirm a profile. The password is never shown
+func confirmMessage(profile Profile) string {
+	password := "(hidden)"
+	if profile.Password == "" {
+		password = "(none)"
+	}
+
+	return fmt.Sprintf(CONFIRM_MESSAGE, profile.Title, profile.Path, profile.Username, password, profile.Url, profile.Notes)
+}
@@ -0,0 +1,57 @@
+package module_keepass
+
+import (
+	"testing"
+
+	horus "github.com/ethanbaker/horus/bot"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateKeepassForm(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(horus.InitDB(sqlite.Open("file:keepass?mode=memory&cache=shared")))
+	bot, err := horus.NewBot("horus-test", horus.PERMISSIONS_ALL)
+	assert.Nil(err)
+	assert.Nil(bot.AddConversation("main"))
+
+	send := func(input *types.Input) *types.Output {
+		input.Permissions = horus.PERMISSIONS_ALL
+		output, err := bot.SendMessage("main", input)
+		assert.Nil(err)
+		return output
+	}
+
+	// The form is offered once the title is known
+	create_keepass(bot, &types.Input{})
+	output := send(&types.Input{Message: "github"})
+	assert.Equal("Value saved successfully. Please enter the path:", output.Message)
+	assert.Equal(PROFILE_FORM, output.Form.ID)
+
+	// Invalid answers ask for the form again instead of ending the wizard
+	output = send(&types.Input{Data: types.FormResponse{ID: PROFILE_FORM, Values: map[string]string{"path": "not a path", "password": "hunter2"}}})
+	assert.Nil(output.Error)
+	assert.Equal("The form could not be saved (invalid path). Please fix it, or enter the path:", output.Message)
+	assert.Equal(PROFILE_FORM, output.Form.ID)
+	assert.Equal(Profile{Title: "github"}, bot.GetVariable("keepass_profile"))
+
+	// Fixed answers continue to the confirmation
+	output = send(&types.Input{Data: types.FormResponse{ID: PROFILE_FORM, Values: map[string]string{"path": "/personal/code", "username": "horus", "password": "hunter2"}}})
+	assert.Nil(output.Error)
+	assert.Equal(types.ConfirmActions, output.Actions)
+	assert.Contains(output.Message, "<STRONG>Path<STRONG>: /personal/code")
+	assert.NotContains(output.Message, "hunter2")
+
+	output = send(&types.Input{Message: "no"})
+	assert.Equal("Password profile creation abandoned.", output.Message)
+
+	// The wizard can still be answered step by step after a rejected form
+	create_keepass(bot, &types.Input{})
+	send(&types.Input{Message: "mail"})
+	output = send(&types.Input{Data: types.FormResponse{ID: PROFILE_FORM, Values: map[string]string{"path": ""}}})
+	assert.Equal("The form could not be saved (invalid path). Please fix it, or enter the path:", output.Message)
+	output = send(&types.Input{Message: "/personal/mail"})
+	assert.Equal("Value saved successfully. Please enter the username:", output.Message)
+}
@@ -25,7 +25,7 @@ func (m *Module) Name() string {
 // Handle a function call
 func (m *Module) Handler(function string, input *types.Input) any {
 	// Check for permissions
-	if input.Permissions|m.Permissions == 0 {
+	if input.Permissions&m.Permissions == 0 {
 		return nil
 	}
 
@@ -0,0 +1,25 @@
+package horus
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParsePermissions parses a comma separated list of permission names (ex: "gpt, public"). An
+// empty list has no permissions
+func ParsePermissions(value string) (byte, error) {
+	var permissions byte
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+
+		p, ok := PERMISSION_NAMES[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown permission '%v' (must be one of all, modules, public, private, gpt or none)", name)
+		}
+		permissions |= p
+	}
+
+	return permissions, nil
+}
@@ -0,0 +1,28 @@
+package horus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePermissions(t *testing.T) {
+	tests := []struct {
+		value       string
+		permissions byte
+		err         bool
+	}{
+		{"all", PERMISSIONS_ALL, false},
+		{"gpt, public", PERMISSIONS_GPT | PERMISSIONS_PUBMODULES, false},
+		{"gpt,modules", PERMISSIONS_GPT | PERMISSIONS_ALLMODULES, false},
+		{"none", PERMISSIONS_NONE, false},
+		{"", PERMISSIONS_NONE, false},
+		{"gpt,privte", 0, true},
+	}
+
+	for _, test := range tests {
+		permissions, err := ParsePermissions(test.value)
+		assert.Equal(t, test.err, err != nil, test.value)
+		assert.Equal(t, test.permissions, permissions, test.value)
+	}
+}
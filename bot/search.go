@@ -0,0 +1,411 @@
+package horus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Whether the database supports native full-text search. If not, the search_terms fallback index is used
+var nativeSearch bool
+
+// SearchQuery describes a full-text search over message content, tool call arguments and tool results
+type SearchQuery struct {
+	Text         string    // The text to search for
+	BotID        uint      // Only search conversations belonging to this bot (0 searches every bot)
+	Conversation string    // Only search the conversation with this name
+	Role         string    // Only search messages with this role (ex: user, assistant, tool)
+	After        time.Time // Only search messages created after this time
+	Before       time.Time // Only search messages created before this time
+	Limit        int       // The maximum amount of results to return (defaults to SEARCH_LIMIT)
+}
+
+// SearchResult represents a single ranked match returned from a search
+type SearchResult struct {
+	BotID            uint      `json:"bot_id"`
+	ConversationID   uint      `json:"conversation_id"`
+	ConversationName string    `json:"conversation"`
+	MessageID        uint      `json:"message_id"`
+	Idx              uint      `json:"index"`     // The index of the message in the conversation
+	Role             string    `json:"role"`      // The role of the message that matched
+	Source           string    `json:"source"`    // Where the match was found (SEARCH_SOURCE_CONTENT or SEARCH_SOURCE_TOOL)
+	ToolName         string    `json:"tool_name"` // The name of the tool for tool calls and tool results
+	Snippet          string    `json:"snippet"`   // An excerpt of the match with matched terms in <STRONG> tags
+	Score            float64   `json:"score"`     // The relevance of the match (higher is better)
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// SearchTerm is an entry in the fallback search index used for databases without native
+// full-text support (such as SQLite)
+type SearchTerm struct {
+	ID        uint   `gorm:"primarykey"`
+	Term      string `gorm:"size:64;index"` // The normalized search term
+	MessageID uint   `gorm:"index"`         // The message the term appears in
+	Source    string `gorm:"size:16"`       // Where in the message the term appears
+	ToolName  string // The tool name of the tool call or tool result
+	Count     int    // How many times the term appears
+}
+
+// searchRow is used to scan search queries before they are turned into results
+type searchRow struct {
+	MessageID        uint
+	ConversationID   uint
+	ConversationName string
+	BotID            uint
+	Idx              uint
+	Role             string
+	Name             string
+	Text             string
+	Source           string
+	Score            float64
+	CreatedAt        time.Time
+}
+
+// Search searches the bot's conversations. The query's BotID is always set to this bot
+func (b *Bot) Search(query SearchQuery) ([]SearchResult, error) {
+	query.BotID = b.Model.ID
+	return Search(query)
+}
+
+// Search searches message content, tool call arguments and tool results, returning results
+// ranked by relevance
+func Search(query SearchQuery) ([]SearchResult, error) {
+	terms := searchTerms(query.Text)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("search query must contain at least one searchable term")
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = SEARCH_LIMIT
+	}
+
+	// Find matches using the native index if possible
+	var rows []searchRow
+	var err error
+	if nativeSearch {
+		rows, err = searchNative(query)
+	} else {
+		rows, err = searchFallback(query, terms)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Rank the rows and build the results
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Score > rows[j].Score
+	})
+	if len(rows) > query.Limit {
+		rows = rows[:query.Limit]
+	}
+
+	results := make([]SearchResult, 0, len(rows))
+	for _, r := range rows {
+		// Tool results are stored as messages named after the tool
+		toolName := ""
+		if r.Source == SEARCH_SOURCE_TOOL || r.Role == "tool" {
+			toolName = r.Name
+		}
+
+		results = append(results, SearchResult{
+			BotID:            r.BotID,
+			ConversationID:   r.ConversationID,
+			ConversationName: r.ConversationName,
+			MessageID:        r.MessageID,
+			Idx:              r.Idx,
+			Role:             r.Role,
+			Source:           r.Source,
+			ToolName:         toolName,
+			Snippet:          snippet(r.Text, terms),
+			Score:            r.Score,
+			CreatedAt:        r.CreatedAt,
+		})
+	}
+
+	return results, nil
+}
+
+// ReindexSearch rebuilds the fallback search index from every stored message. This only
+// needs to be called when switching an existing database to one without native full-text search
+func ReindexSearch() error {
+	if nativeSearch {
+		return nil
+	}
+
+	// Clear the existing index
+	if err := db.Where("1 = 1").Delete(&SearchTerm{}).Error; err != nil {
+		return err
+	}
+
+	// Index every message in batches
+	var messages []Message
+	return db.Preload("ToolCalls").FindInBatches(&messages, 100, func(tx *gorm.DB, batch int) error {
+		for i := range messages {
+			if err := indexMessage(&messages[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// setupSearch creates the native full-text indexes if the database supports them
+func setupSearch() error {
+	nativeSearch = db.Dialector.Name() == "mysql"
+	if !nativeSearch {
+		return nil
+	}
+
+	indexes := []struct {
+		model  any
+		name   string
+		table  string
+		column string
+	}{
+		{&Message{}, "idx_messages_content_fulltext", "messages", "content"},
+		{&ToolCall{}, "idx_tool_calls_call_arguments_fulltext", "tool_calls", "call_arguments"},
+	}
+
+	for _, idx := range indexes {
+		if db.Migrator().HasIndex(idx.model, idx.name) {
+			continue
+		}
+
+		if err := db.Exec(fmt.Sprintf("CREATE FULLTEXT INDEX %s ON %s (%s)", idx.name, idx.table, idx.column)).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// searchNative searches using MySQL's full-text indexes
+func searchNative(query SearchQuery) ([]searchRow, error) {
+	rows := []searchRow{}
+
+	// Search message content (this includes tool results)
+	var content []searchRow
+	tx := db.Table("messages").
+		Select("messages.id AS message_id, messages.conversation_id, conversations.name AS conversation_name, conversations.bot_id, messages.idx, messages.role, messages.name, messages.content AS text, ? AS source, MATCH(messages.content) AGAINST (?) AS score, messages.created_at", SEARCH_SOURCE_CONTENT, query.Text).
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id AND conversations.deleted_at IS NULL").
+		Where("messages.deleted_at IS NULL AND MATCH(messages.content) AGAINST (?)", query.Text)
+
+	if err := filterSearch(tx, query).Order("score DESC").Limit(query.Limit).Scan(&content).Error; err != nil {
+		return nil, err
+	}
+	rows = append(rows, content...)
+
+	// Search tool call arguments
+	var calls []searchRow
+	tx = db.Table("tool_calls").
+		Select("messages.id AS message_id, messages.conversation_id, conversations.name AS conversation_name, conversations.bot_id, messages.idx, messages.role, tool_calls.call_name AS name, tool_calls.call_arguments AS text, ? AS source, MATCH(tool_calls.call_arguments) AGAINST (?) AS score, messages.created_at", SEARCH_SOURCE_TOOL, query.Text).
+		Joins("JOIN messages ON messages.id = tool_calls.message_id AND messages.deleted_at IS NULL").
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id AND conversations.deleted_at IS NULL").
+		Where("tool_calls.deleted_at IS NULL AND MATCH(tool_calls.call_arguments) AGAINST (?)", query.Text)
+
+	if err := filterSearch(tx, query).Order("score DESC").Limit(query.Limit).Scan(&calls).Error; err != nil {
+		return nil, err
+	}
+	rows = append(rows, calls...)
+
+	return rows, nil
+}
+
+// searchFallback searches using the search_terms index. Results are ranked by the amount of
+// distinct terms matched, then by how often they appear
+func searchFallback(query SearchQuery, terms []string) ([]searchRow, error) {
+	var rows []searchRow
+	tx := db.Table("search_terms").
+		Select("search_terms.message_id, messages.conversation_id, conversations.name AS conversation_name, conversations.bot_id, messages.idx, messages.role, search_terms.tool_name AS name, messages.content AS text, search_terms.source, COUNT(DISTINCT search_terms.term) + 1.0 - 1.0 / (1 + SUM(search_terms.count)) AS score, messages.created_at").
+		Joins("JOIN messages ON messages.id = search_terms.message_id AND messages.deleted_at IS NULL").
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id AND conversations.deleted_at IS NULL").
+		Where("search_terms.term IN ?", terms).
+		Group("search_terms.message_id, messages.conversation_id, conversations.name, conversations.bot_id, messages.idx, messages.role, search_terms.tool_name, messages.content, search_terms.source, messages.created_at")
+
+	if err := filterSearch(tx, query).Order("score DESC").Limit(query.Limit).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	// Tool call matches show the call's arguments instead of the message content
+	for i := range rows {
+		if rows[i].Source != SEARCH_SOURCE_TOOL {
+			continue
+		}
+
+		var call ToolCall
+		if err := db.Where("message_id = ? AND call_name = ?", rows[i].MessageID, rows[i].Name).First(&call).Error; err != nil {
+			return nil, err
+		}
+		rows[i].Text = call.CallArguments
+	}
+
+	return rows, nil
+}
+
+// filterSearch adds the query's filters to a search
+func filterSearch(tx *gorm.DB, query SearchQuery) *gorm.DB {
+	if query.BotID != 0 {
+		tx = tx.Where("conversations.bot_id = ?", query.BotID)
+	}
+	if query.Conversation != "" {
+		tx = tx.Where("conversations.name = ?", query.Conversation)
+	}
+	if query.Role != "" {
+		tx = tx.Where("messages.role = ?", query.Role)
+	}
+	if !query.After.IsZero() {
+		tx = tx.Where("messages.created_at >= ?", query.After)
+	}
+	if !query.Before.IsZero() {
+		tx = tx.Where("messages.created_at <= ?", query.Before)
+	}
+
+	return tx
+}
+
+// indexMessage adds a message's content and tool call arguments to the fallback index
+func indexMessage(m *Message) error {
+	if nativeSearch {
+		return nil
+	}
+
+	// Tool results are named after the tool that produced them
+	toolName := ""
+	if m.Role == "tool" {
+		toolName = m.Name
+	}
+
+	entries := searchEntries(m.Model.ID, SEARCH_SOURCE_CONTENT, toolName, m.Content)
+	for _, call := range m.ToolCalls {
+		entries = append(entries, searchEntries(m.Model.ID, SEARCH_SOURCE_TOOL, call.CallName, call.CallArguments)...)
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+	return db.Create(&entries).Error
+}
+
+// unindexMessage removes a message from the fallback index
+func unindexMessage(m *Message) error {
+	if nativeSearch {
+		return nil
+	}
+
+	return db.Where("message_id = ?", m.Model.ID).Delete(&SearchTerm{}).Error
+}
+
+// searchEntries creates index entries for every term in a piece of text
+func searchEntries(messageID uint, source string, toolName string, text string) []SearchTerm {
+	counts := map[string]int{}
+	order := []string{}
+	for _, term := range searchTerms(text) {
+		if counts[term] == 0 {
+			order = append(order, term)
+		}
+		counts[term]++
+	}
+
+	entries := make([]SearchTerm, 0, len(order))
+	for _, term := range order {
+		entries = append(entries, SearchTerm{
+			Term:      term,
+			MessageID: messageID,
+			Source:    source,
+			ToolName:  toolName,
+			Count:     counts[term],
+		})
+	}
+
+	return entries
+}
+
+// searchTerms splits text into lowercase terms made of letters and numbers. Single characters are ignored
+func searchTerms(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	terms := make([]string, 0, len(fields))
+	for _, f := range fields {
+		r := []rune(f)
+		if len(r) < 2 {
+			continue
+		}
+		if len(r) > 64 {
+			r = r[:64]
+		}
+		terms = append(terms, string(r))
+	}
+
+	return terms
+}
+
+// snippet returns an excerpt of text around the first matched term, with every matched
+// term wrapped in <STRONG> tags
+func snippet(text string, terms []string) string {
+	original := []rune(text)
+
+	// Lowercase rune by rune so indexes line up with the original text
+	lower := make([]rune, len(original))
+	for i, r := range original {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// Find every match in the text
+	type match struct{ start, end int }
+	matches := []match{}
+	for i := 0; i < len(lower); i++ {
+		for _, term := range terms {
+			t := []rune(term)
+			if i+len(t) <= len(lower) && string(lower[i:i+len(t)]) == term {
+				matches = append(matches, match{i, i + len(t)})
+				i += len(t) - 1
+				break
+			}
+		}
+	}
+
+	// Find the window surrounding the first match
+	start, end := 0, len(original)
+	if len(matches) > 0 {
+		start = matches[0].start - SEARCH_SNIPPET_RADIUS
+		end = matches[0].end + SEARCH_SNIPPET_RADIUS
+	} else {
+		end = 2 * SEARCH_SNIPPET_RADIUS
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > len(original) {
+		end = len(original)
+	}
+
+	// Build the snippet, highlighting matches inside of the window
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+
+	pos := start
+	for _, m := range matches {
+		if m.start < start || m.end > end {
+			continue
+		}
+
+		sb.WriteString(string(original[pos:m.start]))
+		sb.WriteString("<STRONG>" + string(original[m.start:m.end]) + "<STRONG>")
+		pos = m.end
+	}
+	sb.WriteString(string(original[pos:end]))
+
+	if end < len(original) {
+		sb.WriteString("…")
+	}
+
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
@@ -0,0 +1,121 @@
+package horus
+
+import (
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchTerms(t *testing.T) {
+	assert := assert.New(t)
+
+	// Terms are lowercase letters and numbers, and single characters are ignored
+	assert.Equal([]string{"what", "the", "weather", "in", "new", "york", "2024"}, searchTerms("What's the weather in New-York, 2024?"))
+	assert.Equal([]string{"café", "naïve", "東京"}, searchTerms("Café, naïve! 東京 a 1"))
+	assert.Empty(searchTerms("a b c - !"))
+
+	// Long terms are cut to fit the index
+	assert.Equal([]string{strings.Repeat("é", 64)}, searchTerms(strings.Repeat("É", 70)))
+}
+
+func TestSnippet(t *testing.T) {
+	assert := assert.New(t)
+
+	// Matches keep their original case
+	assert.Equal("<STRONG>Weather<STRONG> in Paris is <STRONG>weather<STRONG>", snippet("Weather in Paris is weather", []string{"weather"}))
+
+	// Matches at the start and end of long text only cut the other side, keeping the radius around the match
+	long := strings.Repeat("filler ", 40)
+	assert.Equal("<STRONG>Start<STRONG> "+strings.TrimSpace(long[:SEARCH_SNIPPET_RADIUS-1])+"…", snippet("Start "+long, []string{"start"}))
+	assert.Equal("…"+strings.TrimSpace(long[len(long)-SEARCH_SNIPPET_RADIUS:])+" <STRONG>end<STRONG>", snippet(long+"end", []string{"end"}))
+
+	// Matches in the middle are cut on both sides, and later matches outside the window aren't shown
+	result := snippet(long+"middle "+long+"middle", []string{"middle"})
+	assert.True(strings.HasPrefix(result, "…"))
+	assert.True(strings.HasSuffix(result, "…"))
+	assert.Equal(1, strings.Count(result, "<STRONG>middle<STRONG>"))
+
+	// Multibyte text is cut on runes, with matches lined up with the original text
+	text := strings.Repeat("日本語", 30) + "ÉTÉ" + strings.Repeat("日本語", 30)
+	result = snippet(text, []string{"été"})
+	assert.Equal("…"+strings.Repeat("日本語", 20)+"<STRONG>ÉTÉ<STRONG>"+strings.Repeat("日本語", 20)+"…", result)
+
+	// Text without matches shows its start
+	assert.Equal("no matches here", snippet("no matches here", []string{"missing"}))
+}
+
+func TestSearchFallback(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBot(t)
+	assert.False(nativeSearch)
+
+	// Add messages to two conversations, including a tool call and its result
+	assert.Nil(b.AddConversation("weather"))
+	assert.Nil(b.AddMessage("weather", openai.ChatMessageRoleUser, "user", "What is the weather in Paris today?"))
+	assert.Nil(b.AddMessage("weather", openai.ChatMessageRoleAssistant, "", "It is sunny in Paris."))
+
+	conversation := b.getConversation("weather")
+	assert.Nil(conversation.AddFunctionCall(&openai.ChatCompletionMessage{
+		Role: openai.ChatMessageRoleAssistant,
+		ToolCalls: []openai.ToolCall{{
+			ID:       "call-1",
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: "get_current_weather", Arguments: `{"location": "London"}`},
+		}},
+	}))
+	assert.Nil(conversation.addToolResult(openai.ToolCall{ID: "call-1", Function: openai.FunctionCall{Name: "get_current_weather"}}, `{"forecast": "rain"}`))
+
+	assert.Nil(b.AddConversation("travel"))
+	assert.Nil(b.AddMessage("travel", openai.ChatMessageRoleUser, "user", "Book a train to Paris"))
+
+	// Messages matching more terms rank first
+	results, err := b.Search(SearchQuery{Text: "weather Paris"})
+	assert.Nil(err)
+	assert.Len(results, 3)
+	assert.Equal("What is the <STRONG>weather<STRONG> in <STRONG>Paris<STRONG> today?", results[0].Snippet)
+	assert.Equal("weather", results[0].ConversationName)
+	assert.Equal(SEARCH_SOURCE_CONTENT, results[0].Source)
+
+	// Filters limit the results
+	results, err = b.Search(SearchQuery{Text: "paris", Conversation: "travel"})
+	assert.Nil(err)
+	assert.Len(results, 1)
+	assert.Equal("Book a train to <STRONG>Paris<STRONG>", results[0].Snippet)
+
+	results, err = b.Search(SearchQuery{Text: "paris", Role: openai.ChatMessageRoleAssistant})
+	assert.Nil(err)
+	assert.Len(results, 1)
+
+	results, err = b.Search(SearchQuery{Text: "paris", Limit: 1})
+	assert.Nil(err)
+	assert.Len(results, 1)
+
+	// Tool call arguments and tool results are searchable
+	results, err = b.Search(SearchQuery{Text: "london"})
+	assert.Nil(err)
+	assert.Len(results, 1)
+	assert.Equal(SEARCH_SOURCE_TOOL, results[0].Source)
+	assert.Equal("get_current_weather", results[0].ToolName)
+	assert.Equal(`{"location": "<STRONG>London<STRONG>"}`, results[0].Snippet)
+
+	results, err = b.Search(SearchQuery{Text: "rain"})
+	assert.Nil(err)
+	assert.Len(results, 1)
+	assert.Equal("get_current_weather", results[0].ToolName)
+
+	// Deleted conversations are removed from the index
+	assert.Nil(b.DeleteConversation("travel"))
+	results, err = b.Search(SearchQuery{Text: "train"})
+	assert.Nil(err)
+	assert.Empty(results)
+
+	var count int64
+	assert.Nil(db.Model(&SearchTerm{}).Where("term = ?", "train").Count(&count).Error)
+	assert.Zero(count)
+
+	// Queries need a searchable term
+	_, err = b.Search(SearchQuery{Text: "a ?"})
+	assert.NotNil(err)
+}
@@ -0,0 +1,271 @@
+package horus
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SessionPolicy configures how channel keys are resolved to conversations and how long old
+// conversations are kept around
+type SessionPolicy struct {
+	IdleTimeout  time.Duration // Start a new conversation after this much silence in a channel (0 never rotates)
+	ArchiveAfter time.Duration // Archive conversations that have been inactive for this long (0 never archives)
+	Retention    time.Duration // Delete archived conversations this long after they were archived (0 keeps them forever)
+}
+
+// Session maps a channel key from an implementation (such as a Discord channel) to the
+// conversation that is currently active in that channel
+type Session struct {
+	gorm.Model
+
+	BotID        uint      // The foreign key to relate the session to a bot
+	Key          string    // The implementation's key for the channel
+	Conversation string    // The name of the active conversation
+	LastActivity time.Time // The last time a message was sent in the channel
+
+	IdleTimeout *time.Duration // Overrides the policy's idle timeout in the channel (nil uses the policy)
+	Modules     string         // A comma separated list of the modules the channel can use (empty allows every module)
+}
+
+// SessionSettings configures a single channel. Settings are kept with the channel's session, so
+// they last across restarts
+type SessionSettings struct {
+	IdleTimeout *time.Duration // Start a new conversation after this much silence (nil uses the policy's idle timeout)
+	Modules     []string       // The modules conversations in the channel can use (empty allows every module)
+}
+
+// SetSessionPolicy sets the policy used to resolve and clean up sessions
+func (b *Bot) SetSessionPolicy(policy SessionPolicy) {
+	b.sessionPolicy = policy
+}
+
+// ResolveSession returns the name of the active conversation for a channel key. A new
+// conversation is started if the channel has none, the conversation was archived, or the
+// channel has been idle for longer than the policy's idle timeout
+func (b *Bot) ResolveSession(key string) (string, error) {
+	now := time.Now().UTC()
+
+	session, err := b.getSession(key)
+	if err != nil {
+		return "", err
+	}
+
+	// Determine if the channel needs a new conversation
+	rotate := session.Conversation == "" || !b.IsConversation(session.Conversation) || b.IsArchived(session.Conversation)
+	idleTimeout := b.sessionPolicy.IdleTimeout
+	if session.IdleTimeout != nil {
+		idleTimeout = *session.IdleTimeout
+	}
+	if idleTimeout > 0 && session.LastActivity.Add(idleTimeout).Before(now) {
+		rotate = true
+	}
+
+	if rotate {
+		if err := b.startSession(session, now); err != nil {
+			return "", err
+		}
+	}
+
+	// Record the activity in the channel
+	session.LastActivity = now
+	return session.Conversation, db.Save(session).Error
+}
+
+// RotateSession always starts a new conversation for a channel key and returns its name
+func (b *Bot) RotateSession(key string) (string, error) {
+	now := time.Now().UTC()
+
+	session, err := b.getSession(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := b.startSession(session, now); err != nil {
+		return "", err
+	}
+
+	session.LastActivity = now
+	return session.Conversation, db.Save(session).Error
+}
+
+// GetSessionSettings returns the settings of a channel key
+func (b *Bot) GetSessionSettings(key string) (SessionSettings, error) {
+	session, err := b.getSession(key)
+	if err != nil {
+		return SessionSettings{}, err
+	}
+
+	settings := SessionSettings{IdleTimeout: session.IdleTimeout}
+	if session.Modules != "" {
+		settings.Modules = strings.Split(session.Modules, ",")
+	}
+	return settings, nil
+}
+
+// SetSessionSettings changes the settings of a channel key. Channels without a session get one,
+// which starts its conversation on the channel's first message
+func (b *Bot) SetSessionSettings(key string, settings SessionSettings) error {
+	session, err := b.getSession(key)
+	if err != nil {
+		return err
+	}
+
+	// Make sure every module exists
+	modules := []string{}
+	for _, m := range settings.Modules {
+		if m = strings.TrimSpace(m); m == "" {
+			continue
+		}
+		if !isAllowed(b.Modules(), m) {
+			return fmt.Errorf("module '%s' does not exist", m)
+		}
+		modules = append(modules, m)
+	}
+
+	session.IdleTimeout = settings.IdleTimeout
+	session.Modules = strings.Join(modules, ",")
+	return db.Save(session).Error
+}
+
+// ArchiveConversation marks a conversation as read-only. Archived conversations can still be
+// read and searched, but no new messages can be added to them
+func (b *Bot) ArchiveConversation(key string) error {
+	conversation := b.getConversation(key)
+	if conversation == nil {
+		return fmt.Errorf("conversation with key '%s' does not exist", key)
+	}
+
+	if conversation.ArchivedAt != nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	conversation.ArchivedAt = &now
+	return db.Model(conversation).Update("archived_at", now).Error
+}
+
+// IsArchived returns true if the conversation exists and is archived
+func (b *Bot) IsArchived(key string) bool {
+	conversation := b.getConversation(key)
+	return conversation != nil && conversation.ArchivedAt != nil
+}
+
+// ApplySessionPolicy archives inactive conversations and deletes archived conversations that
+// are past the policy's retention period
+func (b *Bot) ApplySessionPolicy() error {
+	now := time.Now().UTC()
+	policy := b.sessionPolicy
+
+	// Find which conversations should be archived or purged
+	archive := []string{}
+	purge := []string{}
+	for _, c := range b.Conversations {
+		if c.ArchivedAt == nil && policy.ArchiveAfter > 0 && c.UpdatedAt.Add(policy.ArchiveAfter).Before(now) {
+			archive = append(archive, c.Name)
+		} else if c.ArchivedAt != nil && policy.Retention > 0 && c.ArchivedAt.Add(policy.Retention).Before(now) {
+			purge = append(purge, c.Name)
+		}
+	}
+
+	for _, name := range archive {
+		if err := b.ArchiveConversation(name); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range purge {
+		if err := b.DeleteConversation(name); err != nil {
+			return err
+		}
+
+		// Sessions pointing at the purged conversation start over on their next message but keep
+		// their settings. Settings kept for the conversation itself are removed with it
+		if err := db.Model(&Session{}).Where("bot_id = ? AND conversation = ?", b.Model.ID, name).Update("conversation", "").Error; err != nil {
+			return err
+		}
+		if err := db.Where("bot_id = ? AND `key` = ?", b.Model.ID, name).Delete(&Session{}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Maintain applies the session policy and prunes tool audit records past their retention. Like
+// the bot's other methods it is not safe for concurrent use, so it must be called from the
+// goroutine that uses the bot (such as through a runner's queue in an implementation)
+func (b *Bot) Maintain() error {
+	if err := b.ApplySessionPolicy(); err != nil {
+		return err
+	}
+
+	return b.PruneToolAudits()
+}
+
+// getSession finds the session for a channel key, returning an unsaved session if one does not exist
+func (b *Bot) getSession(key string) (*Session, error) {
+	if key == "" {
+		return nil, fmt.Errorf("session key cannot be empty")
+	}
+
+	session := Session{}
+	err := db.Where(map[string]any{"bot_id": b.Model.ID, "key": key}).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Session{BotID: b.Model.ID, Key: key}, nil
+	}
+
+	return &session, err
+}
+
+// allowedModules returns the modules a conversation can use, or nil if it can use every module.
+// A conversation follows the settings of the session it is active in, or of the session with its
+// name as the key (such as a thread registered as its own conversation)
+func (b *Bot) allowedModules(conversation string) []string {
+	session := Session{}
+	err := db.Where("bot_id = ? AND (conversation = ? OR `key` = ?)", b.Model.ID, conversation, conversation).First(&session).Error
+	if err != nil || session.Modules == "" {
+		return nil
+	}
+
+	return strings.Split(session.Modules, ",")
+}
+
+// moduleAllowed returns whether a conversation can use a module
+func (b *Bot) moduleAllowed(conversation string, module string) bool {
+	return isAllowed(b.allowedModules(conversation), module)
+}
+
+// isAllowed returns whether a module is in a list of allowed modules (nil allows every module)
+func isAllowed(allowed []string, module string) bool {
+	if allowed == nil {
+		return true
+	}
+
+	for _, m := range allowed {
+		if m == module {
+			return true
+		}
+	}
+	return false
+}
+
+// startSession starts a new conversation for a session
+func (b *Bot) startSession(session *Session, now time.Time) error {
+	name := fmt.Sprintf("%v-%v", session.Key, now.Unix())
+
+	// Make sure the name is unique if the channel rotates twice in one second
+	for i := 1; b.IsConversation(name); i++ {
+		name = fmt.Sprintf("%v-%v-%v", session.Key, now.Unix(), i)
+	}
+
+	if err := b.AddConversation(name); err != nil {
+		return err
+	}
+
+	session.Conversation = name
+	return nil
+}
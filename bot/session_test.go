@@ -0,0 +1,279 @@
+package horus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// idleFor makes a channel look like it has been silent for a duration
+func idleFor(t *testing.T, b *Bot, key string, idle time.Duration) {
+	t.Helper()
+
+	if err := db.Model(&Session{}).Where("bot_id = ? AND `key` = ?", b.Model.ID, key).Update("last_activity", time.Now().UTC().Add(-idle)).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveSession(t *testing.T) {
+	hour := time.Hour
+	never := time.Duration(0)
+
+	tests := []struct {
+		name     string
+		policy   SessionPolicy
+		override *time.Duration // The channel's idle timeout setting
+		idle     time.Duration  // How long the channel has been silent
+		archive  bool           // Whether the active conversation is archived
+		rotate   bool           // Whether a new conversation is expected
+	}{
+		{name: "active", policy: SessionPolicy{IdleTimeout: hour}, idle: time.Minute},
+		{name: "idle", policy: SessionPolicy{IdleTimeout: hour}, idle: 2 * time.Hour, rotate: true},
+		{name: "no_timeout", idle: 1000 * time.Hour},
+		{name: "override_longer", policy: SessionPolicy{IdleTimeout: time.Minute}, override: &hour, idle: 10 * time.Minute},
+		{name: "override_never", policy: SessionPolicy{IdleTimeout: time.Minute}, override: &never, idle: 1000 * time.Hour},
+		{name: "override_shorter", override: &hour, idle: 2 * time.Hour, rotate: true},
+		{name: "archived", archive: true, rotate: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			b := newTestBot(t)
+			b.SetSessionPolicy(test.policy)
+
+			// The first message in a channel starts a conversation
+			first, err := b.ResolveSession("channel")
+			assert.Nil(err)
+			assert.True(b.IsConversation(first))
+
+			if test.override != nil {
+				assert.Nil(b.SetSessionSettings("channel", SessionSettings{IdleTimeout: test.override}))
+			}
+			if test.archive {
+				assert.Nil(b.ArchiveConversation(first))
+			}
+			idleFor(t, b, "channel", test.idle)
+
+			second, err := b.ResolveSession("channel")
+			assert.Nil(err)
+			assert.Equal(test.rotate, first != second)
+			assert.True(b.IsConversation(second))
+			assert.False(b.IsArchived(second))
+
+			// Resolving records the activity, so the channel is no longer idle
+			third, err := b.ResolveSession("channel")
+			assert.Nil(err)
+			assert.Equal(second, third)
+		})
+	}
+
+	_, err := newTestBot(t).ResolveSession("")
+	assert.NotNil(t, err)
+}
+
+func TestRotateSession(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBot(t)
+	addTestModule(b, PERMISSIONS_PRVMODULES)
+
+	first, err := b.ResolveSession("channel")
+	assert.Nil(err)
+	assert.Nil(b.SetSessionSettings("channel", SessionSettings{Modules: []string{"test"}}))
+
+	// Rotating always starts a new conversation, even twice in one second, and keeps the settings
+	second, err := b.RotateSession("channel")
+	assert.Nil(err)
+	third, err := b.RotateSession("channel")
+	assert.Nil(err)
+	assert.NotEqual(first, second)
+	assert.NotEqual(second, third)
+	assert.True(b.IsConversation(third))
+
+	resolved, err := b.ResolveSession("channel")
+	assert.Nil(err)
+	assert.Equal(third, resolved)
+
+	settings, err := b.GetSessionSettings("channel")
+	assert.Nil(err)
+	assert.Equal([]string{"test"}, settings.Modules)
+}
+
+func TestArchiveConversation(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBot(t)
+	assert.Nil(b.AddConversation("main"))
+
+	assert.NotNil(b.ArchiveConversation("missing"))
+	assert.False(b.IsArchived("missing"))
+
+	// Archiving twice keeps the first time
+	assert.Nil(b.ArchiveConversation("main"))
+	archivedAt := *b.getConversation("main").ArchivedAt
+	assert.Nil(b.ArchiveConversation("main"))
+	assert.Equal(archivedAt, *b.getConversation("main").ArchivedAt)
+	assert.True(b.IsArchived("main"))
+
+	// Archived conversations refuse messages, and stay archived when loaded again
+	messages := len(b.getConversation("main").Messages)
+	_, err := b.SendMessage("main", &types.Input{Message: "hello", Permissions: PERMISSIONS_ALL})
+	assert.EqualError(err, "conversation with key 'main' is archived")
+	assert.Len(b.getConversation("main").Messages, messages)
+
+	loaded, err := GetBotByName(b.Name)
+	assert.Nil(err)
+	assert.True(loaded.IsArchived("main"))
+}
+
+func TestApplySessionPolicy(t *testing.T) {
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name     string
+		policy   SessionPolicy
+		inactive time.Duration // How long the conversation has been inactive
+		archived time.Duration // How long ago the conversation was archived (0 isn't archived)
+		archive  bool          // Whether the conversation is expected to be archived
+		purge    bool          // Whether the conversation is expected to be deleted
+	}{
+		{name: "recent", policy: SessionPolicy{ArchiveAfter: day}, inactive: time.Hour},
+		{name: "inactive", policy: SessionPolicy{ArchiveAfter: day}, inactive: 2 * day, archive: true},
+		{name: "never_archives", inactive: 1000 * day},
+		{name: "retained", policy: SessionPolicy{Retention: 7 * day}, archived: day, archive: true},
+		{name: "expired", policy: SessionPolicy{Retention: 7 * day}, archived: 8 * day, purge: true},
+		{name: "kept_forever", archived: 1000 * day, archive: true},
+
+		// Conversations are archived before they can be deleted
+		{name: "archived_first", policy: SessionPolicy{ArchiveAfter: day, Retention: time.Nanosecond}, inactive: 2 * day, archive: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			b := newTestBot(t)
+			b.SetSessionPolicy(test.policy)
+			assert.Nil(b.AddConversation("other"))
+
+			key, err := b.ResolveSession("channel")
+			assert.Nil(err)
+
+			// Date the conversation
+			c := b.getConversation(key)
+			c.UpdatedAt = time.Now().UTC().Add(-test.inactive)
+			if test.archived > 0 {
+				archivedAt := time.Now().UTC().Add(-test.archived)
+				c.ArchivedAt = &archivedAt
+			}
+
+			assert.Nil(b.Maintain())
+			assert.Equal(!test.purge, b.IsConversation(key))
+			assert.Equal(test.archive, b.IsArchived(key))
+
+			// Other conversations are left alone
+			assert.True(b.IsConversation("other"))
+			assert.False(b.IsArchived("other"))
+
+			// The channel starts a new conversation after its conversation is deleted
+			session, err := b.getSession("channel")
+			assert.Nil(err)
+			assert.Equal(test.purge, session.Conversation == "")
+		})
+	}
+}
+
+func TestApplySessionPolicySettings(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBot(t)
+	addTestModule(b, PERMISSIONS_PRVMODULES)
+	b.SetSessionPolicy(SessionPolicy{Retention: time.Hour})
+
+	key, err := b.ResolveSession("channel")
+	assert.Nil(err)
+	assert.Nil(b.SetSessionSettings("channel", SessionSettings{Modules: []string{"test"}}))
+	assert.Nil(b.SetSessionSettings(key, SessionSettings{Modules: []string{"test"}}))
+
+	archivedAt := time.Now().UTC().Add(-2 * time.Hour)
+	b.getConversation(key).ArchivedAt = &archivedAt
+	assert.Nil(b.ApplySessionPolicy())
+
+	// Channels keep their settings, while settings kept for the deleted conversation are removed
+	settings, err := b.GetSessionSettings("channel")
+	assert.Nil(err)
+	assert.Equal([]string{"test"}, settings.Modules)
+
+	var count int64
+	assert.Nil(db.Model(&Session{}).Where("bot_id = ? AND `key` = ?", b.Model.ID, key).Count(&count).Error)
+	assert.Zero(count)
+
+	// The channel's next message starts a new conversation with its settings
+	next, err := b.ResolveSession("channel")
+	assert.Nil(err)
+	assert.True(b.IsConversation(next))
+	assert.False(b.IsArchived(next))
+	assert.Equal([]string{"test"}, b.allowedModules(next))
+}
+
+func TestMaintainPrunesAudits(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBot(t)
+
+	input := &types.Input{Caller: "user"}
+	assert.Nil(b.auditTool("main", "call-1", "set_value", `{}`, input, "ok", 0, ""))
+	assert.Nil(b.auditTool("main", "call-2", "set_value", `{}`, input, "ok", 0, ""))
+	assert.Nil(db.Model(&ToolAudit{}).Where("tool_call_id = ?", "call-1").Update("created_at", time.Now().UTC().Add(-48*time.Hour)).Error)
+
+	// Without a retention, records are kept
+	assert.Nil(b.Maintain())
+	audits, err := b.ToolAudits(AuditQuery{})
+	assert.Nil(err)
+	assert.Len(audits, 2)
+
+	b.SetAuditRetention(24 * time.Hour)
+	assert.Nil(b.Maintain())
+	audits, err = b.ToolAudits(AuditQuery{})
+	assert.Nil(err)
+	assert.Len(audits, 1)
+	assert.Equal("call-2", audits[0].ToolCallID)
+}
+
+func TestAllowedModules(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBot(t)
+	addTestModule(b, PERMISSIONS_PRVMODULES)
+
+	channel, err := b.ResolveSession("channel")
+	assert.Nil(err)
+	open, err := b.ResolveSession("open")
+	assert.Nil(err)
+	assert.Nil(b.AddConversation("thread"))
+
+	assert.Nil(b.SetSessionSettings("channel", SessionSettings{Modules: []string{"test", " "}}))
+	assert.Nil(b.SetSessionSettings("thread", SessionSettings{Modules: []string{"test"}}))
+	assert.NotNil(b.SetSessionSettings("channel", SessionSettings{Modules: []string{"missing"}}))
+
+	tests := []struct {
+		conversation string
+		module       string
+		allowed      bool
+	}{
+		// Conversations follow the settings of the channel they are active in
+		{channel, "test", true},
+		{channel, "keepass", false},
+
+		// Conversations registered as their own channel follow their own settings
+		{"thread", "test", true},
+		{"thread", "keepass", false},
+
+		// Conversations without settings can use every module
+		{open, "keepass", true},
+		{"unknown", "keepass", true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(test.allowed, b.moduleAllowed(test.conversation, test.module), test.conversation+" "+test.module)
+	}
+	assert.Nil(b.allowedModules(open))
+	assert.Equal([]string{"test"}, b.allowedModules(channel))
+}
@@ -11,10 +11,17 @@ var db *gorm.DB
 
 // InitSQL initializes the SQL database the structs are connected to
 func InitSQL(dsn string) error {
+	return InitDB(mysql.Open(dsn))
+}
+
+// InitDB initializes the database the structs are connected to with any gorm dialector
+// (such as SQLite). Full-text search uses the database's native index when it is
+// supported, and a fallback index table otherwise
+func InitDB(dialector gorm.Dialector) error {
 	var err error
 
 	// Open a database with gorm
-	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err = gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		return err
 	}
@@ -26,6 +33,9 @@ func InitSQL(dsn string) error {
 	if err = db.AutoMigrate(&Message{}); err != nil {
 		return err
 	}
+	if err = db.AutoMigrate(&Attachment{}); err != nil {
+		return err
+	}
 	if err = db.AutoMigrate(&Memory{}); err != nil {
 		return err
 	}
@@ -35,16 +45,28 @@ func InitSQL(dsn string) error {
 	if err = db.AutoMigrate(&Bot{}); err != nil {
 		return err
 	}
+	if err = db.AutoMigrate(&Session{}); err != nil {
+		return err
+	}
+	if err = db.AutoMigrate(&ToolAudit{}); err != nil {
+		return err
+	}
+	if err = db.AutoMigrate(&SearchTerm{}); err != nil {
+		return err
+	}
 
-	return nil
+	// Set up the search indexes
+	return setupSearch()
 }
 
 // GetAllBots gets a list of all bots in the SQL database
 func GetAllBots() ([]Bot, error) {
 	bots := []Bot{}
 
-	// Load associated memory objects
-	if err := db.Model(&Bot{}).Preload("Memory").Preload("Conversations.Messages.ToolCalls").Find(&bots).Error; err != nil {
+	// Load associated memory objects. Attachments are loaded without their content, which is read
+	// when a message needs it
+	withoutContent := func(db *gorm.DB) *gorm.DB { return db.Omit("content") }
+	if err := db.Model(&Bot{}).Preload("Memory").Preload("Conversations.Messages.ToolCalls").Preload("Conversations.Messages.Attachments", withoutContent).Find(&bots).Error; err != nil {
 		return bots, err
 	}
 
@@ -53,6 +75,7 @@ func GetAllBots() ([]Bot, error) {
 		bot := &bots[i]
 		bot.functionDefinitions = map[string]openai.FunctionDefinition{}
 		bot.handlers = []func(function string, input *types.Input) any{}
+		bot.sideEffects = map[string]bool{}
 		bot.functionQueue = []func(bot *Bot, input *types.Input) *types.Output{}
 		bot.variables = map[string]any{}
 	}
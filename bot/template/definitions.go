@@ -1,6 +1,7 @@
 package module_template
 
 import (
+	horus "github.com/ethanbaker/horus/bot"
 	"github.com/ethanbaker/horus/utils/schema"
 	openai "github.com/sashabaranov/go-openai"
 )
@@ -13,23 +14,11 @@ var functionDefinitions = map[string]openai.FunctionDefinition{
 		Description: "Desc for function_1", // The description of the function for the model to understand
 		Parameters:  schema.Definition{},   // The parameters of the function for the model to understand usage (it must be json serialized if a struct)
 	},
+}
 
+// A list of tools created from typed functions. Their function declarations are derived from
+// the struct tags of each function's argument type, so no schema.Definition is needed
+var tools = []horus.Tool{
 	// OpenAI Documentation Demo
-	"get_current_weather_demo": {
-		Name:        "get_current_weather_demo",
-		Description: "Get the current weather in a given location",
-		Parameters: schema.Definition{
-			Type: schema.Object,
-			Properties: map[string]schema.Definition{
-				"location": {
-					Type:        schema.String,
-					Description: "The city and state, e.g. San Francisco, CA",
-				},
-				"unit": {
-					Type: schema.String,
-					Enum: []string{"celsius", "fahrenheit"},
-				},
-			},
-		},
-	},
+	horus.MustTool("get_current_weather_demo", "Get the current weather in a given location", get_current_weather_demo),
 }
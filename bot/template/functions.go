@@ -1,14 +1,15 @@
 package module_template
 
 import (
+	"context"
+
 	horus "github.com/ethanbaker/horus/bot"
 	"github.com/ethanbaker/horus/utils/types"
 )
 
 // A list of all enabled functions in the module
 var functions = map[string]func(bot *horus.Bot, input *types.Input) any{
-	"function_1":               function_1,
-	"get_current_weather_demo": get_current_weather_demo,
+	"function_1": function_1,
 }
 
 // Function takes in input from the bot and returns an object that can be json
@@ -17,23 +18,31 @@ func function_1(bot *horus.Bot, input *types.Input) any {
 	return nil
 }
 
-func get_current_weather_demo(bot *horus.Bot, input *types.Input) any {
-	// Get variables from the model using parameters
-	location, _ := input.GetString("location", "")
-	unit, _ := input.GetString("unit", "")
+// Arguments are decoded from the model's function call using json tags. The description, enum
+// and required tags describe the parameter to the model
+type WeatherDemoArgs struct {
+	Location string `json:"location" description:"The city and state, e.g. San Francisco, CA" required:"true"`
+	Unit     string `json:"unit" enum:"celsius,fahrenheit"`
+}
 
+// Results are json marshalled to the model
+type WeatherDemoResult struct {
+	Location    string   `json:"location"`
+	Temperature string   `json:"temperature"`
+	Unit        string   `json:"unit"`
+	Forecast    []string `json:"forecast"`
+}
+
+// Typed functions take in a context, the bot and their decoded arguments. Returned errors are
+// sent to the model
+func get_current_weather_demo(ctx context.Context, bot *horus.Bot, args WeatherDemoArgs) (WeatherDemoResult, error) {
 	// Create demo data to return
-	var weatherInfo struct {
-		Location    string   `json:"location"`
-		Temperature string   `json:"temperature"`
-		Unit        string   `json:"unit"`
-		Forecast    []string `json:"forecast"`
+	weatherInfo := WeatherDemoResult{
+		Location:    args.Location,
+		Temperature: "72",
+		Unit:        args.Unit,
+		Forecast:    []string{"sunny", "windy"},
 	}
 
-	weatherInfo.Location = location
-	weatherInfo.Temperature = "72"
-	weatherInfo.Unit = unit
-	weatherInfo.Forecast = []string{"sunny", "windy"}
-
-	return weatherInfo
+	return weatherInfo, nil
 }
@@ -25,7 +25,7 @@ func (m *Module) Name() string {
 // Handle a function call
 func (m *Module) Handler(function string, input *types.Input) any {
 	// Check for permissions
-	if input.Permissions|m.Permissions == 0 {
+	if input.Permissions&m.Permissions == 0 {
 		return nil
 	}
 
@@ -44,11 +44,29 @@ func NewModule(bot *horus.Bot, enabled bool) {
 	// Create the module and add static information
 	var m Module
 	m.Enabled = enabled
-	m.Permissions = horus.PERMISSIONS_NONE
+	m.Permissions = horus.PERMISSIONS_PRVMODULES
 	m.Functions = functions
 	m.bot = bot
 
+	// Add tools created from typed functions
+	for name, f := range horus.ToolFunctions(tools) {
+		m.Functions[name] = f
+	}
+	for name, def := range horus.ToolDefinitions(tools) {
+		functionDefinitions[name] = def
+	}
+
 	// Add the module's handler and function definitions to the bot
 	bot.AddHandlers(m.Handler)
 	bot.AddDefinitions(m.Name(), &functionDefinitions)
+
+	// Functions that change something (send an email, delete a file) should be confirmed by the
+	// user before they run. Typed tools can use WithSideEffects instead. Side effects must be
+	// added after the definitions they name
+	bot.AddSideEffects("function_1")
+	for _, t := range tools {
+		if t.SideEffects {
+			bot.AddSideEffects(t.Definition.Name)
+		}
+	}
 }
@@ -0,0 +1,49 @@
+package module_template
+
+import (
+	"context"
+	"testing"
+
+	horus "github.com/ethanbaker/horus/bot"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+type deleteDemoArgs struct {
+	Name string `json:"name" required:"true"`
+}
+
+func TestNewModuleSideEffects(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(horus.InitDB(sqlite.Open("file:template?mode=memory&cache=shared")))
+	bot, err := horus.NewBot("horus-test", horus.PERMISSIONS_ALL)
+	assert.Nil(err)
+	assert.Nil(bot.AddConversation("main"))
+
+	// Side-effecting tools are registered once their definitions are added
+	deleted := []string{}
+	tools = append(tools, horus.MustTool("delete_demo", "Delete a file", func(ctx context.Context, bot *horus.Bot, args deleteDemoArgs) (map[string]string, error) {
+		deleted = append(deleted, args.Name)
+		return map[string]string{"message": "deleted"}, nil
+	}).WithSideEffects())
+	defer func() { tools = tools[:len(tools)-1] }()
+
+	assert.NotPanics(func() { NewModule(bot, true) })
+
+	// Calling a side-effecting function directly is recorded as the user's approval
+	_, err = bot.CallFunction("main", "delete_demo", `{"name": "notes.txt"}`, &types.Input{Permissions: horus.PERMISSIONS_PRVMODULES})
+	assert.Nil(err)
+	assert.Equal([]string{"notes.txt"}, deleted)
+
+	audits, err := bot.ToolAudits(horus.AuditQuery{Function: "delete_demo"})
+	assert.Nil(err)
+	assert.Len(audits, 1)
+	assert.Equal(horus.CONFIRMATION_APPROVED, audits[0].Decision)
+
+	// The module needs its permission
+	_, err = bot.CallFunction("main", "delete_demo", `{"name": "notes.txt"}`, &types.Input{Permissions: horus.PERMISSIONS_GPT})
+	assert.NotNil(err)
+	assert.Len(deleted, 1)
+}
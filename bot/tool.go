@@ -0,0 +1,108 @@
+package horus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethanbaker/horus/utils/schema"
+	"github.com/ethanbaker/horus/utils/types"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Tool bundles a function definition with the function that handles it. Tools are usually
+// created from typed Go functions with NewTool
+type Tool struct {
+	Definition  openai.FunctionDefinition              // The definition given to the model
+	Function    func(bot *Bot, input *types.Input) any // The function that handles calls
+	SideEffects bool                                   // Whether the user must confirm calls before they run
+}
+
+// WithSideEffects marks the tool as side-effecting. Bots pause before running side-effecting
+// tools until the user confirms the call
+func (t Tool) WithSideEffects() Tool {
+	t.SideEffects = true
+	return t
+}
+
+// toolError is returned to the model when a typed tool returns an error
+type toolError struct {
+	Message string `json:"error"`
+}
+
+func (e toolError) Error() string {
+	return e.Message
+}
+
+// NewTool creates a tool from a typed function. The function's parameters are derived from the
+// fields of its argument struct (see schema.Reflect), the model's arguments are decoded into the
+// struct before the function is called, and the function's result is marshaled back to the model.
+// A result of type *types.Output is returned directly to the user instead
+func NewTool[A any, R any](name string, description string, fn func(ctx context.Context, bot *Bot, args A) (R, error)) (Tool, error) {
+	var zero A
+	params, err := schema.Reflect(zero)
+	if err != nil {
+		return Tool{}, fmt.Errorf("cannot derive parameters for tool %v: %w", name, err)
+	}
+	if params.Type != schema.Object {
+		return Tool{}, fmt.Errorf("arguments for tool %v must be a struct", name)
+	}
+
+	return Tool{
+		Definition: openai.FunctionDefinition{
+			Name:        name,
+			Description: description,
+			Parameters:  params,
+		},
+		Function: func(bot *Bot, input *types.Input) any {
+			// Decode the model's arguments into the argument struct
+			var args A
+			raw, err := json.Marshal(input.Parameters)
+			if err != nil {
+				return toolError{Message: fmt.Sprintf("cannot read arguments: %v", err)}
+			}
+			if err = json.Unmarshal(raw, &args); err != nil {
+				return toolError{Message: fmt.Sprintf("cannot decode arguments: %v", err)}
+			}
+
+			// Call the function
+			result, err := fn(context.Background(), bot, args)
+			if err != nil {
+				return toolError{Message: err.Error()}
+			}
+
+			return result
+		},
+	}, nil
+}
+
+// MustTool is like NewTool but panics if the tool cannot be created. It is meant for
+// package-level tool lists in modules
+func MustTool[A any, R any](name string, description string, fn func(ctx context.Context, bot *Bot, args A) (R, error)) Tool {
+	t, err := NewTool(name, description, fn)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+// ToolDefinitions creates a map of function definitions from a list of tools
+func ToolDefinitions(tools []Tool) map[string]openai.FunctionDefinition {
+	definitions := map[string]openai.FunctionDefinition{}
+	for _, t := range tools {
+		definitions[t.Definition.Name] = t.Definition
+	}
+
+	return definitions
+}
+
+// ToolFunctions creates a map of functions from a list of tools
+func ToolFunctions(tools []Tool) map[string]func(bot *Bot, input *types.Input) any {
+	functions := map[string]func(bot *Bot, input *types.Input) any{}
+	for _, t := range tools {
+		functions[t.Definition.Name] = t.Function
+	}
+
+	return functions
+}
@@ -0,0 +1,140 @@
+package horus
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethanbaker/horus/utils/schema"
+	"github.com/ethanbaker/horus/utils/types"
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+)
+
+type tripArgs struct {
+	City    string   `json:"city" description:"The city to visit" required:"true"`
+	Nights  int      `json:"nights" required:"true"`
+	Class   string   `json:"class" enum:"economy, business"`
+	Guests  []string `json:"guests,omitempty"`
+	Ignored string   `json:"-"`
+	private string
+}
+
+type tripResult struct {
+	Booking string `json:"booking"`
+	Nights  int    `json:"nights"`
+}
+
+// bookTrip books trips to every city except Atlantis
+func bookTrip(ctx context.Context, bot *Bot, args tripArgs) (tripResult, error) {
+	if args.City == "Atlantis" {
+		return tripResult{}, errors.New("no trips to Atlantis")
+	}
+	return tripResult{Booking: args.City + "-1", Nights: args.Nights}, nil
+}
+
+func TestNewTool(t *testing.T) {
+	assert := assert.New(t)
+
+	tool, err := NewTool("book_trip", "Book a trip", bookTrip)
+	assert.Nil(err)
+	assert.False(tool.SideEffects)
+	assert.True(tool.WithSideEffects().SideEffects)
+
+	// Parameters are derived from the argument struct's fields and tags
+	assert.Equal(openai.FunctionDefinition{
+		Name:        "book_trip",
+		Description: "Book a trip",
+		Parameters: schema.Definition{
+			Type: schema.Object,
+			Properties: map[string]schema.Definition{
+				"city":   {Type: schema.String, Description: "The city to visit"},
+				"nights": {Type: schema.Integer},
+				"class":  {Type: schema.String, Enum: []string{"economy", "business"}},
+				"guests": {Type: schema.Array, Items: &schema.Definition{Type: schema.String}},
+			},
+			Required: []string{"city", "nights"},
+		},
+	}, tool.Definition)
+
+	// Arguments that aren't structs can't be described to the model
+	_, err = NewTool("bad", "Bad", func(ctx context.Context, bot *Bot, args string) (string, error) { return args, nil })
+	assert.EqualError(err, "arguments for tool bad must be a struct")
+	_, err = NewTool("bad", "Bad", func(ctx context.Context, bot *Bot, args struct{ C chan int }) (string, error) { return "", nil })
+	assert.NotNil(err)
+	assert.Panics(func() {
+		MustTool("bad", "Bad", func(ctx context.Context, bot *Bot, args int) (int, error) { return args, nil })
+	})
+}
+
+func TestToolFunction(t *testing.T) {
+	assert := assert.New(t)
+	tool := MustTool("book_trip", "Book a trip", bookTrip)
+
+	call := func(parameters objx.Map) any {
+		return tool.Function(nil, &types.Input{Parameters: parameters})
+	}
+
+	// Arguments are decoded into the argument struct, and results are returned as they are
+	assert.Equal(tripResult{Booking: "Paris-1", Nights: 3}, call(objx.Map{"city": "Paris", "nights": 3, "guests": []any{"sam"}}))
+
+	// Errors are returned to the model as JSON
+	result := call(objx.Map{"city": "Atlantis", "nights": 1})
+	assert.Equal(toolError{Message: "no trips to Atlantis"}, result)
+	assert.EqualError(result.(error), "no trips to Atlantis")
+
+	// Arguments that don't fit the struct are errors instead of calls
+	result = call(objx.Map{"city": "Paris", "nights": "three"})
+	assert.IsType(toolError{}, result)
+	assert.Contains(result.(error).Error(), "cannot decode arguments")
+
+	result = call(objx.Map{"city": func() {}})
+	assert.IsType(toolError{}, result)
+	assert.Contains(result.(error).Error(), "cannot read arguments")
+}
+
+func TestToolCalls(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+		expected  string
+	}{
+		{"result", `{"city": "Paris", "nights": 2}`, `result: {"booking":"Paris-1","nights":2}`},
+		{"error", `{"city": "Atlantis", "nights": 2}`, `result: {"error":"no trips to Atlantis"}`},
+		{"missing_required", `{"city": "Paris"}`, `"error":"invalid arguments"`},
+		{"bad_enum", `{"city": "Paris", "nights": 2, "class": "first"}`, `"error":"invalid arguments"`},
+		{"bad_json", `{"city": "Paris", "nights": `, `"error":"invalid arguments"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			b := newTestBot(t)
+
+			// Add the tool like a module would
+			tools := []Tool{MustTool("book_trip", "Book a trip", bookTrip)}
+			functions := ToolFunctions(tools)
+			b.AddTools("travel", tools)
+			b.AddHandlers(func(function string, input *types.Input) any {
+				if f, ok := functions[function]; ok {
+					return f(b, input)
+				}
+				return nil
+			})
+
+			b.Setup(newFakeModel(t, callOnce("book_trip", test.arguments)))
+			assert.Nil(b.AddConversation("main"))
+
+			// The model gets the marshaled result, the tool's error, or why its arguments are invalid
+			output, err := b.SendMessage("main", &types.Input{Message: "Book a trip", Permissions: PERMISSIONS_ALL})
+			assert.Nil(err)
+			assert.Contains(output.Message, test.expected)
+
+			audits, err := b.ToolAudits(AuditQuery{Function: "book_trip"})
+			assert.Nil(err)
+			assert.Len(audits, 1)
+			assert.Equal("travel", audits[0].Module)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+// Bot is a client for a single bot on the server. Its methods mirror those of a Horus bot
+type Bot struct {
+	client *Client
+	name   string
+	stream types.OutreachMethod // The stream key replies are streamed to (see WithStream)
+}
+
+// Bot returns a client for a bot on the server
+func (c *Client) Bot(name string) *Bot {
+	return &Bot{client: c, name: name}
+}
+
+// WithStream returns a copy of the bot client whose messages stream their replies to a stream
+// key as they are generated (see Client.Stream). Messages still return the complete reply
+func (b *Bot) WithStream(key types.OutreachMethod) *Bot {
+	streamed := *b
+	streamed.stream = key
+	return &streamed
+}
+
+// Info describes the bot
+func (b *Bot) Info(ctx context.Context) (types.APIBot, error) {
+	var info types.APIBot
+	return info, b.client.do(ctx, http.MethodGet, b.path(), nil, &info)
+}
+
+// Conversations lists the bot's conversations
+func (b *Bot) Conversations(ctx context.Context) ([]types.APIConversation, error) {
+	conversations := []types.APIConversation{}
+	return conversations, b.client.do(ctx, http.MethodGet, b.path("conversations"), nil, &conversations)
+}
+
+// AddConversation adds a new conversation to the bot
+func (b *Bot) AddConversation(ctx context.Context, key string) error {
+	return b.client.do(ctx, http.MethodPost, b.path("conversations"), types.APIConversationRequest{Key: key}, nil)
+}
+
+// DeleteConversation deletes a conversation from the bot
+func (b *Bot) DeleteConversation(ctx context.Context, key string) error {
+	return b.client.do(ctx, http.MethodDelete, b.path("conversations", key), nil, nil)
+}
+
+// ForkConversation copies a conversation and its messages into a new conversation
+func (b *Bot) ForkConversation(ctx context.Context, key string, newKey string) error {
+	return b.client.do(ctx, http.MethodPost, b.path("conversations"), types.APIConversationRequest{Key: newKey, Fork: key}, nil)
+}
+
+// RenameConversation changes the key of a conversation
+func (b *Bot) RenameConversation(ctx context.Context, key string, newKey string) error {
+	return b.client.do(ctx, http.MethodPatch, b.path("conversations", key), types.APIConversationUpdate{Key: newKey}, nil)
+}
+
+// IsConversation returns true if the conversation exists
+func (b *Bot) IsConversation(ctx context.Context, key string) (bool, error) {
+	err := b.client.do(ctx, http.MethodGet, b.path("conversations", key), nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// History gets the messages in a conversation
+func (b *Bot) History(ctx context.Context, key string) ([]types.APIMessage, error) {
+	messages := []types.APIMessage{}
+	return messages, b.client.do(ctx, http.MethodGet, b.path("conversations", key, "messages"), nil, &messages)
+}
+
+// SendMessage sends a message to the bot in a given conversation. Files in the response are
+// downloaded into the output's attachments
+func (b *Bot) SendMessage(ctx context.Context, key string, input *types.Input) (*types.Output, error) {
+	req := types.APIMessageRequest{
+		Message:     input.Message,
+		Permissions: input.Permissions,
+		Caller:      input.Caller,
+		Stream:      b.stream,
+	}
+
+	var res types.APIOutput
+	if err := b.client.do(ctx, http.MethodPost, b.path("conversations", key, "messages"), req, &res); err != nil {
+		return nil, err
+	}
+
+	output := &types.Output{
+		Message: res.Message,
+		Data:    res.Data,
+		Blocks:  res.Blocks,
+		Actions: res.Actions,
+	}
+	if res.Error != "" {
+		output.Error = errors.New(res.Error)
+	}
+
+	for _, f := range res.Files {
+		file, err := b.client.File(ctx, f)
+		if err != nil {
+			return output, fmt.Errorf("cannot download file %v: %w", f.Filename, err)
+		}
+		output.Attachments = append(output.Attachments, file)
+	}
+
+	return output, nil
+}
+
+// path builds an API path for the bot from unescaped segments
+func (b *Bot) path(segments ...string) string {
+	path := "/api/bots/" + url.PathEscape(b.name)
+	for _, s := range segments {
+		path += "/" + url.PathEscape(s)
+	}
+
+	return path
+}
@@ -0,0 +1,193 @@
+// Package client is a typed client for the Horus API (see the server package). It mirrors the
+// surface of a Horus bot so implementations can run as separate processes
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* ---- CONSTANTS ---- */
+
+// Defaults for new clients
+const (
+	DEFAULT_RETRIES     = 3
+	DEFAULT_RETRY_DELAY = 500 * time.Millisecond
+	DEFAULT_TIMEOUT     = 2 * time.Minute
+)
+
+/* ---- CLIENT ---- */
+
+// Client talks to a Horus API server
+type Client struct {
+	BaseURL    string        // The server's address (ex: http://localhost:8080)
+	APIKey     string        // The key issued to the implementation (see horus-server keys)
+	HTTPClient *http.Client  // The HTTP client used for requests
+	Retries    int           // How many times failed requests are retried
+	RetryDelay time.Duration // How long to wait before the first retry (doubled after each retry)
+}
+
+// New creates a client for a Horus API server
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: DEFAULT_TIMEOUT},
+		Retries:    DEFAULT_RETRIES,
+		RetryDelay: DEFAULT_RETRY_DELAY,
+	}
+}
+
+// Bots lists the bots served by the server
+func (c *Client) Bots(ctx context.Context) ([]types.APIBot, error) {
+	bots := []types.APIBot{}
+	return bots, c.do(ctx, http.MethodGet, "/api/bots", nil, &bots)
+}
+
+// File downloads a file output
+func (c *Client) File(ctx context.Context, file types.APIFile) (types.FileOutput, error) {
+	output := types.FileOutput{
+		Filename:    file.Filename,
+		ContentType: file.ContentType,
+	}
+
+	res, err := c.send(ctx, http.MethodGet, file.URL, nil)
+	if err != nil {
+		return output, err
+	}
+	defer res.Body.Close()
+
+	output.Content, err = io.ReadAll(res.Body)
+	return output, err
+}
+
+// do sends a request and decodes the JSON response into v (if v is not nil)
+func (c *Client) do(ctx context.Context, method string, path string, body any, v any) error {
+	res, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if v == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("cannot decode response: %w", err)
+	}
+	return nil
+}
+
+// send sends a request, retrying when the server is unavailable. Requests that change state are
+// only retried when the server reports that it did not handle them. The caller must close the
+// body of a successful response
+func (c *Client) send(ctx context.Context, method string, path string, body any) (*http.Response, error) {
+	return c.sendWithHeader(ctx, method, path, body, nil)
+}
+
+// sendWithHeader sends a request with extra headers (see send)
+func (c *Client) sendWithHeader(ctx context.Context, method string, path string, body any, header http.Header) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	delay := c.RetryDelay
+	for attempt := 0; ; attempt++ {
+		res, err := c.attempt(ctx, method, path, payload, header)
+		if err == nil {
+			return res, nil
+		}
+
+		if attempt >= c.Retries || !retryable(method, err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		// Wait before retrying
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// attempt sends a request once
+func (c *Client) attempt(ctx context.Context, method string, path string, payload []byte, header http.Header) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	if res.StatusCode >= 400 {
+		defer res.Body.Close()
+		return nil, readError(res)
+	}
+	return res, nil
+}
+
+// readError creates an API error from an error response
+func readError(res *http.Response) error {
+	apiErr := &APIError{StatusCode: res.StatusCode}
+
+	var body types.APIError
+	if err := json.NewDecoder(res.Body).Decode(&body); err == nil && body.Error != "" {
+		apiErr.Message = body.Error
+	} else {
+		apiErr.Message = http.StatusText(res.StatusCode)
+	}
+
+	return apiErr
+}
+
+// retryable returns true if a failed request can be sent again
+func retryable(method string, err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		// The server did not handle the request, so any request can be retried
+		if apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusServiceUnavailable {
+			return true
+		}
+
+		return errors.Is(err, ErrUnavailable) && idempotent(method)
+	}
+
+	// The request may have been handled before the connection failed
+	return errors.Is(err, ErrUnavailable) && idempotent(method)
+}
+
+// idempotent returns true if sending a request twice has the same effect as sending it once
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodPut
+}
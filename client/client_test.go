@@ -0,0 +1,196 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethanbaker/horus/client"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// newClient creates a client for a test server that retries quickly
+func newClient(handler http.HandlerFunc) (*client.Client, *httptest.Server) {
+	ts := httptest.NewServer(handler)
+	c := client.New(ts.URL)
+	c.RetryDelay = time.Millisecond
+	return c, ts
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestSendMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	c, ts := newClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/bots/horus/conversations/a%20b/messages":
+			var req types.APIMessageRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			writeJSON(w, http.StatusOK, types.APIOutput{
+				Message: "echo: " + req.Message,
+				Files:   []types.APIFile{{ID: "1", Filename: "a.txt", URL: "/api/files/1"}},
+			})
+
+		case "/api/files/1":
+			w.Write([]byte("hello"))
+
+		default:
+			writeJSON(w, http.StatusNotFound, types.APIError{Error: "missing"})
+		}
+	})
+	defer ts.Close()
+
+	output, err := c.Bot("horus").SendMessage(context.Background(), "a b", &types.Input{Message: "hi"})
+	assert.Nil(err)
+	assert.Equal("echo: hi", output.Message)
+	assert.Equal([]types.FileOutput{{Filename: "a.txt", Content: []byte("hello")}}, output.Attachments)
+}
+
+func TestErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	c, ts := newClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			writeJSON(w, http.StatusConflict, types.APIError{Error: "conversation 'x' already exists"})
+		default:
+			writeJSON(w, http.StatusNotFound, types.APIError{Error: "conversation 'x' does not exist"})
+		}
+	})
+	defer ts.Close()
+
+	bot := c.Bot("horus")
+
+	err := bot.AddConversation(context.Background(), "x")
+	assert.True(errors.Is(err, client.ErrConflict))
+
+	var apiErr *client.APIError
+	assert.True(errors.As(err, &apiErr))
+	assert.Equal("conversation 'x' already exists", apiErr.Message)
+
+	_, err = bot.History(context.Background(), "x")
+	assert.True(errors.Is(err, client.ErrNotFound))
+
+	exists, err := bot.IsConversation(context.Background(), "x")
+	assert.Nil(err)
+	assert.False(exists)
+}
+
+func TestRetries(t *testing.T) {
+	assert := assert.New(t)
+
+	var gets, posts int32
+	c, ts := newClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			// Fail twice before succeeding
+			if atomic.AddInt32(&gets, 1) <= 2 {
+				writeJSON(w, http.StatusBadGateway, types.APIError{Error: "bad gateway"})
+				return
+			}
+			writeJSON(w, http.StatusOK, []types.APIBot{{Name: "horus"}})
+			return
+		}
+
+		atomic.AddInt32(&posts, 1)
+		writeJSON(w, http.StatusBadGateway, types.APIError{Error: "bad gateway"})
+	})
+	defer ts.Close()
+
+	bots, err := c.Bots(context.Background())
+	assert.Nil(err)
+	assert.Equal("horus", bots[0].Name)
+	assert.Equal(int32(3), gets)
+
+	// Messages may have been handled, so they are not retried
+	_, err = c.Bot("horus").SendMessage(context.Background(), "x", &types.Input{Message: "hi"})
+	assert.True(errors.Is(err, client.ErrUnavailable))
+	assert.Equal(int32(1), posts)
+}
+
+func TestContext(t *testing.T) {
+	assert := assert.New(t)
+
+	c, ts := newClient(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusServiceUnavailable, types.APIError{Error: "busy"})
+	})
+	defer ts.Close()
+	c.RetryDelay = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Bots(ctx)
+	assert.True(errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestStream(t *testing.T) {
+	assert := assert.New(t)
+
+	// Each connection sends one event and closes, so the client has to reconnect
+	var connections int32
+	resumedFrom := make(chan string, 2)
+	c, ts := newClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/api/stream/discord", r.URL.Path)
+		n := atomic.AddInt32(&connections, 1)
+		resumedFrom <- r.Header.Get("Last-Event-ID")
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		if n == 1 {
+			data, _ := json.Marshal(types.APIOutreach{Method: types.Discord, Message: "reminder"})
+			fmt.Fprintf(w, ": heartbeat\n\nid: 7\nevent: outreach\ndata: %s\n\n", data)
+			return
+		}
+
+		data, _ := json.Marshal(types.APIToken{Bot: "horus", Conversation: "test", Token: "hi"})
+		fmt.Fprintf(w, "id: 8\nevent: token\ndata: %s\n\n", data)
+	})
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := c.Stream(ctx, types.Discord)
+	assert.Nil(err)
+
+	e := <-ch
+	assert.Equal("7", e.ID)
+	assert.Equal("reminder", e.Outreach.Message)
+	assert.Equal("", <-resumedFrom)
+
+	// The client resumes after the last event it received
+	e = <-ch
+	assert.Equal("8", e.ID)
+	assert.Equal("hi", e.Token.Token)
+	assert.Equal("7", <-resumedFrom)
+
+	// The channel closes once the context is canceled
+	cancel()
+	for range ch {
+	}
+}
+
+func TestWithStream(t *testing.T) {
+	assert := assert.New(t)
+
+	c, ts := newClient(func(w http.ResponseWriter, r *http.Request) {
+		var req types.APIMessageRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		writeJSON(w, http.StatusOK, types.APIOutput{Message: string(req.Stream)})
+	})
+	defer ts.Close()
+
+	output, err := c.Bot("horus").WithStream("terminal").SendMessage(context.Background(), "test", &types.Input{Message: "hi"})
+	assert.Nil(err)
+	assert.Equal("terminal", output.Message)
+}
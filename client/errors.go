@@ -0,0 +1,48 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors that API errors can be compared against with errors.Is
+var (
+	ErrBadRequest   = errors.New("bad request")  // The request was invalid
+	ErrUnauthorized = errors.New("unauthorized") // The request was not authenticated or not allowed
+	ErrNotFound     = errors.New("not found")    // The bot, conversation or file does not exist
+	ErrConflict     = errors.New("conflict")     // The resource already exists
+	ErrServer       = errors.New("server error") // Horus failed to handle the request
+	ErrUnavailable  = errors.New("unavailable")  // Horus could not be reached or is overloaded
+)
+
+// APIError is returned when the Horus API responds with an error
+type APIError struct {
+	StatusCode int    // The HTTP status of the response
+	Message    string // The error message from the API
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("horus api error (status %v): %v", e.StatusCode, e.Message)
+}
+
+// Is matches the error against the error variables in this package by status code
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrUnavailable:
+		return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusBadGateway ||
+			e.StatusCode == http.StatusServiceUnavailable || e.StatusCode == http.StatusGatewayTimeout
+	case ErrServer:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+
+	return false
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+// The longest wait between reconnection attempts
+const MAX_RECONNECT_DELAY = time.Minute
+
+// Event is an event received on a stream. Exactly one of the payloads is set, depending on the
+// event's type
+type Event struct {
+	ID   string // The event's ID, used to resume the stream
+	Type string // The event's type (see types.EVENT_OUTREACH, types.EVENT_TOKEN and types.EVENT_REPLY)
+
+	Outreach *types.APIOutreach // Set for outreach events
+	Token    *types.APIToken    // Set for token events
+	Reply    *types.APIReply    // Set for reply events
+}
+
+// Stream subscribes to the events sent to a stream key: outreach messages for the method and
+// replies to messages sent with the key (see Bot.WithStream). The first connection is made
+// before returning. After that the stream reconnects on its own, resuming after the last event
+// received, until the context is canceled and the channel is closed
+func (c *Client) Stream(ctx context.Context, key types.OutreachMethod) (<-chan Event, error) {
+	path := "/api/stream/" + url.PathEscape(string(key))
+
+	// Streams stay open, so they are not limited by the client's timeout
+	stream := *c
+	httpClient := *c.HTTPClient
+	httpClient.Timeout = 0
+	stream.HTTPClient = &httpClient
+	c = &stream
+
+	res, err := c.send(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Event)
+	go func() {
+		defer close(ch)
+
+		lastID := ""
+		delay := c.RetryDelay
+		for {
+			readEvents(res.Body, func(e event) {
+				if e.id != "" {
+					lastID = e.id
+				}
+
+				if parsed, ok := parseEvent(e); ok {
+					select {
+					case ch <- parsed:
+					case <-ctx.Done():
+					}
+				}
+			})
+			res.Body.Close()
+
+			// Reconnect until the context is canceled
+			for {
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+					return
+				}
+
+				header := http.Header{}
+				if lastID != "" {
+					header.Set("Last-Event-ID", lastID)
+				}
+
+				if res, err = c.sendWithHeader(ctx, http.MethodGet, path, nil, header); err == nil {
+					delay = c.RetryDelay
+					break
+				}
+				if delay < MAX_RECONNECT_DELAY {
+					delay *= 2
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// SubscribeOutreach streams the outreach messages sent to a method (see Stream)
+func (c *Client) SubscribeOutreach(ctx context.Context, method types.OutreachMethod) (<-chan types.APIOutreach, error) {
+	events, err := c.Stream(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan types.APIOutreach)
+	go func() {
+		defer close(ch)
+
+		for e := range events {
+			if e.Outreach == nil {
+				continue
+			}
+
+			select {
+			case ch <- *e.Outreach:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+/* ---- SERVER-SENT EVENTS ---- */
+
+// event is a server-sent event
+type event struct {
+	id   string
+	name string
+	data string
+}
+
+// parseEvent decodes the payload of a server-sent event. Unknown events are skipped
+func parseEvent(e event) (Event, bool) {
+	parsed := Event{ID: e.id, Type: e.name}
+
+	var payload any
+	switch e.name {
+	case types.EVENT_OUTREACH:
+		parsed.Outreach = &types.APIOutreach{}
+		payload = parsed.Outreach
+	case types.EVENT_TOKEN:
+		parsed.Token = &types.APIToken{}
+		payload = parsed.Token
+	case types.EVENT_REPLY:
+		parsed.Reply = &types.APIReply{}
+		payload = parsed.Reply
+	default:
+		return parsed, false
+	}
+
+	return parsed, json.Unmarshal([]byte(e.data), payload) == nil
+}
+
+// readEvents calls fn for each server-sent event in a stream until the stream ends
+func readEvents(r io.Reader, fn func(e event)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var e event
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// A blank line dispatches the event
+		if line == "" {
+			if len(data) > 0 {
+				e.data = strings.Join(data, "\n")
+				fn(e)
+			}
+			e, data = event{}, nil
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			e.id = value
+		case "event":
+			e.name = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Package common connects Horus implementations to a bot. An implementation only provides its
+// transport (receiving messages and sending outputs) and a Runner does the rest: resolving the
+// conversation a message belongs to, sending it to the bot, reporting errors, delivering the
+// output and starting conversations from outreach messages
+package common
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/format"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+/* -------- CONSTANTS -------- */
+
+// How many received messages can wait to be handled
+const QUEUE_SIZE = 64
+
+// The permissions of messages that don't set any
+const DEFAULT_PERMISSIONS = 0xFF
+
+/* -------- TYPES -------- */
+
+// Horus is the part of a Horus bot implementations use
+type Horus interface {
+	IsConversation(key string) bool
+	AddConversation(key string) error
+	ResolveSession(key string) (string, error)
+	RotateSession(key string) (string, error)
+	SendMessage(key string, input *types.Input) (*types.Output, error)
+	AddMessage(key string, role string, name string, content string) error
+	Maintain() error
+}
+
+// Route describes the conversation a message belongs to. A conversation key is used if the
+// conversation exists, then the session is used. Messages with neither are ignored unless the
+// conversation should be created
+type Route struct {
+	Key     string // A fixed conversation key (ex: a thread)
+	Session string // A session key resolved to the session's current conversation (ex: a chat)
+	Create  bool   // Whether a missing conversation key is created
+}
+
+// Message is a message received by an implementation
+type Message struct {
+	Route
+	Target any    // Where the reply is sent, passed back to the implementation's Send
+	Text   string // The message's content
+	Caller string // Who sent the message
+	Data   any    // Any data sent with the message (ex: a types.FormResponse)
+
+	Attachments []types.Attachment // Files sent with the message
+
+	Permissions byte // The caller's permissions (0 gives every permission, as in the server's API)
+}
+
+// Implementation is the transport of a Horus implementation
+type Implementation interface {
+	// Name names the implementation in logs (ex: discord)
+	Name() string
+
+	// Send formats an output and sends it, along with its files, to a target
+	Send(target any, output *types.Output) error
+
+	// SendOutreach sends an outreach message to the user and returns the route of the
+	// conversation replies to it continue. Sessions are rotated and keys are created
+	SendOutreach(output *types.Output) (Route, error)
+}
+
+// Broadcaster is implemented by implementations that send outreach to several users. Each user
+// gets their own copy of an outreach message and their own conversation
+type Broadcaster interface {
+	// BroadcastOutreach sends an outreach message to every user and returns the route of each
+	// conversation it was delivered to, along with any errors from the users it wasn't
+	BroadcastOutreach(output *types.Output) ([]Route, error)
+}
+
+/* -------- RUNNER -------- */
+
+// Runner handles messages and outreach for an implementation. Bots are not safe for concurrent
+// use, so a runner must only be used from one goroutine. Implementations with their own event
+// loop call Handle and Outreach from it and run the work in Queue; others call Run. Work is
+// queued with Receive and Do
+type Runner struct {
+	bot   Horus
+	impl  Implementation
+	queue chan func()
+}
+
+// NewRunner creates a runner connecting an implementation to a bot
+func NewRunner(bot Horus, impl Implementation) *Runner {
+	return &Runner{
+		bot:   bot,
+		impl:  impl,
+		queue: make(chan func(), QUEUE_SIZE),
+	}
+}
+
+// Run handles queued work and outreach messages until stop is closed
+func (r *Runner) Run(outreach <-chan string, stop <-chan struct{}) {
+	for {
+		select {
+		case f := <-r.queue:
+			f()
+
+		case content := <-outreach:
+			r.Outreach(content)
+
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Queue returns the work queued with Receive and Do, for implementations that run it from their
+// own event loop instead of calling Run
+func (r *Runner) Queue() <-chan func() {
+	return r.queue
+}
+
+// Receive queues a message to be handled by Run. It is safe to call from any goroutine
+func (r *Runner) Receive(m Message) {
+	r.queue <- func() { r.Handle(m) }
+}
+
+// Do queues a function that uses the bot to be called by Run. It is safe to call from any
+// goroutine
+func (r *Runner) Do(f func(bot Horus)) {
+	r.queue <- func() { f(r.bot) }
+}
+
+// Every queues a function that uses the bot every interval, like Do. It returns immediately
+func (r *Runner) Every(interval time.Duration, f func(bot Horus)) {
+	go func() {
+		for range time.Tick(interval) {
+			r.Do(f)
+		}
+	}()
+}
+
+// Maintain applies the bot's session policy and audit retention every interval. Maintenance is
+// queued like messages, since bots cannot be used concurrently
+func (r *Runner) Maintain(interval time.Duration) {
+	r.Every(interval, func(bot Horus) {
+		if err := bot.Maintain(); err != nil {
+			log.Printf("[ERROR]: In %v, error maintaining bot (err: %v)\n", r.impl.Name(), err)
+		}
+	})
+}
+
+// Handle sends a message to its conversation and sends the output back
+func (r *Runner) Handle(m Message) {
+	// Determine what conversation this message should belong to
+	name, err := r.resolve(m.Route)
+	if err != nil {
+		r.Error(m.Target, err)
+		return
+	} else if name == "" {
+		return
+	}
+
+	permissions := m.Permissions
+	if permissions == 0 {
+		permissions = DEFAULT_PERMISSIONS
+	}
+
+	// Send the message to the horus bot
+	resp, err := r.bot.SendMessage(name, &types.Input{
+		Message:     m.Text,
+		Permissions: permissions,
+		Caller:      m.Caller,
+		Data:        m.Data,
+		Attachments: m.Attachments,
+	})
+
+	// Reply with any errors if they occur
+	if err != nil {
+		r.Error(m.Target, err)
+		return
+	} else if resp.Error != nil {
+		r.Error(m.Target, resp.Error)
+		return
+	}
+
+	// Send the output
+	if err := r.impl.Send(m.Target, resp); err != nil {
+		log.Printf("[ERROR]: In %v, error sending output (err: %v)\n", r.impl.Name(), err)
+	}
+}
+
+// Error tells the user an error occurred
+func (r *Runner) Error(target any, err error) {
+	output := &types.Output{Message: format.Escape(fmt.Sprintf("Sorry, an error occurred: %v", err))}
+	if err := r.impl.Send(target, output); err != nil {
+		log.Printf("[ERROR]: In %v, error sending error (err: %v)\n", r.impl.Name(), err)
+	}
+}
+
+// Outreach sends an outreach message to the user, or to every user of a Broadcaster, and starts
+// a new conversation with it
+func (r *Runner) Outreach(content string) {
+	// Send the user a message
+	var routes []Route
+	var err error
+	if b, ok := r.impl.(Broadcaster); ok {
+		routes, err = b.BroadcastOutreach(&types.Output{Message: content})
+	} else {
+		var route Route
+		if route, err = r.impl.SendOutreach(&types.Output{Message: content}); err == nil {
+			routes = []Route{route}
+		}
+	}
+	if err != nil {
+		log.Printf("[ERROR]: In %v, error sending user message (err: %v)\n", r.impl.Name(), err)
+	}
+
+	for _, route := range routes {
+		r.startOutreach(route, content)
+	}
+}
+
+// startOutreach starts a new conversation with an outreach message that was sent to a route
+func (r *Runner) startOutreach(route Route, content string) {
+	// Always create a new conversation when an outreach message appears
+	var name string
+	var err error
+	if route.Session != "" {
+		name, err = r.bot.RotateSession(route.Session)
+	} else {
+		name, err = route.Key, r.bot.AddConversation(route.Key)
+	}
+	if err != nil {
+		log.Printf("[ERROR]: In %v, error creating conversation (err: %v)\n", r.impl.Name(), err)
+		return
+	}
+
+	// Add the outreach message to the conversation
+	if err := r.bot.AddMessage(name, openai.ChatMessageRoleAssistant, "", content); err != nil {
+		log.Printf("[ERROR]: In %v, error adding message to conversation (err: %v)\n", r.impl.Name(), err)
+	}
+}
+
+// resolve finds the conversation of a route, returning an empty name if the message should be
+// ignored
+func (r *Runner) resolve(route Route) (string, error) {
+	if route.Key != "" && r.bot.IsConversation(route.Key) {
+		return route.Key, nil
+	}
+
+	if route.Session != "" {
+		return r.bot.ResolveSession(route.Session)
+	}
+
+	if route.Key != "" && route.Create {
+		return route.Key, r.bot.AddConversation(route.Key)
+	}
+
+	return "", nil
+}
@@ -0,0 +1,182 @@
+package common
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethanbaker/horus/implementations/common/commontest"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeImplementation records the outputs sent to each target
+type fakeImplementation struct {
+	sent  map[any][]string
+	route Route
+}
+
+func (f *fakeImplementation) Name() string {
+	return "fake"
+}
+
+func (f *fakeImplementation) Send(target any, output *types.Output) error {
+	f.sent[target] = append(f.sent[target], output.Message)
+	return nil
+}
+
+func (f *fakeImplementation) SendOutreach(output *types.Output) (Route, error) {
+	f.sent["outreach"] = append(f.sent["outreach"], output.Message)
+	return f.route, nil
+}
+
+func setup() (*commontest.Horus, *fakeImplementation, *Runner) {
+	h := commontest.New()
+	impl := &fakeImplementation{sent: map[any][]string{}}
+	return h, impl, NewRunner(h, impl)
+}
+
+func TestHandle(t *testing.T) {
+	assert := assert.New(t)
+	h, impl, r := setup()
+
+	// Sessions resolve to their current conversation
+	r.Handle(Message{Route: Route{Session: "chat"}, Target: 1, Text: "hi", Caller: "ethan"})
+	assert.Equal([]string{"ethan: hi"}, h.Messages["chat#0"])
+	assert.Equal([]string{"<STRONG>echo<STRONG>: hi"}, impl.sent[1])
+
+	// Keys are only used if the conversation exists or should be created
+	r.Handle(Message{Route: Route{Key: "thread"}, Target: 2, Text: "hi"})
+	assert.Empty(impl.sent[2])
+
+	r.Handle(Message{Route: Route{Key: "thread", Create: true}, Target: 2, Text: "hi"})
+	r.Handle(Message{Route: Route{Key: "thread", Session: "chat"}, Target: 2, Text: "again"})
+	assert.Equal([]string{": hi", ": again"}, h.Messages["thread"])
+	assert.Len(impl.sent[2], 2)
+
+	// Data is sent along with the message
+	r.Handle(Message{Route: Route{Session: "chat"}, Target: 3, Data: types.FormResponse{ID: "profile"}})
+	assert.Equal([]string{"form: profile"}, impl.sent[3])
+
+	// Attachments are sent along with the message
+	r.Handle(Message{Route: Route{Key: "files", Create: true}, Target: 5, Text: "look", Attachments: []types.Attachment{{Filename: "screenshot.png"}}})
+	assert.Equal([]string{": look", "attachment: screenshot.png"}, h.Messages["files"])
+
+	// Messages have every permission unless they are limited
+	r.Handle(Message{Route: Route{Session: "chat"}, Target: 4, Text: "permissions"})
+	r.Handle(Message{Route: Route{Session: "chat"}, Target: 4, Text: "permissions", Permissions: 0b101})
+	assert.Equal([]string{"permissions: 11111111", "permissions: 00000101"}, impl.sent[4])
+}
+
+func TestErrors(t *testing.T) {
+	assert := assert.New(t)
+	h, impl, r := setup()
+	h.Replies["bad output"] = &types.Output{Error: fmt.Errorf("bad <output>")}
+
+	r.Handle(Message{Route: Route{Session: "chat"}, Target: 1, Text: "fail"})
+	r.Handle(Message{Route: Route{Session: "chat"}, Target: 1, Text: "bad output"})
+
+	assert.Equal([]string{
+		"<LITERAL>Sorry, an error occurred: failed</LITERAL>",
+		`<LITERAL>Sorry, an error occurred: bad \<output></LITERAL>`,
+	}, impl.sent[1])
+}
+
+func TestOutreach(t *testing.T) {
+	assert := assert.New(t)
+	h, impl, r := setup()
+
+	// Sessions are rotated
+	impl.route = Route{Session: "chat"}
+	r.Outreach("Good morning")
+	assert.Equal([]string{"Good morning"}, impl.sent["outreach"])
+	assert.Equal([]string{"assistant: Good morning"}, h.Messages["chat#1"])
+
+	// Keys are created
+	impl.route = Route{Key: "email"}
+	r.Outreach("Digest")
+	assert.Equal([]string{"assistant: Digest"}, h.Messages["email"])
+}
+
+// fakeBroadcaster sends outreach to several users, failing for one of them
+type fakeBroadcaster struct {
+	*fakeImplementation
+}
+
+func (f fakeBroadcaster) BroadcastOutreach(output *types.Output) ([]Route, error) {
+	f.sent["outreach"] = append(f.sent["outreach"], output.Message)
+	return []Route{{Session: "ann"}, {Key: "bob"}}, fmt.Errorf("carl: failed")
+}
+
+func TestBroadcastOutreach(t *testing.T) {
+	assert := assert.New(t)
+	h, impl, _ := setup()
+	r := NewRunner(h, fakeBroadcaster{impl})
+
+	// Every user the message was delivered to gets their own conversation
+	r.Outreach("Good morning")
+	assert.Equal([]string{"Good morning"}, impl.sent["outreach"])
+	assert.Equal([]string{"assistant: Good morning"}, h.Messages["ann#1"])
+	assert.Equal([]string{"assistant: Good morning"}, h.Messages["bob"])
+}
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+	h, impl, r := setup()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	outreach := make(chan string)
+	go func() {
+		r.Run(outreach, stop)
+		close(done)
+	}()
+
+	r.Receive(Message{Route: Route{Session: "chat"}, Target: 1, Text: "queued"})
+	r.Do(func(bot Horus) { bot.AddConversation("made") })
+	outreach <- "Hello"
+
+	// Wait for the queue to be handled
+	flushed := make(chan struct{})
+	r.Do(func(Horus) { close(flushed) })
+	<-flushed
+	close(stop)
+	<-done
+
+	assert.Equal([]string{"<STRONG>echo<STRONG>: queued"}, impl.sent[1])
+	assert.True(h.IsConversation("made"))
+	assert.Equal([]string{"Hello"}, impl.sent["outreach"])
+}
+
+func TestQueue(t *testing.T) {
+	assert := assert.New(t)
+	h, impl, r := setup()
+
+	// Work queued with Receive and Do waits for the implementation's loop to run it
+	r.Receive(Message{Route: Route{Session: "chat"}, Target: 1, Text: "queued"})
+	r.Do(func(bot Horus) { bot.AddConversation("made") })
+	assert.False(h.IsConversation("made"))
+
+	(<-r.Queue())()
+	(<-r.Queue())()
+	assert.Equal([]string{"<STRONG>echo<STRONG>: queued"}, impl.sent[1])
+	assert.True(h.IsConversation("made"))
+}
+
+func TestMaintain(t *testing.T) {
+	assert := assert.New(t)
+	h, _, r := setup()
+
+	// Maintenance is queued every interval instead of running alongside messages
+	r.Maintain(time.Millisecond)
+	(<-r.Queue())()
+	assert.Equal(1, h.Maintained)
+	(<-r.Queue())()
+	assert.Equal(2, h.Maintained)
+
+	// Other work can be queued the same way
+	h, _, r = setup()
+	r.Every(time.Millisecond, func(bot Horus) { bot.AddConversation("every") })
+	(<-r.Queue())()
+	assert.True(h.IsConversation("every"))
+}
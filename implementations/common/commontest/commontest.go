@@ -0,0 +1,88 @@
+// Package commontest provides a fake Horus bot for testing implementations
+package commontest
+
+import (
+	"fmt"
+
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+// Horus is an in-memory Horus bot. Messages are echoed back in bold unless they have a reply or
+// an error, and messages asking for "permissions" are answered with the caller's permissions
+type Horus struct {
+	Messages   map[string][]string      // The messages of each conversation
+	Sessions   map[string]int           // How many times each session was rotated
+	Replies    map[string]*types.Output // The outputs sent back for specific messages
+	Errors     map[string]error         // The errors returned for specific messages
+	Maintained int                      // How many times the bot was maintained
+}
+
+// New creates a fake Horus bot that fails on messages asking it to "fail"
+func New() *Horus {
+	return &Horus{
+		Messages: map[string][]string{},
+		Sessions: map[string]int{},
+		Replies:  map[string]*types.Output{},
+		Errors:   map[string]error{"fail": fmt.Errorf("failed")},
+	}
+}
+
+// IsConversation returns whether a conversation exists
+func (h *Horus) IsConversation(key string) bool {
+	_, ok := h.Messages[key]
+	return ok
+}
+
+// AddConversation adds an empty conversation
+func (h *Horus) AddConversation(key string) error {
+	if h.IsConversation(key) {
+		return fmt.Errorf("conversation already exists")
+	}
+	h.Messages[key] = []string{}
+	return nil
+}
+
+// ResolveSession returns the conversation of a session, named after how many times it was
+// rotated (ex: "chat#0")
+func (h *Horus) ResolveSession(key string) (string, error) {
+	return fmt.Sprintf("%v#%v", key, h.Sessions[key]), nil
+}
+
+// RotateSession starts a new conversation in a session
+func (h *Horus) RotateSession(key string) (string, error) {
+	h.Sessions[key]++
+	return h.ResolveSession(key)
+}
+
+// SendMessage records a message and its attachments, then replies to it
+func (h *Horus) SendMessage(key string, input *types.Input) (*types.Output, error) {
+	h.Messages[key] = append(h.Messages[key], input.Caller+": "+input.Message)
+	for _, a := range input.Attachments {
+		h.Messages[key] = append(h.Messages[key], "attachment: "+a.Filename)
+	}
+
+	if err, ok := h.Errors[input.Message]; ok {
+		return nil, err
+	} else if output, ok := h.Replies[input.Message]; ok {
+		return output, output.Error
+	}
+
+	if form, ok := input.Data.(types.FormResponse); ok {
+		return &types.Output{Message: "form: " + form.ID}, nil
+	} else if input.Message == "permissions" {
+		return &types.Output{Message: fmt.Sprintf("permissions: %08b", input.Permissions)}, nil
+	}
+	return &types.Output{Message: "<STRONG>echo<STRONG>: " + input.Message}, nil
+}
+
+// AddMessage records a message added without a reply
+func (h *Horus) AddMessage(key string, role string, name string, content string) error {
+	h.Messages[key] = append(h.Messages[key], role+": "+content)
+	return nil
+}
+
+// Maintain records that the bot was maintained
+func (h *Horus) Maintain() error {
+	h.Maintained++
+	return nil
+}
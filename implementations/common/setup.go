@@ -0,0 +1,85 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	horus "github.com/ethanbaker/horus/bot"
+	module_ambient "github.com/ethanbaker/horus/bot/module_ambient"
+	module_config "github.com/ethanbaker/horus/bot/module_config"
+	module_keepass "github.com/ethanbaker/horus/bot/module_keepass"
+	"github.com/ethanbaker/horus/outreach"
+	"github.com/ethanbaker/horus/utils/types"
+	mysql_driver "github.com/go-sql-driver/mysql"
+	"github.com/sashabaranov/go-openai"
+)
+
+/* -------- SETUP -------- */
+
+// The name of the bot every implementation serves
+const BOT_NAME = "horus-main"
+
+// SQL config
+var SQL_CONFIG = mysql_driver.Config{
+	User:      os.Getenv("SQL_USER"),
+	Passwd:    os.Getenv("SQL_PASSWD"),
+	Net:       os.Getenv("SQL_NET"),
+	Addr:      os.Getenv("SQL_ADDR"),
+	DBName:    os.Getenv("SQL_DBNAME"),
+	ParseTime: true,
+	Loc:       time.Local,
+}
+
+// Setup describes how an implementation uses the bot
+type Setup struct {
+	Method         types.OutreachMethod // The outreach method the implementation delivers
+	SessionPolicy  horus.SessionPolicy  // How conversations are rotated and cleaned up
+	AuditRetention time.Duration        // How long tool audit records are kept
+}
+
+// NewBot connects to the database and OpenAI from the environment, then loads the main bot with
+// its modules (creating it the first time) and sets up outreach. It returns the bot and the
+// channel the implementation's outreach messages are sent on
+func NewBot(setup Setup) (*horus.Bot, chan string, error) {
+	if err := horus.InitSQL(SQL_CONFIG.FormatDSN()); err != nil {
+		return nil, nil, err
+	}
+
+	// Try to get a bot that we've already created, creating one if there isn't one
+	b, err := horus.GetBotByName(BOT_NAME)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting horus bot: %w", err)
+	}
+
+	if b == nil {
+		if b, err = horus.NewBot(BOT_NAME, horus.PERMISSIONS_ALL); err != nil {
+			return nil, nil, fmt.Errorf("error making horus bot: %w", err)
+		}
+	}
+
+	// Setup the bot
+	module_ambient.NewModule(b, true)
+	module_config.NewModule(b, true)
+	module_keepass.NewModule(b, true)
+	b.Setup(openai.NewClient(os.Getenv("OPENAI_TOKEN")))
+
+	b.SetSessionPolicy(setup.SessionPolicy)
+	b.SetAuditRetention(setup.AuditRetention)
+
+	// Setup outreach
+	if err = outreach.Setup(SQL_CONFIG.FormatDSN()); err != nil {
+		return nil, nil, fmt.Errorf("error initalizing db for outreach: %w", err)
+	}
+
+	ch, err := outreach.AddChannel(setup.Method)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error adding channel to outreach: %w", err)
+	}
+
+	if err = outreach.AddConfig(setup.Method, os.Getenv("BASE_PATH")+os.Getenv("OUTREACH_CONFIG")); err != nil {
+		return nil, nil, fmt.Errorf("error setting up outreach: %w", err)
+	}
+
+	return b, ch, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	horus "github.com/ethanbaker/horus/bot"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- CONSTANTS -------- */
+
+// The longest downloading an attachment can take
+const DOWNLOAD_TIMEOUT = 30 * time.Second
+
+// The largest attachment downloaded, in bytes. Larger attachments are sent without their content
+// so Horus still knows about them
+const MAX_DOWNLOAD_SIZE = horus.ATTACHMENT_MAX_SIZE
+
+/* -------- GLOBALS -------- */
+
+// The client attachments are downloaded with
+var downloadClient = &http.Client{Timeout: DOWNLOAD_TIMEOUT}
+
+/* -------- FUNCTIONS -------- */
+
+// downloadAttachments downloads the files attached to a message
+func downloadAttachments(attachments []*discordgo.MessageAttachment) ([]types.Attachment, error) {
+	files := []types.Attachment{}
+	for _, a := range attachments {
+		file := types.Attachment{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+		}
+
+		if a.Size <= MAX_DOWNLOAD_SIZE {
+			content, err := download(a.URL)
+			if err != nil {
+				return nil, fmt.Errorf("cannot download %v: %w", a.Filename, err)
+			}
+			file.Content = content
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// download gets the content of a URL, up to the largest attachment size
+func download(url string) ([]byte, error) {
+	resp, err := downloadClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v", resp.Status)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, MAX_DOWNLOAD_SIZE+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > MAX_DOWNLOAD_SIZE {
+		return nil, fmt.Errorf("file is larger than %v bytes", MAX_DOWNLOAD_SIZE)
+	}
+
+	return content, nil
+}
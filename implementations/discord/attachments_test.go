@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadAttachments(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.log" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("contents of " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	// Files are downloaded, and files that are too large are only described
+	files, err := downloadAttachments([]*discordgo.MessageAttachment{
+		{URL: server.URL + "/app.log", Filename: "app.log", ContentType: "text/plain; charset=utf-8", Size: 20},
+		{URL: server.URL + "/video.mp4", Filename: "video.mp4", ContentType: "video/mp4", Size: MAX_DOWNLOAD_SIZE + 1},
+	})
+	assert.Nil(err)
+	assert.Equal([]types.Attachment{
+		{Filename: "app.log", ContentType: "text/plain; charset=utf-8", Content: []byte("contents of /app.log"), Size: 20},
+		{Filename: "video.mp4", ContentType: "video/mp4", Size: MAX_DOWNLOAD_SIZE + 1},
+	}, files)
+
+	// Failed downloads are reported
+	_, err = downloadAttachments([]*discordgo.MessageAttachment{{URL: server.URL + "/missing.log", Filename: "missing.log"}})
+	assert.ErrorContains(err, "cannot download missing.log: unexpected status 404 Not Found")
+}
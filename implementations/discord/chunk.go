@@ -0,0 +1,181 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+/* -------- GLOBALS -------- */
+
+// Boundaries chunks are split on, in order of preference
+var chunkBoundaries = []string{"\n\n", "\n", " "}
+
+/* -------- TYPES -------- */
+
+// markdownState is the Discord markdown left open at a point in a message
+type markdownState struct {
+	fence    string // The fence of the open code block, if any
+	language string // The language of the open code block
+	spoiler  bool   // Whether a spoiler is open
+	inline   int    // Where the open inline code starts, or -1
+}
+
+/* -------- FUNCTIONS -------- */
+
+// splitMessage splits markdown into chunks of at most limit bytes. Chunks are split on paragraph,
+// then line, then word boundaries. Code blocks and spoilers open at a split are closed at the end
+// of the chunk and re-opened at the start of the next one, and inline code is never split
+func splitMessage(content string, limit int) []string {
+	chunks := []string{}
+
+	// The markdown re-opened at the start of a chunk can't be split off again
+	start := 0
+	for len(content) > limit {
+		chunk, rest, reopened := splitChunk(content, limit, start)
+		chunks = append(chunks, chunk)
+		content, start = rest, reopened
+	}
+
+	if strings.TrimSpace(content[start:]) != "" {
+		chunks = append(chunks, content)
+	}
+	return chunks
+}
+
+// splitChunk splits the first chunk off of markdown that is longer than the limit. It returns
+// the chunk with its markdown closed, the rest with its markdown re-opened and the length of the
+// re-opened markdown
+func splitChunk(content string, limit int, start int) (string, string, int) {
+	budget := limit
+	_, first := utf8.DecodeRuneInString(content[start:])
+	for {
+		cut, skip := findCut(content, budget, start)
+		state := scanMarkdown(content[:cut])
+
+		// Never split inline code, moving the cut before it if possible
+		if state.inline > start {
+			cut, skip = state.inline, 0
+			state = scanMarkdown(content[:cut])
+		}
+
+		chunk := strings.TrimRight(content[:cut], " ") + closers(state)
+
+		// Shrink the budget until the chunk fits with its closing markdown, since a shorter cut
+		// can leave different markdown open. A character is always kept after the re-opened
+		// markdown so splitting makes progress
+		if over := len(chunk) - limit; over > 0 && budget > start+first {
+			budget -= over
+			if budget < start+first {
+				budget = start + first
+			}
+			continue
+		}
+
+		// Blank lines in code blocks are kept
+		rest := content[cut+skip:]
+		if state.fence != "" && skip > 1 {
+			rest = content[cut+1:]
+		}
+
+		reopen := openers(state)
+		return chunk, reopen + rest, len(reopen)
+	}
+}
+
+// findCut finds where to split content so the chunk fits in the budget. It returns the index to
+// cut at and the length of the boundary skipped after it
+func findCut(content string, budget int, start int) (int, int) {
+	for _, boundary := range chunkBoundaries {
+		if i := strings.LastIndex(content[:budget], boundary); i > start {
+			return i, len(boundary)
+		}
+	}
+
+	// Cut words that are too long, keeping characters whole
+	cut := budget
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return cut, 0
+}
+
+// scanMarkdown finds the markdown left open at the end of text
+func scanMarkdown(text string) markdownState {
+	state := markdownState{inline: -1}
+	inlineFence := ""
+
+	for i := 0; i < len(text); {
+		// Code block content is only ended by its fence
+		if state.fence != "" {
+			if strings.HasPrefix(text[i:], state.fence) {
+				i += len(state.fence)
+				state.fence, state.language = "", ""
+				continue
+			}
+			i++
+			continue
+		}
+
+		switch {
+		case text[i] == '\\' && state.inline < 0:
+			i += 2
+
+		case text[i] == '`':
+			run := i
+			for run < len(text) && text[run] == '`' {
+				run++
+			}
+			fence := text[i:run]
+
+			switch {
+			case state.inline >= 0:
+				// Inline code is only ended by a matching run of backticks
+				if fence == inlineFence {
+					state.inline, inlineFence = -1, ""
+				}
+			case len(fence) >= 3:
+				state.fence = fence
+				if end := strings.IndexByte(text[run:], '\n'); end >= 0 && !strings.ContainsAny(text[run:run+end], " `") {
+					state.language = text[run : run+end]
+				}
+			default:
+				state.inline, inlineFence = i, fence
+			}
+			i = run
+
+		case strings.HasPrefix(text[i:], "||") && state.inline < 0:
+			state.spoiler = !state.spoiler
+			i += 2
+
+		default:
+			i++
+		}
+	}
+
+	return state
+}
+
+// closers returns the markdown that closes an open state. Code blocks are always inside spoilers
+// since spoilers can't be opened in code
+func closers(state markdownState) string {
+	s := ""
+	if state.fence != "" {
+		s += "\n" + state.fence
+	}
+	if state.spoiler {
+		s += "||"
+	}
+	return s
+}
+
+// openers returns the markdown that re-opens an open state
+func openers(state markdownState) string {
+	s := ""
+	if state.spoiler {
+		s += "||"
+	}
+	if state.fence != "" {
+		s += state.fence + state.language + "\n"
+	}
+	return s
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	// Short messages are sent as they are
+	assert.Equal([]string{"hello"}, splitMessage("hello", 20))
+	assert.Equal([]string{}, splitMessage("", 20))
+
+	// Paragraphs are preferred over lines, and lines over words
+	assert.Equal([]string{"one two", "three four"}, splitMessage("one two\n\nthree four", 12))
+	assert.Equal([]string{"one\ntwo", "three"}, splitMessage("one\ntwo\nthree", 8))
+	assert.Equal([]string{"one two", "three"}, splitMessage("one two three", 8))
+
+	// Words that are too long are cut
+	assert.Equal([]string{"abcde", "fghij"}, splitMessage("abcdefghij", 5))
+}
+
+func TestSplitMarkdown(t *testing.T) {
+	assert := assert.New(t)
+
+	// Code blocks are closed and re-opened with their language
+	chunks := splitMessage("Code:\n```go\nline1\nline2\nline3\n```", 24)
+	assert.Equal([]string{"Code:\n```go\nline1\n```", "```go\nline2\nline3\n```"}, chunks)
+
+	// Spoilers are closed and re-opened
+	chunks = splitMessage("||secret one two||", 13)
+	assert.Equal([]string{"||secret||", "||one two||"}, chunks)
+
+	// Inline code is never split
+	chunks = splitMessage("see `a b c d`", 12)
+	assert.Equal([]string{"see", "`a b c d`"}, chunks)
+
+	// Escaped markdown doesn't open anything
+	chunks = splitMessage(`\|\| a b`, 7)
+	assert.Equal([]string{`\|\| a`, "b"}, chunks)
+
+	// Every chunk fits
+	long := "||" + strings.Repeat("word ", 100) + "||\n```\n" + strings.Repeat("code\n", 100) + "```"
+	for _, chunk := range splitMessage(long, 100) {
+		assert.LessOrEqual(len(chunk), 100)
+		assert.Equal(0, strings.Count(chunk, "||")%2)
+		assert.Equal(0, strings.Count(chunk, "```")%2)
+	}
+}
+
+func TestSplitMessageLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	// Generate messages from pieces of markdown and check every chunk fits in the limit
+	pieces := []string{"word", "a", "longerword", " ", " ", "\n", "\n\n", "||", "`", "```", "```go\n", "\\", "é", "日本語", "**bold**"}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		var sb strings.Builder
+		for n := r.Intn(120); n > 0; n-- {
+			sb.WriteString(pieces[r.Intn(len(pieces))])
+		}
+		content := sb.String()
+		limit := 20 + r.Intn(80)
+
+		// Chunks can only fit if the limit leaves room for a character inside the longest
+		// re-opened and closed code block and spoiler
+		fence := 0
+		for _, run := range regexp.MustCompile("`+").FindAllString(content, -1) {
+			if len(run) > fence {
+				fence = len(run)
+			}
+		}
+		if limit < len(openers(markdownState{fence: strings.Repeat("`", fence), language: "go", spoiler: true}))+len(closers(markdownState{fence: strings.Repeat("`", fence), spoiler: true}))+utf8.UTFMax {
+			continue
+		}
+
+		for _, chunk := range splitMessage(content, limit) {
+			if !assert.LessOrEqual(len(chunk), limit, "limit %v: %q", limit, content) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/ethanbaker/horus/implementations/common"
+	"github.com/ethanbaker/horus/utils/schema"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+/* -------- CONSTANTS -------- */
+
+// Discord command limits
+const (
+	MAX_COMMANDS            = 100
+	MAX_COMMAND_NAME        = 32
+	MAX_COMMAND_DESCRIPTION = 100
+	MAX_CHOICES             = 25
+)
+
+// Prefixes removed from function names to name their commands, so get_current_weather is /weather
+var COMMAND_PREFIXES = []string{"get_", "current_"}
+
+// Characters that can't be in command names
+var invalidCommandChars = regexp.MustCompile(`[^\p{Ll}\p{N}_-]`)
+
+// The option types of each schema type. Other types can't be command options
+var optionTypes = map[schema.DataType]discordgo.ApplicationCommandOptionType{
+	schema.String:  discordgo.ApplicationCommandOptionString,
+	schema.Integer: discordgo.ApplicationCommandOptionInteger,
+	schema.Number:  discordgo.ApplicationCommandOptionNumber,
+	schema.Boolean: discordgo.ApplicationCommandOptionBoolean,
+}
+
+/* -------- TYPES -------- */
+
+// Functions is the part of a Horus bot used to run function commands
+type Functions interface {
+	Functions() []openai.FunctionDefinition
+	CallFunction(key string, function string, arguments string, input *types.Input) (*types.Output, error)
+}
+
+/* -------- GLOBALS -------- */
+
+// The Horus bot's functions. They must only be called through the runner since the bot is not
+// safe for concurrent use
+var functions Functions
+
+// The function each generated command calls
+var functionCommands = map[string]string{}
+
+/* -------- FUNCTIONS -------- */
+
+// commandsFor creates a slash command for each function. Functions with parameters that can't
+// be command options are skipped. It returns the commands and the function each one calls
+func commandsFor(definitions []openai.FunctionDefinition, reserved ...string) ([]*discordgo.ApplicationCommand, map[string]string) {
+	commands := []*discordgo.ApplicationCommand{}
+	names := map[string]string{}
+	for _, r := range reserved {
+		names[r] = ""
+	}
+
+	for _, def := range definitions {
+		name := commandName(def.Name)
+		if _, ok := names[name]; ok {
+			log.Printf("[WARNING]: In discord, skipping function %v since command /%v already exists\n", def.Name, name)
+			continue
+		}
+		if len(commands)+len(reserved) == MAX_COMMANDS {
+			log.Printf("[WARNING]: In discord, skipping function %v since there are too many commands\n", def.Name)
+			continue
+		}
+
+		options, ok := commandOptions(def.Parameters)
+		if !ok {
+			log.Printf("[WARNING]: In discord, skipping function %v since its parameters can't be command options\n", def.Name)
+			continue
+		}
+
+		commands = append(commands, &discordgo.ApplicationCommand{
+			Name:        name,
+			Description: truncate(def.Description, def.Name, MAX_COMMAND_DESCRIPTION),
+			Options:     options,
+		})
+		names[name] = def.Name
+	}
+
+	for _, r := range reserved {
+		delete(names, r)
+	}
+	return commands, names
+}
+
+// commandName names the command for a function
+func commandName(function string) string {
+	name := strings.ToLower(function)
+	for _, prefix := range COMMAND_PREFIXES {
+		if trimmed := strings.TrimPrefix(name, prefix); trimmed != "" {
+			name = trimmed
+		}
+	}
+
+	// Names are limited in characters, so they are cut on runes to stay valid UTF-8
+	name = invalidCommandChars.ReplaceAllString(name, "_")
+	return cut(name, MAX_COMMAND_NAME)
+}
+
+// commandOptions creates command options from a function's parameters. Required options are
+// listed first, as Discord requires. It returns false if a parameter can't be an option
+func commandOptions(parameters any) ([]*discordgo.ApplicationCommandOption, bool) {
+	var def schema.Definition
+	switch p := parameters.(type) {
+	case nil:
+		return nil, true
+	case schema.Definition:
+		def = p
+	case *schema.Definition:
+		def = *p
+	default:
+		return nil, false
+	}
+
+	options := []*discordgo.ApplicationCommandOption{}
+	for name, prop := range def.Properties {
+		optionType, ok := optionTypes[prop.Type]
+		if !ok || name != strings.ToLower(name) || len(name) > MAX_COMMAND_NAME {
+			return nil, false
+		}
+
+		option := &discordgo.ApplicationCommandOption{
+			Type:        optionType,
+			Name:        name,
+			Description: truncate(prop.Description, name, MAX_COMMAND_DESCRIPTION),
+			Required:    contains(def.Required, name),
+		}
+
+		// Enums are offered as choices
+		if prop.Type == schema.String && len(prop.Enum) <= MAX_CHOICES {
+			for _, e := range prop.Enum {
+				option.Choices = append(option.Choices, &discordgo.ApplicationCommandOptionChoice{Name: e, Value: e})
+			}
+		}
+
+		options = append(options, option)
+	}
+
+	sort.Slice(options, func(i, j int) bool {
+		if options[i].Required != options[j].Required {
+			return options[i].Required
+		}
+		return options[i].Name < options[j].Name
+	})
+	return options, true
+}
+
+// onFunctionCommand runs the function of a generated command with the user's permissions and
+// sends its output to the channel
+func onFunctionCommand(s *discordgo.Session, i *discordgo.InteractionCreate, function string, permissions byte) {
+	data := i.ApplicationCommandData()
+
+	// Collect the arguments
+	args := map[string]any{}
+	for _, opt := range data.Options {
+		args[opt.Name] = opt.Value
+	}
+	arguments, err := json.Marshal(args)
+	if err != nil {
+		runner.Do(func(common.Horus) { runner.Error(i.ChannelID, err) })
+		return
+	}
+
+	// Acknowledge the command since the function might take a while
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "Running `" + function + "`...",
+		},
+	})
+	if err != nil {
+		log.Printf("[ERROR]: In discord, error responding to command (err: %v)\n", err)
+		return
+	}
+
+	runner.Do(func(common.Horus) {
+		resp, err := functions.CallFunction("discord-"+i.ChannelID, function, string(arguments), &types.Input{
+			Caller:      "discord-" + interactionUser(i).ID,
+			Permissions: permissions,
+		})
+		if err != nil {
+			runner.Error(i.ChannelID, err)
+			return
+		}
+
+		if err := sendOutput(s, i.ChannelID, resp); err != nil {
+			log.Printf("[ERROR]: In discord, error sending output (err: %v)\n", err)
+		}
+	})
+}
+
+/* ---- HELPERS ---- */
+
+// truncate shortens text to a limit of characters, using a fallback if the text is empty
+func truncate(text string, fallback string, limit int) string {
+	if text == "" {
+		text = fallback
+	}
+	if runes := []rune(text); len(runes) > limit {
+		text = strings.TrimSpace(string(runes[:limit-3])) + "..."
+	}
+	return text
+}
+
+// cut shortens text to a limit of characters without marking that it was cut. It is used for
+// values that are sent back, such as custom IDs
+func cut(text string, limit int) string {
+	if runes := []rune(text); len(runes) > limit {
+		text = string(runes[:limit])
+	}
+	return text
+}
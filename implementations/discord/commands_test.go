@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/ethanbaker/horus/utils/schema"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandName(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("weather", commandName("get_current_weather"))
+	assert.Equal("time", commandName("get_current_time"))
+	assert.Equal("set_timezone", commandName("set_timezone"))
+	assert.Equal("get_", commandName("get_"))
+	assert.Equal("send_email", commandName("Send Email"))
+	assert.Len(commandName("a_very_long_function_name_that_goes_on_and_on"), MAX_COMMAND_NAME)
+
+	// Long names are cut on characters, not bytes
+	name := commandName(strings.Repeat("é", 40))
+	assert.True(utf8.ValidString(name))
+	assert.Equal(strings.Repeat("é", MAX_COMMAND_NAME), name)
+	assert.Equal("a"+strings.Repeat("λ", MAX_COMMAND_NAME-1), commandName("a"+strings.Repeat("Λ", 40)))
+}
+
+func TestCommandsFor(t *testing.T) {
+	assert := assert.New(t)
+
+	definitions := []openai.FunctionDefinition{
+		{
+			Name:        "get_current_weather",
+			Description: "Get the current weather in a given location",
+			Parameters: schema.Definition{
+				Type: schema.Object,
+				Properties: map[string]schema.Definition{
+					"unit":     {Type: schema.String, Enum: []string{"celsius", "fahrenheit"}},
+					"location": {Type: schema.String, Description: "The city"},
+					"days":     {Type: schema.Integer},
+				},
+				Required: []string{"location"},
+			},
+		},
+		{
+			Name:       "get_current_time",
+			Parameters: &schema.Definition{Type: schema.Object},
+		},
+		{
+			Name:       "save_notes",
+			Parameters: schema.Definition{Type: schema.Object, Properties: map[string]schema.Definition{"notes": {Type: schema.Array}}},
+		},
+		{Name: "conversation"},
+		{Name: "current_weather"},
+	}
+
+	commands, names := commandsFor(definitions, "conversation")
+	assert.Equal(map[string]string{"weather": "get_current_weather", "time": "get_current_time"}, names)
+	assert.Len(commands, 2)
+
+	// Options are created from parameters, with required options first and enums as choices
+	weather := commands[0]
+	assert.Equal("weather", weather.Name)
+	assert.Equal("Get the current weather in a given location", weather.Description)
+	assert.Len(weather.Options, 3)
+
+	assert.Equal("location", weather.Options[0].Name)
+	assert.Equal("The city", weather.Options[0].Description)
+	assert.True(weather.Options[0].Required)
+
+	assert.Equal("days", weather.Options[1].Name)
+	assert.Equal(discordgo.ApplicationCommandOptionInteger, weather.Options[1].Type)
+	assert.False(weather.Options[1].Required)
+
+	assert.Equal("unit", weather.Options[2].Name)
+	assert.Equal([]*discordgo.ApplicationCommandOptionChoice{{Name: "celsius", Value: "celsius"}, {Name: "fahrenheit", Value: "fahrenheit"}}, weather.Options[2].Choices)
+
+	// Commands without descriptions are described by their function
+	assert.Equal("time", commands[1].Name)
+	assert.Equal("get_current_time", commands[1].Description)
+	assert.Empty(commands[1].Options)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	horus "github.com/ethanbaker/horus/bot"
+	"gopkg.in/yaml.v3"
+)
+
+/* -------- CONSTANTS -------- */
+
+// Channel modes
+const (
+	CHANNEL_OPEN   = "open"   // Every message in the channel is sent to the channel's session
+	CHANNEL_THREAD = "thread" // The conversation command creates a thread with its own conversation
+)
+
+/* -------- TYPES -------- */
+
+// Config configures the guilds and users the Discord bot serves. For example:
+//
+//	guilds:
+//	  - id: "123456789012345678"
+//	    channels:
+//	      - { id: "223456789012345678", mode: open }
+//	      - { id: "323456789012345678", mode: thread }
+//	default_permissions: none
+//	users:
+//	  - { id: "423456789012345678", permissions: all, outreach: true }
+//	  - { id: "523456789012345678", permissions: "gpt,public" }
+type Config struct {
+	Guilds             []GuildConfig `yaml:"guilds"`
+	Users              []UserConfig  `yaml:"users"`
+	DefaultPermissions string        `yaml:"default_permissions"` // The permissions of users that aren't listed
+
+	channels    map[string]string // The mode of each channel
+	permissions map[string]byte   // The permissions of each listed user
+	fallback    byte              // The permissions of other users
+}
+
+// GuildConfig configures a guild. Commands are registered in every guild
+type GuildConfig struct {
+	ID       string          `yaml:"id"`
+	Channels []ChannelConfig `yaml:"channels"`
+}
+
+// ChannelConfig configures a channel of a guild
+type ChannelConfig struct {
+	ID   string `yaml:"id"`
+	Mode string `yaml:"mode"` // How the bot is used in the channel (open or thread)
+}
+
+// UserConfig configures a user
+type UserConfig struct {
+	ID          string `yaml:"id"`
+	Permissions string `yaml:"permissions"` // The user's permissions (ex: "gpt,public")
+	Outreach    bool   `yaml:"outreach"`    // Whether outreach messages are sent to the user's DMs
+}
+
+/* -------- FUNCTIONS -------- */
+
+// loadConfig reads and validates a config file. Without a config file, a single guild and
+// outreach user are configured from the environment like before config files were supported
+func loadConfig(path string) (Config, error) {
+	if path == "" {
+		config := configFromEnv()
+		if err := config.validate(); err != nil {
+			return Config{}, fmt.Errorf("invalid environment (or set DISCORD_CONFIG to use a config file):\n%w", err)
+		}
+		return config, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	// Unknown fields are rejected so typos don't go unnoticed
+	config := Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("cannot parse %v: %w", path, err)
+	}
+
+	if err := config.validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid config %v:\n%w", path, err)
+	}
+	return config, nil
+}
+
+// configFromEnv creates a config from the DISCORD_GUILD_ID, DISCORD_BOT_OPEN_CHANNELS,
+// DISCORD_BOT_THREAD_CHANNELS and DISCORD_USER_ID environment variables. Every user has every
+// permission
+func configFromEnv() Config {
+	guild := GuildConfig{ID: os.Getenv("DISCORD_GUILD_ID")}
+	for _, channels := range []struct{ mode, env string }{
+		{CHANNEL_OPEN, "DISCORD_BOT_OPEN_CHANNELS"},
+		{CHANNEL_THREAD, "DISCORD_BOT_THREAD_CHANNELS"},
+	} {
+		for _, id := range strings.Split(os.Getenv(channels.env), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				guild.Channels = append(guild.Channels, ChannelConfig{ID: id, Mode: channels.mode})
+			}
+		}
+	}
+
+	config := Config{Guilds: []GuildConfig{guild}, DefaultPermissions: "all"}
+	if id := os.Getenv("DISCORD_USER_ID"); id != "" {
+		config.Users = append(config.Users, UserConfig{ID: id, Permissions: "all", Outreach: true})
+	}
+	return config
+}
+
+// validate checks a config, returning every problem it has at once, and indexes its channels
+// and users
+func (c *Config) validate() error {
+	problems := []error{}
+	problem := func(format string, a ...any) {
+		problems = append(problems, fmt.Errorf("  "+format, a...))
+	}
+
+	c.channels = map[string]string{}
+	c.permissions = map[string]byte{}
+
+	if len(c.Guilds) == 0 {
+		problem("at least one guild is required")
+	}
+
+	guilds := map[string]bool{}
+	for i, g := range c.Guilds {
+		if err := checkID(g.ID); err != nil {
+			problem("guilds[%v].id: %v", i, err)
+		} else if guilds[g.ID] {
+			problem("guilds[%v].id: guild %v is listed more than once", i, g.ID)
+		}
+		guilds[g.ID] = true
+
+		for j, ch := range g.Channels {
+			if err := checkID(ch.ID); err != nil {
+				problem("guilds[%v].channels[%v].id: %v", i, j, err)
+			} else if _, ok := c.channels[ch.ID]; ok {
+				problem("guilds[%v].channels[%v].id: channel %v is listed more than once", i, j, ch.ID)
+			}
+
+			if ch.Mode != CHANNEL_OPEN && ch.Mode != CHANNEL_THREAD {
+				problem("guilds[%v].channels[%v].mode: must be '%v' or '%v', not '%v'", i, j, CHANNEL_OPEN, CHANNEL_THREAD, ch.Mode)
+			}
+			c.channels[ch.ID] = ch.Mode
+		}
+	}
+
+	var err error
+	if c.fallback, err = horus.ParsePermissions(c.DefaultPermissions); err != nil {
+		problem("default_permissions: %v", err)
+	}
+
+	for i, u := range c.Users {
+		if err := checkID(u.ID); err != nil {
+			problem("users[%v].id: %v", i, err)
+		} else if _, ok := c.permissions[u.ID]; ok {
+			problem("users[%v].id: user %v is listed more than once", i, u.ID)
+		}
+
+		p, err := horus.ParsePermissions(u.Permissions)
+		if err != nil {
+			problem("users[%v].permissions: %v", i, err)
+		} else if u.Outreach && p&horus.PERMISSIONS_GPT == 0 {
+			problem("users[%v].outreach: the user can't reply to outreach messages without the 'gpt' permission", i)
+		}
+		c.permissions[u.ID] = p
+	}
+
+	return errors.Join(problems...)
+}
+
+// channelMode returns the mode of a channel, or an empty string if the bot isn't used in it
+func (c *Config) channelMode(channelID string) string {
+	return c.channels[channelID]
+}
+
+// addChannel uses the bot in a channel that isn't in the config (ex: an outreach DM). It must
+// be called before the bot starts handling messages
+func (c *Config) addChannel(channelID string, mode string) {
+	c.channels[channelID] = mode
+}
+
+// userPermissions returns the permissions of a user
+func (c *Config) userPermissions(userID string) byte {
+	if p, ok := c.permissions[userID]; ok {
+		return p
+	}
+	return c.fallback
+}
+
+// outreachUsers returns the users outreach messages are sent to
+func (c *Config) outreachUsers() []string {
+	users := []string{}
+	for _, u := range c.Users {
+		if u.Outreach {
+			users = append(users, u.ID)
+		}
+	}
+	return users
+}
+
+/* ---- HELPERS ---- */
+
+// checkID makes sure an ID is a Discord snowflake
+func checkID(id string) error {
+	if id == "" {
+		return fmt.Errorf("an id is required")
+	}
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return fmt.Errorf("'%v' is not a Discord id (ids are numbers, quote them to keep them exact)", id)
+	}
+	return nil
+}
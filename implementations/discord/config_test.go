@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	horus "github.com/ethanbaker/horus/bot"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "discord.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	config, err := loadConfig(writeConfig(t, `
+guilds:
+  - id: "100"
+    channels:
+      - { id: "101", mode: open }
+      - { id: "102", mode: thread }
+  - id: 200
+    channels:
+      - { id: "201", mode: open }
+default_permissions: gpt
+users:
+  - { id: "1", permissions: all, outreach: true }
+  - { id: "2", permissions: "gpt, public" }
+  - { id: "3", permissions: none }
+`))
+	assert.Nil(err)
+
+	// Channels in every guild are used in their mode
+	assert.Equal(CHANNEL_OPEN, config.channelMode("101"))
+	assert.Equal(CHANNEL_THREAD, config.channelMode("102"))
+	assert.Equal(CHANNEL_OPEN, config.channelMode("201"))
+	assert.Equal("", config.channelMode("300"))
+
+	config.addChannel("400", CHANNEL_OPEN)
+	assert.Equal(CHANNEL_OPEN, config.channelMode("400"))
+
+	// Users have their own permissions, and other users have the default ones
+	assert.Equal(byte(horus.PERMISSIONS_ALL), config.userPermissions("1"))
+	assert.Equal(byte(horus.PERMISSIONS_GPT|horus.PERMISSIONS_PUBMODULES), config.userPermissions("2"))
+	assert.Equal(byte(horus.PERMISSIONS_NONE), config.userPermissions("3"))
+	assert.Equal(byte(horus.PERMISSIONS_GPT), config.userPermissions("4"))
+	assert.Equal([]string{"1"}, config.outreachUsers())
+
+	// Every problem is reported at once
+	_, err = loadConfig(writeConfig(t, `
+guilds:
+  - id: "100"
+    channels:
+      - { id: "101", mode: opne }
+      - { id: "101", mode: open }
+      - { id: general, mode: open }
+users:
+  - { id: "1", permissions: "gpt,privte" }
+  - { id: "2", outreach: true }
+`))
+	assert.ErrorContains(err, "guilds[0].channels[0].mode: must be 'open' or 'thread', not 'opne'")
+	assert.ErrorContains(err, "guilds[0].channels[1].id: channel 101 is listed more than once")
+	assert.ErrorContains(err, "guilds[0].channels[2].id: 'general' is not a Discord id")
+	assert.ErrorContains(err, "users[0].permissions: unknown permission 'privte'")
+	assert.ErrorContains(err, "users[1].outreach: the user can't reply to outreach messages")
+
+	// Unknown fields and missing guilds are errors
+	_, err = loadConfig(writeConfig(t, "guild: 100\n"))
+	assert.ErrorContains(err, "field guild not found")
+
+	_, err = loadConfig(writeConfig(t, "users: []\n"))
+	assert.ErrorContains(err, "at least one guild is required")
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("DISCORD_GUILD_ID", "100")
+	t.Setenv("DISCORD_BOT_OPEN_CHANNELS", "101,102")
+	t.Setenv("DISCORD_BOT_THREAD_CHANNELS", "103")
+	t.Setenv("DISCORD_USER_ID", "1")
+
+	config, err := loadConfig("")
+	assert.Nil(err)
+	assert.Equal(CHANNEL_OPEN, config.channelMode("102"))
+	assert.Equal(CHANNEL_THREAD, config.channelMode("103"))
+	assert.Equal([]string{"1"}, config.outreachUsers())
+	assert.Equal(byte(horus.PERMISSIONS_ALL), config.userPermissions("2"))
+
+	t.Setenv("DISCORD_GUILD_ID", "")
+	_, err = loadConfig("")
+	assert.ErrorContains(err, "guilds[0].id: an id is required")
+}
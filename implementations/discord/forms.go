@@ -0,0 +1,180 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/ethanbaker/horus/implementations/common"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- CONSTANTS -------- */
+
+// Custom ID prefix for buttons that open forms and for the modals they open
+const FORM_PREFIX = "horus-form:"
+
+// Discord modal limits
+const (
+	MAX_MODAL_FIELDS = 5
+	MAX_MODAL_TITLE  = 45
+	MAX_INPUT_LABEL  = 45
+	MAX_PLACEHOLDER  = 100
+	MAX_INPUT_LENGTH = 4000
+)
+
+// The placeholder of secret fields without one
+const SECRET_PLACEHOLDER = "Sent to Horus only, never shown in the channel"
+
+/* -------- GLOBALS -------- */
+
+// The form offered in each channel, if any. Forms are sent from the runner and opened from
+// interaction handlers, so they are guarded by a mutex
+var (
+	formsMu sync.Mutex
+	forms   = map[string]types.Form{}
+)
+
+/* -------- FUNCTIONS -------- */
+
+// renderFormButton offers a form in a channel as a button that opens it. Forms with more fields
+// than a modal can hold aren't offered, so the user answers them step by step instead
+func renderFormButton(channelID string, form *types.Form) (discordgo.MessageComponent, bool) {
+	if form == nil || len(form.Fields) == 0 || len(form.Fields) > MAX_MODAL_FIELDS {
+		return nil, false
+	}
+
+	formsMu.Lock()
+	forms[channelID] = *form
+	formsMu.Unlock()
+
+	return discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    truncate(form.Title, "Open form", MAX_BUTTON_LABEL),
+				Style:    discordgo.PrimaryButton,
+				CustomID: FORM_PREFIX + form.ID,
+			},
+		},
+	}, true
+}
+
+// renderForm renders the fields of a form as the text inputs of a modal. Discord can't mask
+// inputs, but the answers are only sent to Horus and never posted in the channel
+func renderForm(form types.Form) []discordgo.MessageComponent {
+	rows := []discordgo.MessageComponent{}
+	for _, f := range form.Fields {
+		style := discordgo.TextInputShort
+		if f.Multiline {
+			style = discordgo.TextInputParagraph
+		}
+
+		placeholder := f.Placeholder
+		if placeholder == "" && f.Secret {
+			placeholder = SECRET_PLACEHOLDER
+		}
+
+		rows = append(rows, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.TextInput{
+					CustomID:    f.Name,
+					Label:       truncate(f.Label, f.Name, MAX_INPUT_LABEL),
+					Style:       style,
+					Placeholder: truncate(placeholder, "", MAX_PLACEHOLDER),
+					Required:    f.Required,
+					MaxLength:   MAX_INPUT_LENGTH,
+				},
+			},
+		})
+	}
+
+	return rows
+}
+
+// onFormOpen opens the form offered in a channel as a modal
+func onFormOpen(s *discordgo.Session, i *discordgo.InteractionCreate, id string) {
+	formsMu.Lock()
+	form, ok := forms[i.ChannelID]
+	formsMu.Unlock()
+
+	resp := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID:   FORM_PREFIX + id,
+			Title:      truncate(form.Title, "Form", MAX_MODAL_TITLE),
+			Components: renderForm(form),
+		},
+	}
+
+	// Only the latest form in a channel can be answered
+	if !ok || form.ID != id {
+		resp = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "This form is no longer open.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}
+	}
+
+	if err := s.InteractionRespond(i.Interaction, resp); err != nil {
+		log.Printf("[ERROR]: In discord, error opening form (err: %v)\n", err)
+	}
+}
+
+// onFormSubmit sends the answers to a form to the channel's conversation as the input's data,
+// with the user's permissions. The answers are never posted in the channel or added to the
+// conversation
+func onFormSubmit(s *discordgo.Session, i *discordgo.InteractionCreate, permissions byte) {
+	data := i.ModalSubmitData()
+	if !strings.HasPrefix(data.CustomID, FORM_PREFIX) {
+		return
+	}
+
+	// Collect the answers
+	values := map[string]string{}
+	for _, c := range data.Components {
+		row, ok := c.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, rc := range row.Components {
+			if input, ok := rc.(*discordgo.TextInput); ok {
+				values[input.CustomID] = input.Value
+			}
+		}
+	}
+
+	// The form can only be answered once
+	formsMu.Lock()
+	delete(forms, i.ChannelID)
+	formsMu.Unlock()
+
+	// Remove the form's button
+	resp := &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate}
+	if i.Message != nil {
+		resp = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    i.Message.Content,
+				Components: []discordgo.MessageComponent{},
+			},
+		}
+	}
+	if err := s.InteractionRespond(i.Interaction, resp); err != nil {
+		log.Printf("[ERROR]: In discord, error responding to form (err: %v)\n", err)
+		return
+	}
+
+	runner.Receive(common.Message{
+		Route:  common.Route{Key: "discord-" + i.ChannelID, Session: "discord-" + i.ChannelID},
+		Target: i.ChannelID,
+		Caller: "discord-" + interactionUser(i).ID,
+		Data: types.FormResponse{
+			ID:     strings.TrimPrefix(data.CustomID, FORM_PREFIX),
+			Values: values,
+		},
+		Permissions: permissions,
+	})
+}
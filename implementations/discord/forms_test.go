@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderForm(t *testing.T) {
+	assert := assert.New(t)
+
+	form := &types.Form{
+		ID:    "profile",
+		Title: "Password profile",
+		Fields: []types.FormField{
+			{Name: "path", Label: "Path", Placeholder: "/personal/email", Required: true},
+			{Name: "password", Label: "Password", Secret: true},
+			{Name: "notes", Label: "Notes", Multiline: true},
+		},
+	}
+
+	// Forms are offered as a button and remembered for the channel
+	row, ok := renderFormButton("channel", form)
+	assert.True(ok)
+	assert.Equal("horus-form:profile", row.(discordgo.ActionsRow).Components[0].(discordgo.Button).CustomID)
+	assert.Equal(*form, forms["channel"])
+
+	// Fields are rendered as text inputs, and secret fields explain that they are never shown
+	rows := renderForm(*form)
+	assert.Len(rows, 3)
+
+	path := rows[0].(discordgo.ActionsRow).Components[0].(discordgo.TextInput)
+	assert.Equal("path", path.CustomID)
+	assert.Equal(discordgo.TextInputShort, path.Style)
+	assert.Equal("/personal/email", path.Placeholder)
+	assert.True(path.Required)
+
+	password := rows[1].(discordgo.ActionsRow).Components[0].(discordgo.TextInput)
+	assert.Equal(SECRET_PLACEHOLDER, password.Placeholder)
+
+	notes := rows[2].(discordgo.ActionsRow).Components[0].(discordgo.TextInput)
+	assert.Equal(discordgo.TextInputParagraph, notes.Style)
+
+	// Forms too big for a modal aren't offered
+	big := &types.Form{ID: "big", Fields: make([]types.FormField, MAX_MODAL_FIELDS+1)}
+	_, ok = renderFormButton("other", big)
+	assert.False(ok)
+	_, ok = renderFormButton("other", nil)
+	assert.False(ok)
+}
+
+func TestRenderActions(t *testing.T) {
+	assert := assert.New(t)
+
+	// A few actions are rendered as buttons
+	rows := renderActions(types.ConfirmActions)
+	assert.Len(rows, 1)
+	assert.Len(rows[0].(discordgo.ActionsRow).Components, 2)
+
+	// Too many actions for buttons are rendered as a select menu
+	actions := []types.Action{}
+	for i := 0; i < 30; i++ {
+		actions = append(actions, types.Action{Label: fmt.Sprint("Option ", i), Value: fmt.Sprint(i)})
+	}
+
+	rows = renderActions(actions)
+	assert.Len(rows, 1)
+	menu := rows[0].(discordgo.ActionsRow).Components[0].(discordgo.SelectMenu)
+	assert.Equal(ACTION_SELECT_ID, menu.CustomID)
+	assert.Len(menu.Options, MAX_SELECT_OPTIONS)
+	assert.Equal(discordgo.SelectMenuOption{Label: "Option 3", Value: "3"}, menu.Options[3])
+}
@@ -1,36 +1,20 @@
 package main
 
 import (
-	"bytes"
-	"fmt"
 	"log"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	horus "github.com/ethanbaker/horus/bot"
-	module_ambient "github.com/ethanbaker/horus/bot/module_ambient"
-	module_config "github.com/ethanbaker/horus/bot/module_config"
-	module_keepass "github.com/ethanbaker/horus/bot/module_keepass"
-	"github.com/ethanbaker/horus/outreach"
+	"github.com/ethanbaker/horus/implementations/common"
 	"github.com/ethanbaker/horus/utils/types"
-	mysql_driver "github.com/go-sql-driver/mysql"
-	"github.com/sashabaranov/go-openai"
-	"gopkg.in/yaml.v3"
 )
 
 // TODO: instead of calling stuff through a bot, call it through an API
 
-/* -------- TYPES -------- */
-
-type ChannelInfo struct {
-	Name            string
-	LastMessageTime time.Time
-}
-
 /* -------- CONSTANTS -------- */
 
 // Discord credentials
@@ -40,164 +24,113 @@ var (
 	CLIENT_ID     string = os.Getenv("DISCORD_CLIENT_ID")
 	CLIENT_SECRET string = os.Getenv("DISCORD_CLIENT_SECRET")
 	TOKEN         string = os.Getenv("DISCORD_TOKEN")
-
-	GUILD_ID            string   = os.Getenv("DISCORD_GUILD_ID")
-	BOT_OPEN_CHANNELS   []string = strings.Split(os.Getenv("DISCORD_BOT_OPEN_CHANNELS"), ",")
-	BOT_THREAD_CHANNELS []string = strings.Split(os.Getenv("DISCORD_BOT_THREAD_CHANNELS"), ",")
 )
 
-// SQL config
-var config = mysql_driver.Config{
-	User:      os.Getenv("SQL_USER"),
-	Passwd:    os.Getenv("SQL_PASSWD"),
-	Net:       os.Getenv("SQL_NET"),
-	Addr:      os.Getenv("SQL_ADDR"),
-	DBName:    os.Getenv("SQL_DBNAME"),
-	ParseTime: true,
-	Loc:       time.Local,
+// The guilds and users the bot serves. Without a config file, they are read from the
+// DISCORD_GUILD_ID, DISCORD_BOT_OPEN_CHANNELS, DISCORD_BOT_THREAD_CHANNELS and DISCORD_USER_ID
+// environment variables
+var CONFIG_PATH string = os.Getenv("DISCORD_CONFIG")
+
+// How conversations in bot channels are rotated and cleaned up
+var SESSION_POLICY = horus.SessionPolicy{
+	IdleTimeout:  6 * time.Hour,       // How long until a new conversation begins in bot channels
+	ArchiveAfter: 7 * 24 * time.Hour,  // How long until inactive conversations become read-only
+	Retention:    90 * 24 * time.Hour, // How long archived conversations are kept
 }
 
-// How long until a new conversation begins in bot channels
-const BOT_CHANNEL_OFFSET = 6 * time.Hour
+// How long tool audit records are kept
+const AUDIT_RETENTION = 30 * 24 * time.Hour
 
-/* -------- GLOBALS -------- */
+// How often the session policy and audit retention are applied
+const MAINTENANCE_INTERVAL = time.Hour
 
-// The OpenAI client
-var client *openai.Client
+/* -------- GLOBALS -------- */
 
-// The Horus bot
-var bot *horus.Bot
+// The runner connecting Discord to the Horus bot
+var runner *common.Runner
 
-// The current conversation in each bot channel
-var currentConversation = make(map[string]*ChannelInfo)
+// The Discord configuration
+var discordConfig Config
 
 /* ------------------ FUNCTIONS ------------------ */
 
 // main starts the discord bot
 func main() {
-	var err error
-
-	// Update the current conversation list
-	for _, channel := range BOT_OPEN_CHANNELS {
-		currentConversation[channel] = &ChannelInfo{
-			Name:            fmt.Sprintf("discord-%v-%v", channel, time.Now().UTC().Unix()),
-			LastMessageTime: time.Now().UTC(),
-		}
+	// Read the configuration before connecting to anything
+	path := CONFIG_PATH
+	if path != "" {
+		path = os.Getenv("BASE_PATH") + path
 	}
 
-	// Initialize the SQl
-	if err = horus.InitSQL(config.FormatDSN()); err != nil {
-		log.Fatal(err)
+	var err error
+	if discordConfig, err = loadConfig(path); err != nil {
+		log.Fatalf("[ERROR]: In discord, error loading config (err: %v)\n", err)
 	}
 
-	// Create the OpenAI client
-	client = openai.NewClient(os.Getenv("OPENAI_TOKEN"))
-
-	// Try to get a bot that we've already created
-	bot, err = horus.GetBotByName("horus-main")
+	// Setup the bot and outreach
+	bot, ch, err := common.NewBot(common.Setup{Method: types.Discord, SessionPolicy: SESSION_POLICY, AuditRetention: AUDIT_RETENTION})
 	if err != nil {
-		log.Fatalf("[ERROR]: In discord, error getting horus bot (err: %v)\n", err)
-	}
-
-	// If the bot is nil, we need to create one
-	if bot == nil {
-		bot, err = horus.NewBot("horus-main", horus.PERMISSIONS_ALL)
-		if err != nil {
-			log.Fatalf("[ERROR]: In discord, error making horus bot (err: %v)\n", err)
-		}
+		log.Fatalf("[ERROR]: In discord, error setting up horus (err: %v)\n", err)
 	}
 
-	// Setup the bot
-	module_ambient.NewModule(bot, true)
-	module_config.NewModule(bot, true)
-	module_keepass.NewModule(bot, true)
-	bot.Setup(client)
-
 	// Create a new Discord session using the provided bot token.
 	dg, err := discordgo.New("Bot " + TOKEN)
 	if err != nil {
 		log.Fatalf("[ERROR]: In discord, error creating Discord session (err: %v)\n", err)
 	}
 
+	// Open a channel to each outreach user for outreach messages. Users can reply there like in
+	// any other bot channel
+	impl := &discord{session: dg}
+	for _, user := range discordConfig.outreachUsers() {
+		dm, err := dg.UserChannelCreate(user)
+		if err != nil {
+			log.Fatalf("[ERROR]: In discord, error opening up user channel for %v (err: %v)\n", user, err)
+		}
+
+		discordConfig.addChannel(dm.ID, CHANNEL_OPEN)
+		impl.outreachChannels = append(impl.outreachChannels, dm.ID)
+	}
+	if len(impl.outreachChannels) == 0 {
+		log.Println("[WARNING]: In discord, no users receive outreach messages")
+	}
+
+	runner = common.NewRunner(bot, impl)
+
 	// Add handlers
 	dg.AddHandler(onMessageCreate)
-	dg.AddHandler(onThreadMessageCreate)
 	dg.AddHandler(onCommand)
+	dg.AddHandler(onAction)
+
+	// Add custom commands, along with a command for each of the bot's functions
+	functions = bot
+	settings = bot
+	commands, names := commandsFor(bot.Functions(), "conversation", "settings")
+	functionCommands = names
 
-	// Add custom commands
-	_, err = dg.ApplicationCommandBulkOverwrite(APP_ID, GUILD_ID, []*discordgo.ApplicationCommand{
+	commands = append([]*discordgo.ApplicationCommand{
 		{
 			Name:        "conversation",
 			Description: "Create a new conversation with Horus",
 		},
-	})
-	if err != nil {
-		log.Fatalf("[ERROR]: In discord, error creating Discord commands (err: %v)\n", err)
-	}
-
-	// Set the intents for what the bot will do
-	dg.Identify.Intents = discordgo.IntentsGuildMessages
-	dg.Identify.Intents = discordgo.IntentsDirectMessages
-
-	// Setup outreach
-	if err = outreach.Setup(config.FormatDSN()); err != nil {
-		log.Fatalf("[ERROR]: In discord, error initalizing db for outreach (err: %v)\n", err)
-	}
-
-	var ch chan string
-	if ch, err = outreach.AddChannel("discord"); err != nil {
-		log.Fatalf("[ERROR]: In discord, error adding channel to outreach (err: %v)\n", err)
-	}
-
-	// On new content, send it to the user
-	go onOutreach(dg, ch)
-
-	// Read in outreach config
-	yamlFile, err := os.ReadFile(os.Getenv("BASE_PATH") + os.Getenv("OUTREACH_CONFIG"))
-	if err != nil {
-		log.Fatalf("[ERROR]: In discord, error opening outreach config (err: %v)\n", err)
-	}
-
-	var outreachConfig types.OutreachConfig
-	if err = yaml.Unmarshal(yamlFile, &outreachConfig); err != nil {
-		log.Fatalf("[ERROR]: In discord, error opening outreach config (err: %v)\n", err)
-	}
+		settingsCommand,
+	}, commands...)
 
-	// Add the static outreaches if they match this implementation key
-	for _, msg := range outreachConfig.Static {
-		// Only add keys that contain discord
-		if msg.Key != "discord" {
-			continue
-		}
-
-		// Add the outreach
-		_, err = outreach.New("static", []types.OutreachMethod{types.Discord}, types.StaticOutreach{
-			Function: msg.Name,
-			Repeat:   msg.Repeat,
-		})
-
-		if err != nil {
-			log.Fatalf("[ERROR]: In discord, error setting up outreach (err: %v)\n", err)
+	for _, guild := range discordConfig.Guilds {
+		if _, err = dg.ApplicationCommandBulkOverwrite(APP_ID, guild.ID, commands); err != nil {
+			log.Fatalf("[ERROR]: In discord, error creating Discord commands in guild %v (err: %v)\n", guild.ID, err)
 		}
 	}
 
-	// Add the dynamic outreaches if they match this implementation key
-	for _, msg := range outreachConfig.Dynamic {
-		// Only add keys that contain discord
-		if msg.Key != "discord" {
-			continue
-		}
+	// Set the intents for what the bot will do
+	dg.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsDirectMessages
 
-		// Add the outreach
-		_, err = outreach.New("dynamic", []types.OutreachMethod{types.Discord}, types.DynamicOutreach{
-			Function:        msg.Name,
-			IntervalMinutes: time.Minute * time.Duration(msg.IntervalMinutes),
-		})
+	// Handle messages and outreach on one goroutine, since the bot is not safe for concurrent use
+	stop := make(chan struct{})
+	go runner.Run(ch, stop)
 
-		if err != nil {
-			log.Fatalf("[ERROR]: In discord, error setting up outreach (err: %v)\n", err)
-		}
-	}
+	// Maintain the bot between messages
+	runner.Maintain(MAINTENANCE_INTERVAL)
 
 	// Open a websocket connection to discord
 	err = dg.Open()
@@ -215,135 +148,69 @@ func main() {
 	<-sc
 
 	// Close the discord session
+	close(stop)
 	dg.Close()
 }
 
-// onMessageCreate function handles any message sent in a bot-specific channel
+// onMessageCreate handles any message sent in a bot channel or a conversation thread
 func onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
-	// Ignore messages not in one of the specified bot channels
-	valid := false
-	for _, id := range BOT_OPEN_CHANNELS {
-		if m.ChannelID == id {
-			valid = true
-			break
-		}
+	// Ignore all messages created by the bot itself and messages without content or files
+	if m.Author.ID == s.State.User.ID || (len(m.Content) == 0 && len(m.Attachments) == 0) {
+		return
 	}
 
-	// Ignore all messages created by the bot itself, messages in threads and messages with 0 length
-	if ch, err := s.State.Channel(m.ChannelID); err == nil || (ch != nil && ch.IsThread()) || m.Author.ID == s.State.User.ID || len(m.Content) == 0 || !valid {
+	// Ignore users that can't talk to Horus
+	permissions, ok := callerPermissions(m.Author.ID)
+	if !ok {
 		return
 	}
 
-	// Determine what conversation this message should belong to
-	var name string
-	obj := currentConversation[m.ChannelID]
-	if obj.LastMessageTime.Add(BOT_CHANNEL_OFFSET).Compare(time.Now().UTC()) >= 0 {
-		// This message is in the same conversation
-		name = obj.Name
-	} else {
-		// This message should be in a new conversation
-		name = fmt.Sprintf("discord-%v-%v", m.ChannelID, time.Now().UTC().Unix())
-		obj.Name = name
+	msg := common.Message{
+		Target:      m.ChannelID,
+		Text:        m.Content,
+		Caller:      "discord-" + m.Author.ID,
+		Permissions: permissions,
 	}
 
-	// Make sure the conversation exists
-	if !bot.IsConversation(name) {
-		if err := bot.AddConversation(name); err != nil {
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Sorry, an error occurred: >>> %v\n", err.Error()))
-			return
-		}
+	// Threads have their own conversation (created with the conversation command) and open
+	// channels use their current session. Other channels are ignored
+	if isThread(s, m.ChannelID) {
+		msg.Key = "discord-" + m.ChannelID
+	} else if discordConfig.channelMode(m.ChannelID) == CHANNEL_OPEN {
+		msg.Session = "discord-" + m.ChannelID
+	} else {
+		return
 	}
 
-	// Send the message to the horus bot
-	resp, err := bot.SendMessage(name, &types.Input{
-		Message: m.Content,
-	})
-
-	// Print any errors if they occur
+	// Download any files sent with the message
+	attachments, err := downloadAttachments(m.Attachments)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Sorry, an error occurred:\n >>> %v\n", err.Error()))
+		runner.Do(func(common.Horus) { runner.Error(m.ChannelID, err) })
 		return
-	} else if resp.Error != nil {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Sorry, an error occurred: >>> %v\n", resp.Error.Error()))
-		return
-	}
-
-	// Send files if present
-	file, ok := resp.Data.(types.FileOutput)
-	if ok {
-		r := bytes.NewReader(file.Content)
-		s.ChannelFileSend(m.ChannelID, file.Filename, r)
 	}
+	msg.Attachments = attachments
 
-	// Format the output
-	output := format(resp.Message)
-
-	// Send the output
-	s.ChannelMessageSend(m.ChannelID, output)
+	runner.Receive(msg)
 }
 
-// onThreadMessageCreate function handles any message sent in threads
-func onThreadMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
-	// Ignore all messages created by the bot itself, messages not in threads and messages with 0 length
-	if ch, err := s.State.Channel(m.ChannelID); err == nil || (ch != nil && !ch.IsThread()) || m.Author.ID == s.State.User.ID || len(m.Content) == 0 {
-		return
-	}
-
-	// Determine what conversation this message should belong to
-	name := fmt.Sprintf("discord-" + m.ChannelID)
-
-	// Make sure the conversation exists
-	if !bot.IsConversation(name) {
+// onCommand handles application commands
+func onCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Only handle application commands
+	if i.Type != discordgo.InteractionApplicationCommand {
 		return
 	}
 
-	// Send the message to the horus bot
-	resp, err := bot.SendMessage(name, &types.Input{
-		Message: m.Content,
-	})
-
-	// Print any errors if they occur
-	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Sorry, an error occurred:\n >>> %v\n", err.Error()))
-		return
-	} else if resp.Error != nil {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Sorry, an error occurred: >>> %v\n", resp.Error.Error()))
+	permissions, ok := authorize(s, i)
+	if !ok {
 		return
 	}
 
-	// Send files if present
-	file, ok := resp.Data.(types.FileOutput)
-	if ok {
-		r := bytes.NewReader(file.Content)
-		s.ChannelFileSend(m.ChannelID, file.Filename, r)
-	}
-
-	// Format the output
-	output := format(resp.Message)
-
-	// Send the output
-	s.ChannelMessageSend(m.ChannelID, output)
-}
-
-func onCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	// Find the name of the command
 	data := i.ApplicationCommandData()
 	switch data.Name {
 	case "conversation":
-		// Ignore commands not in one of the specified bot channels
-		valid := false
-		for _, id := range BOT_THREAD_CHANNELS {
-			if i.ChannelID == id {
-				valid = true
-				break
-			}
-		}
-		if !valid {
-			return
-		}
-
-		// Make sure the channel is not a thread
-		if ch, err := s.State.Channel(i.ChannelID); err == nil || (ch != nil && ch.IsThread()) {
+		// Ignore commands outside of the thread channels
+		if discordConfig.channelMode(i.ChannelID) != CHANNEL_THREAD || isThread(s, i.ChannelID) {
 			return
 		}
 
@@ -358,14 +225,14 @@ func onCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
 			},
 		)
 		if err != nil {
-			s.ChannelMessageSend(i.ChannelID, fmt.Sprintf("Sorry, an error occurred: >>> %v\n", err.Error()))
+			runner.Do(func(common.Horus) { runner.Error(i.ChannelID, err) })
 			return
 		}
 
 		// Get the response ID
 		m, err := s.InteractionResponse(i.Interaction)
 		if err != nil {
-			s.ChannelMessageSend(i.ChannelID, fmt.Sprintf("Sorry, an error occurred: >>> %v\n", err.Error()))
+			runner.Do(func(common.Horus) { runner.Error(i.ChannelID, err) })
 			return
 		}
 
@@ -375,73 +242,73 @@ func onCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
 			Invitable: false,
 		})
 		if err != nil {
-			s.ChannelMessageSend(i.ChannelID, fmt.Sprintf("Sorry, an error occurred:\n >>> %v\n", err.Error()))
+			runner.Do(func(common.Horus) { runner.Error(i.ChannelID, err) })
 			return
 		}
 
-		// Register the thread to the bot
-		if err := bot.AddConversation("discord-" + thread.ID); err != nil {
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Sorry, an error occurred: >>> %v\n", err.Error()))
+		// Register the thread to the bot with the settings of its channel
+		runner.Do(func(bot common.Horus) {
+			if err := bot.AddConversation("discord-" + thread.ID); err != nil {
+				runner.Error(thread.ID, err)
+			}
+			if err := copySettings(i.ChannelID, thread.ID); err != nil {
+				runner.Error(thread.ID, err)
+			}
+		})
+
+	case "settings":
+		onSettingsCommand(s, i)
+
+	default:
+		// Run the function of a generated command
+		if function, ok := functionCommands[data.Name]; ok {
+			onFunctionCommand(s, i, function, permissions)
 		}
 	}
 }
 
-// Handle messages that should be sent to a user
-func onOutreach(s *discordgo.Session, ch chan string) {
-	// Open a channel to the user
-	channel, err := s.UserChannelCreate(os.Getenv("DISCORD_USER_ID"))
+/* ---- HELPERS ---- */
+
+// isThread returns whether a channel is a thread
+func isThread(s *discordgo.Session, channelID string) bool {
+	ch, err := s.State.Channel(channelID)
 	if err != nil {
-		log.Fatalf("[ERROR]: In discord, error opening up user channel (err: %v)\n", err)
+		ch, err = s.Channel(channelID)
 	}
+	return err == nil && ch.IsThread()
+}
 
-	BOT_OPEN_CHANNELS = append(BOT_OPEN_CHANNELS, channel.ID)
-
-	for {
-		content := <-ch
-
-		// Send the user a message
-		_, err = s.ChannelMessageSend(channel.ID, format(content))
-		if err != nil {
-			log.Printf("[ERROR]: In discord, error sending user message (err: %v)\n", err)
-			continue
-		}
-
-		// Always create a new conversation when an outreach message appears
-		currentConversation[channel.ID] = &ChannelInfo{
-			Name:            fmt.Sprintf("discord-%v-%v", channel.ID, time.Now().UTC().Unix()),
-			LastMessageTime: time.Now().UTC(),
-		}
-		name := currentConversation[channel.ID].Name
+// interactionUser returns the user that created an interaction. Interactions in guilds are
+// created by members
+func interactionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil {
+		return i.Member.User
+	}
+	return i.User
+}
 
-		// Make sure the conversation exists
-		if !bot.IsConversation(name) {
-			if err := bot.AddConversation(name); err != nil {
-				log.Printf("[ERROR]: In discord, error sending creating conversation (err: %v)\n", err)
-				continue
-			}
-		}
+// callerPermissions returns the permissions of a user, and false if the user can't talk to Horus
+func callerPermissions(userID string) (byte, bool) {
+	permissions := discordConfig.userPermissions(userID)
+	return permissions, permissions&horus.PERMISSIONS_GPT != 0
+}
 
-		// Add the outreach message to the conversation
-		if err := bot.AddMessage(name, openai.ChatMessageRoleAssistant, "", content); err != nil {
-			log.Printf("[ERROR]: In discord, error adding message to conversation (err: %v)\n", err)
-			continue
-		}
+// authorize returns the permissions of the user that created an interaction. Users that can't
+// talk to Horus are told so and false is returned
+func authorize(s *discordgo.Session, i *discordgo.InteractionCreate) (byte, bool) {
+	permissions, ok := callerPermissions(interactionUser(i).ID)
+	if !ok {
+		respondEphemeral(s, i, "Sorry, you don't have permission to use Horus.")
 	}
+	return permissions, ok
 }
 
-// Format an output string to match discord messages
-func format(output string) string {
-	output = strings.ReplaceAll(output, "<STRONG>", "**")
-	output = strings.ReplaceAll(output, "<EM>", "*")
-	output = strings.ReplaceAll(output, "<EM>", "*")
-	output = strings.ReplaceAll(output, "<INS>", "__")
-	output = strings.ReplaceAll(output, "<DEL>", "~~")
-	output = strings.ReplaceAll(output, "<DEL>", "~~")
-	output = strings.ReplaceAll(output, "<BLOCKQUOTE_IN>", "> ")
-	output = strings.ReplaceAll(output, "<BLOCKQUOTE>", ">>> ")
-	output = strings.ReplaceAll(output, "<CODE_IN>", "`")
-	output = strings.ReplaceAll(output, "<CODE>", "```")
-	output = strings.ReplaceAll(output, "<SPOILER>", "||")
-
-	return output
+// contains returns whether a list contains a value
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
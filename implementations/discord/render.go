@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/ethanbaker/horus/implementations/common"
+	"github.com/ethanbaker/horus/utils/format"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- CONSTANTS -------- */
+
+// Custom ID prefix for buttons created from output actions
+const ACTION_PREFIX = "horus-action:"
+
+// Custom ID of select menus created from output actions
+const ACTION_SELECT_ID = "horus-action-select"
+
+// The name of the file long replies are attached as
+const LONG_REPLY_FILENAME = "reply.md"
+
+// Discord message limits
+const (
+	MAX_EMBEDS            = 10
+	MAX_EMBED_TITLE       = 256
+	MAX_EMBED_DESCRIPTION = 4096
+	MAX_EMBED_FIELDS      = 25
+	MAX_FIELD_NAME        = 256
+	MAX_FIELD_VALUE       = 1024
+	MAX_EMBED_TOTAL       = 6000 // The limit of every embed in a message together
+	MAX_BUTTONS_PER_ROW   = 5
+	MAX_ACTION_ROWS       = 5
+	MAX_CUSTOM_ID         = 100
+	MAX_BUTTON_LABEL      = 80
+	MAX_SELECT_OPTIONS    = 25
+	MAX_SELECT_VALUE      = 100
+	MAX_MESSAGE_LENGTH    = 2000 // Counted in characters, so counting bytes is safe
+	MAX_CHUNKS            = 5    // Not a Discord limit: longer replies are attached instead
+)
+
+/* -------- IMPLEMENTATION -------- */
+
+// discord sends outputs to Discord channels. Targets are channel IDs
+type discord struct {
+	session          *discordgo.Session
+	outreachChannels []string // The DM channels of the users outreach messages are sent to
+}
+
+// Name names the implementation
+func (d *discord) Name() string {
+	return "discord"
+}
+
+// Send sends an output to a channel
+func (d *discord) Send(target any, output *types.Output) error {
+	return sendOutput(d.session, target.(string), output)
+}
+
+// SendOutreach sends an outreach message to the first outreach channel. Replies continue in the
+// channel's session. Runners use BroadcastOutreach instead to reach every outreach user
+func (d *discord) SendOutreach(output *types.Output) (common.Route, error) {
+	if len(d.outreachChannels) == 0 {
+		return common.Route{}, fmt.Errorf("no users receive outreach messages")
+	}
+
+	channel := d.outreachChannels[0]
+	return common.Route{Session: "discord-" + channel}, sendOutput(d.session, channel, output)
+}
+
+// BroadcastOutreach sends an outreach message to every outreach channel. Replies continue in
+// each channel's session
+func (d *discord) BroadcastOutreach(output *types.Output) ([]common.Route, error) {
+	routes := []common.Route{}
+	errs := []error{}
+	for _, channel := range d.outreachChannels {
+		if err := sendOutput(d.session, channel, output); err != nil {
+			errs = append(errs, fmt.Errorf("channel %v: %w", channel, err))
+			continue
+		}
+		routes = append(routes, common.Route{Session: "discord-" + channel})
+	}
+
+	return routes, errors.Join(errs...)
+}
+
+/* -------- FUNCTIONS -------- */
+
+// sendOutput sends an output to a channel. Every chunk but the last is sent on its own, and the
+// last is sent with the rest of the output
+func sendOutput(s *discordgo.Session, channelID string, resp *types.Output) error {
+	chunks, msg := renderOutput(channelID, resp)
+
+	if len(chunks) > 0 {
+		for _, chunk := range chunks[:len(chunks)-1] {
+			if _, err := s.ChannelMessageSend(channelID, chunk); err != nil {
+				return err
+			}
+		}
+		msg.Content = chunks[len(chunks)-1]
+	}
+
+	// Don't send empty messages
+	if msg.Content == "" && len(msg.Embeds) == 0 && len(msg.Files) == 0 {
+		return nil
+	}
+
+	_, err := s.ChannelMessageSendComplex(channelID, msg)
+	return err
+}
+
+// renderOutput renders an output as the chunks of its content and a message holding everything
+// else. Blocks are rendered as embeds, attachments are sent as files and actions are rendered as
+// buttons. Long content is split into chunks, and content too long for that is attached as a
+// markdown file
+func renderOutput(channelID string, resp *types.Output) ([]string, *discordgo.MessageSend) {
+	content := format.FormatDiscord(resp.Message)
+	msg := &discordgo.MessageSend{}
+
+	// Render blocks as embeds, falling back to text once the embed limits are reached
+	total := 0
+	for _, b := range resp.Blocks {
+		// Skip blocks without content, since Discord refuses empty embeds
+		text := format.RenderBlock(b)
+		if text == "" {
+			continue
+		}
+
+		embed := renderEmbed(b)
+		if size := embedLength(embed); len(msg.Embeds) < MAX_EMBEDS && total+size <= MAX_EMBED_TOTAL {
+			msg.Embeds = append(msg.Embeds, embed)
+			total += size
+			continue
+		}
+
+		if content != "" {
+			content += "\n\n"
+		}
+		content += format.FormatDiscord(text)
+	}
+
+	// Split the content into chunks, attaching it instead if there are too many
+	chunks := splitMessage(content, MAX_MESSAGE_LENGTH)
+	if len(chunks) > MAX_CHUNKS {
+		chunks = []string{"The reply is too long to send as messages, so it is attached."}
+		msg.Files = append(msg.Files, &discordgo.File{
+			Name:        LONG_REPLY_FILENAME,
+			ContentType: "text/markdown",
+			Reader:      strings.NewReader(content),
+		})
+	}
+
+	// Add files
+	for _, file := range resp.Files() {
+		msg.Files = append(msg.Files, &discordgo.File{
+			Name:        file.Filename,
+			ContentType: file.ContentType,
+			Reader:      bytes.NewReader(file.Content),
+		})
+	}
+
+	// Render actions as rows of buttons, and offer any form as a button that opens it
+	msg.Components = renderActions(resp.Actions)
+	if len(msg.Components) < MAX_ACTION_ROWS {
+		if row, ok := renderFormButton(channelID, resp.Form); ok {
+			msg.Components = append(msg.Components, row)
+		}
+	}
+
+	return chunks, msg
+}
+
+// renderEmbed renders a block as a Discord embed. Text is cut to fit Discord's embed limits
+func renderEmbed(b types.Block) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title: truncate(format.FormatDiscord(b.Title), "", MAX_EMBED_TITLE),
+	}
+
+	switch b.Type {
+	case types.TextBlock:
+		embed.Description = truncate(format.FormatDiscord(b.Text), "", MAX_EMBED_DESCRIPTION)
+
+	case types.CardBlock:
+		for _, f := range b.Fields {
+			if len(embed.Fields) == MAX_EMBED_FIELDS {
+				break
+			}
+
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+				Name:   truncate(format.FormatDiscord(f.Name), "-", MAX_FIELD_NAME),
+				Value:  truncate(format.FormatDiscord(f.Value), "-", MAX_FIELD_VALUE),
+				Inline: f.Inline,
+			})
+		}
+
+	case types.ListBlock:
+		// Render the list without its title since the embed has one
+		b.Title = ""
+		embed.Description = truncate(format.FormatDiscord(format.RenderBlock(b)), "", MAX_EMBED_DESCRIPTION)
+
+	case types.TableBlock:
+		// Cut the table inside its code block so the block is still closed
+		if len(b.Columns) > 0 || len(b.Rows) > 0 {
+			table := format.RenderTable(b.Columns, b.Rows)
+			embed.Description = "```\n" + truncate(table, "", MAX_EMBED_DESCRIPTION-len("```\n\n```")) + "\n```"
+		}
+	}
+
+	return embed
+}
+
+// embedLength counts the characters of an embed toward the message's total embed limit
+func embedLength(embed *discordgo.MessageEmbed) int {
+	length := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	for _, f := range embed.Fields {
+		length += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+	return length
+}
+
+// renderActions renders actions as rows of buttons, or as a select menu if there are too many
+// actions for buttons
+func renderActions(actions []types.Action) []discordgo.MessageComponent {
+	rows := []discordgo.MessageComponent{}
+
+	if len(actions) > MAX_BUTTONS_PER_ROW*MAX_ACTION_ROWS {
+		menu := discordgo.SelectMenu{
+			CustomID:    ACTION_SELECT_ID,
+			Placeholder: "Choose a reply",
+		}
+		values := map[string]bool{}
+		for _, a := range actions {
+			if len(menu.Options) == MAX_SELECT_OPTIONS {
+				break
+			}
+
+			// Values have a maximum length and must be unique
+			value := cut(a.Value, MAX_SELECT_VALUE)
+			if values[value] {
+				continue
+			}
+			values[value] = true
+
+			menu.Options = append(menu.Options, discordgo.SelectMenuOption{
+				Label: truncate(a.Label, value, MAX_SELECT_VALUE),
+				Value: value,
+			})
+		}
+
+		return append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{menu}})
+	}
+
+	var row discordgo.ActionsRow
+	ids := map[string]bool{}
+	for _, a := range actions {
+		// Custom IDs have a maximum length and must be unique. Actions with the same value send
+		// the same reply, so only the first is shown
+		id := cut(ACTION_PREFIX+a.Value, MAX_CUSTOM_ID)
+		if ids[id] {
+			continue
+		}
+		ids[id] = true
+
+		if len(row.Components) == MAX_BUTTONS_PER_ROW {
+			rows = append(rows, row)
+			row = discordgo.ActionsRow{}
+		}
+		if len(rows) == MAX_ACTION_ROWS {
+			break
+		}
+
+		style := discordgo.SecondaryButton
+		switch a.Style {
+		case types.PrimaryAction:
+			style = discordgo.PrimaryButton
+		case types.DangerAction:
+			style = discordgo.DangerButton
+		}
+
+		row.Components = append(row.Components, discordgo.Button{
+			Label:    truncate(a.Label, a.Value, MAX_BUTTON_LABEL),
+			Style:    style,
+			CustomID: id,
+		})
+	}
+
+	if len(row.Components) > 0 && len(rows) < MAX_ACTION_ROWS {
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// onAction handles buttons and select menus created from output actions by sending the chosen
+// action's value to the channel's conversation. Buttons and modals of forms are handled too
+func onAction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent && i.Type != discordgo.InteractionModalSubmit {
+		return
+	}
+
+	permissions, ok := authorize(s, i)
+	if !ok {
+		return
+	}
+
+	if i.Type == discordgo.InteractionModalSubmit {
+		onFormSubmit(s, i, permissions)
+		return
+	}
+
+	// Find the value of the action
+	data := i.MessageComponentData()
+	var value string
+	switch {
+	case data.CustomID == ACTION_SELECT_ID && len(data.Values) > 0:
+		value = data.Values[0]
+	case strings.HasPrefix(data.CustomID, ACTION_PREFIX):
+		value = strings.TrimPrefix(data.CustomID, ACTION_PREFIX)
+	case strings.HasPrefix(data.CustomID, FORM_PREFIX):
+		onFormOpen(s, i, strings.TrimPrefix(data.CustomID, FORM_PREFIX))
+		return
+	default:
+		return
+	}
+
+	// Remove the buttons so the action can only be chosen once
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    i.Message.Content,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+	if err != nil {
+		log.Printf("[ERROR]: In discord, error responding to action (err: %v)\n", err)
+		return
+	}
+
+	// Threads have their own conversation, other channels use their current session
+	runner.Receive(common.Message{
+		Route:       common.Route{Key: "discord-" + i.ChannelID, Session: "discord-" + i.ChannelID},
+		Target:      i.ChannelID,
+		Text:        value,
+		Caller:      "discord-" + interactionUser(i).ID,
+		Permissions: permissions,
+	})
+}
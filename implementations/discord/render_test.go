@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// The path of the golden file holding the expected rendering of each output
+const EXPECTED_PATH = "./testing/expected.json"
+
+// Rewrite the golden file with the current output instead of comparing against it
+var update = flag.Bool("update", false, "update the golden file")
+
+// rendered is the part of a rendered output that is compared against the golden file
+type rendered struct {
+	Chunks     []string                     `json:"chunks"`
+	Embeds     []*discordgo.MessageEmbed    `json:"embeds,omitempty"`
+	Components []discordgo.MessageComponent `json:"components,omitempty"`
+	Files      []string                     `json:"files,omitempty"`
+}
+
+// Compile a list of all outputs rendered in the tests
+var OUTPUTS = map[string]*types.Output{
+	"Message only": {Message: "Hello, <STRONG>world<STRONG>!"},
+	"Blocks": {Message: "Entry found", Blocks: []types.Block{
+		{Type: types.TextBlock, Title: "Note", Text: "Some <EM>text<EM>"},
+		{Type: types.CardBlock, Title: "github", Fields: []types.Field{
+			{Name: "Username", Value: "horus", Inline: true},
+			{Name: "URL", Value: "https://github.com"},
+		}},
+		{Type: types.ListBlock, Title: "Steps", Items: []string{"First", "Second"}, Ordered: true},
+	}},
+	"Table with uneven rows": {Blocks: []types.Block{
+		{Type: types.TableBlock, Title: "Values", Columns: []string{"Name", "Value"}, Rows: [][]string{
+			{"short"},
+			{"a", "b", "extra cell"},
+			{"éèê", "日本"},
+		}},
+	}},
+	"Empty blocks": {Message: "Nothing to show", Blocks: []types.Block{
+		{Type: types.TextBlock},
+		{Type: types.CardBlock},
+		{Type: types.TableBlock, Title: "Empty table"},
+	}},
+	"Too many embeds": {Blocks: []types.Block{
+		{Type: types.TextBlock, Text: "1"}, {Type: types.TextBlock, Text: "2"}, {Type: types.TextBlock, Text: "3"},
+		{Type: types.TextBlock, Text: "4"}, {Type: types.TextBlock, Text: "5"}, {Type: types.TextBlock, Text: "6"},
+		{Type: types.TextBlock, Text: "7"}, {Type: types.TextBlock, Text: "8"}, {Type: types.TextBlock, Text: "9"},
+		{Type: types.TextBlock, Text: "10"}, {Type: types.ListBlock, Title: "Overflow", Items: []string{"11"}},
+	}},
+	"Actions": {Message: "Should I go ahead?", Actions: types.ConfirmActions},
+	"Form": {
+		Message: "What should the entry be called?",
+		Actions: []types.Action{{Label: "Cancel", Value: "cancel", Style: types.DangerAction}},
+		Form: &types.Form{ID: "entry", Title: "New entry", Fields: []types.FormField{
+			{Name: "title", Label: "Title", Required: true},
+			{Name: "password", Label: "Password", Secret: true},
+		}},
+	},
+	"Files": {
+		Message:     "Here is the report",
+		Attachments: []types.FileOutput{{Filename: "report.csv", ContentType: "text/csv", Content: []byte("a,b")}},
+	},
+	"Duplicate actions": {Message: "Pick one", Actions: []types.Action{
+		{Label: "Yes", Value: "yes"},
+		{Label: "Yes again", Value: "yes"},
+		{Label: strings.Repeat("long label ", 10), Value: "long"},
+		{Value: "no label"},
+		{Label: "Long value", Value: strings.Repeat("v", 150)},
+		{Label: "Long value again", Value: strings.Repeat("v", 160)},
+	}},
+	"Many actions":      {Message: "Pick one", Actions: manyActions(30)},
+	"Long message":      {Message: strings.Repeat("word ", MAX_MESSAGE_LENGTH/5+10)},
+	"Very long message": {Message: strings.Repeat("word ", MAX_CHUNKS*MAX_MESSAGE_LENGTH/5+10)},
+}
+
+// manyActions creates a list of actions where every other value repeats
+func manyActions(n int) []types.Action {
+	actions := []types.Action{}
+	for i := 0; i < n; i++ {
+		actions = append(actions, types.Action{Label: fmt.Sprintf("Action %v", i), Value: fmt.Sprintf("value %v", i-i%2)})
+	}
+	return actions
+}
+
+func TestRenderOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	expected := map[string]json.RawMessage{}
+	if !*update {
+		data, err := os.ReadFile(EXPECTED_PATH)
+		assert.Nil(err)
+		assert.Nil(json.Unmarshal(data, &expected))
+	}
+
+	got := map[string]json.RawMessage{}
+	for name, output := range OUTPUTS {
+		chunks, msg := renderOutput("channel", output)
+
+		// Long chunks are summarized so the golden file stays readable
+		r := rendered{Chunks: []string{}, Embeds: msg.Embeds, Components: msg.Components}
+		for _, chunk := range chunks {
+			if len(chunk) > 100 {
+				chunk = chunk[:100] + "..."
+			}
+			r.Chunks = append(r.Chunks, chunk)
+		}
+		for _, f := range msg.Files {
+			r.Files = append(r.Files, f.Name+" ("+f.ContentType+")")
+		}
+
+		var sb strings.Builder
+		encoder := json.NewEncoder(&sb)
+		encoder.SetEscapeHTML(false)
+		assert.Nil(encoder.Encode(r))
+		got[name] = json.RawMessage(strings.TrimSpace(sb.String()))
+
+		if !*update {
+			assert.JSONEq(string(expected[name]), string(got[name]), name)
+		}
+	}
+
+	if *update {
+		var sb strings.Builder
+		encoder := json.NewEncoder(&sb)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "    ")
+		assert.Nil(encoder.Encode(got))
+		assert.Nil(os.WriteFile(EXPECTED_PATH, []byte(sb.String()), 0644))
+	}
+}
+
+func TestRenderEmbedLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	fields := []types.Field{}
+	for i := 0; i < 30; i++ {
+		fields = append(fields, types.Field{Name: fmt.Sprintf("Field %v", i), Value: strings.Repeat("v", 1100)})
+	}
+	rows := [][]string{}
+	for i := 0; i < 1000; i++ {
+		rows = append(rows, []string{fmt.Sprintf("row %v", i)})
+	}
+
+	// Titles, descriptions and fields are cut to Discord's limits
+	embed := renderEmbed(types.Block{Type: types.TextBlock, Title: strings.Repeat("t", 300), Text: strings.Repeat("é", 5000)})
+	assert.Equal(MAX_EMBED_TITLE, utf8.RuneCountInString(embed.Title))
+	assert.Equal(MAX_EMBED_DESCRIPTION, utf8.RuneCountInString(embed.Description))
+	assert.True(strings.HasSuffix(embed.Description, "..."))
+
+	embed = renderEmbed(types.Block{Type: types.CardBlock, Title: "Fields", Fields: fields})
+	assert.Len(embed.Fields, MAX_EMBED_FIELDS)
+	assert.Equal(MAX_FIELD_VALUE, utf8.RuneCountInString(embed.Fields[0].Value))
+
+	// Tables are cut inside their code block
+	embed = renderEmbed(types.Block{Type: types.TableBlock, Columns: []string{"Value"}, Rows: rows})
+	assert.LessOrEqual(utf8.RuneCountInString(embed.Description), MAX_EMBED_DESCRIPTION)
+	assert.True(strings.HasPrefix(embed.Description, "```\n"))
+	assert.True(strings.HasSuffix(embed.Description, "...\n```"))
+
+	// Blocks past the total embed limit are sent as text instead
+	blocks := []types.Block{}
+	for _, text := range []string{"a", "b", "c"} {
+		blocks = append(blocks, types.Block{Type: types.TextBlock, Title: text, Text: strings.Repeat(text, 2500)})
+	}
+	blocks = append(blocks, types.Block{Type: types.TextBlock, Title: "small", Text: "small"})
+
+	chunks, msg := renderOutput("channel", &types.Output{Message: "Reports", Blocks: blocks})
+	assert.Len(msg.Embeds, 3)
+	assert.Equal("a", msg.Embeds[0].Title)
+	assert.Equal("b", msg.Embeds[1].Title)
+	assert.Equal("small", msg.Embeds[2].Title)
+	assert.Equal("Reports", chunks[0])
+	assert.Contains(strings.Join(chunks, ""), strings.Repeat("c", 100))
+
+	total := 0
+	for _, e := range msg.Embeds {
+		total += embedLength(e)
+	}
+	assert.LessOrEqual(total, MAX_EMBED_TOTAL)
+}
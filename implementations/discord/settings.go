@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	horus "github.com/ethanbaker/horus/bot"
+	"github.com/ethanbaker/horus/implementations/common"
+)
+
+/* -------- TYPES -------- */
+
+// Settings is the part of a Horus bot used to configure channels
+type Settings interface {
+	Modules() []string
+	GetSessionSettings(key string) (horus.SessionSettings, error)
+	SetSessionSettings(key string, settings horus.SessionSettings) error
+}
+
+/* -------- GLOBALS -------- */
+
+// The Horus bot's channel settings. They must only be used through the runner since the bot is
+// not safe for concurrent use
+var settings Settings
+
+// The command used to configure a channel
+var settingsCommand = &discordgo.ApplicationCommand{
+	Name:                     "settings",
+	Description:              "Show or change how Horus behaves in this channel",
+	DefaultMemberPermissions: &manageChannels,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "rotation",
+			Description: "Minutes of silence before a new conversation starts (0 never starts one)",
+			MinValue:    &zero,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "modules",
+			Description: "A comma separated list of the modules Horus can use here, or 'all'",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "reset",
+			Description: "Go back to the default settings",
+		},
+	},
+}
+
+var (
+	manageChannels int64 = discordgo.PermissionManageChannels
+	zero           float64
+)
+
+/* -------- FUNCTIONS -------- */
+
+// onSettingsCommand shows or changes the settings of a bot channel or conversation thread. The
+// settings of thread channels are given to the threads created in them. The settings are only
+// shown to the user that ran the command
+func onSettingsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if discordConfig.channelMode(i.ChannelID) == "" && !isThread(s, i.ChannelID) {
+		respondEphemeral(s, i, "Horus can only be configured in bot channels and conversation threads.")
+		return
+	}
+
+	// Acknowledge the command, since it waits for the runner
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+	if err != nil {
+		log.Printf("[ERROR]: In discord, error responding to command (err: %v)\n", err)
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	key := "discord-" + i.ChannelID
+
+	runner.Do(func(common.Horus) {
+		content, err := updateSettings(key, options)
+		if err != nil {
+			content = "Error: " + err.Error()
+		}
+
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+			log.Printf("[ERROR]: In discord, error editing command response (err: %v)\n", err)
+		}
+	})
+}
+
+// updateSettings applies the options of a settings command to a channel and describes the
+// channel's settings. It must be called from the runner
+func updateSettings(key string, options []*discordgo.ApplicationCommandInteractionDataOption) (string, error) {
+	current, err := settings.GetSessionSettings(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(options) > 0 {
+		current = applySettings(current, options)
+		if err := settings.SetSessionSettings(key, current); err != nil {
+			return "", err
+		}
+	}
+
+	return describeSettings(current, settings.Modules()), nil
+}
+
+// applySettings changes settings with the options of a settings command
+func applySettings(current horus.SessionSettings, options []*discordgo.ApplicationCommandInteractionDataOption) horus.SessionSettings {
+	// Reset the settings before applying other options
+	for _, opt := range options {
+		if opt.Name == "reset" && opt.BoolValue() {
+			current = horus.SessionSettings{}
+		}
+	}
+
+	for _, opt := range options {
+		switch opt.Name {
+		case "rotation":
+			rotation := time.Duration(opt.IntValue()) * time.Minute
+			current.IdleTimeout = &rotation
+
+		case "modules":
+			current.Modules = nil
+			if value := strings.TrimSpace(opt.StringValue()); !strings.EqualFold(value, "all") {
+				current.Modules = strings.Split(value, ",")
+			}
+		}
+	}
+
+	return current
+}
+
+// describeSettings describes a channel's settings
+func describeSettings(current horus.SessionSettings, modules []string) string {
+	rotation := "default"
+	if current.IdleTimeout != nil && *current.IdleTimeout == 0 {
+		rotation = "never"
+	} else if current.IdleTimeout != nil {
+		rotation = fmt.Sprintf("after %v of silence", *current.IdleTimeout)
+	}
+
+	allowed := "all"
+	if len(current.Modules) > 0 {
+		allowed = strings.Join(current.Modules, ", ")
+	}
+
+	return fmt.Sprintf(
+		"**New conversations:** %v\n**Modules:** %v\n**Available modules:** %v",
+		rotation, allowed, strings.Join(modules, ", "),
+	)
+}
+
+// copySettings gives a new conversation thread the settings of the channel it was created in.
+// It must be called from the runner
+func copySettings(channelID string, threadID string) error {
+	current, err := settings.GetSessionSettings("discord-" + channelID)
+	if err != nil || (current.IdleTimeout == nil && len(current.Modules) == 0) {
+		return err
+	}
+
+	return settings.SetSessionSettings("discord-"+threadID, current)
+}
+
+/* ---- HELPERS ---- */
+
+// respondEphemeral responds to an interaction with a message only its user can see
+func respondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		log.Printf("[ERROR]: In discord, error responding to interaction (err: %v)\n", err)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	horus "github.com/ethanbaker/horus/bot"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplySettings(t *testing.T) {
+	assert := assert.New(t)
+
+	option := func(name string, t discordgo.ApplicationCommandOptionType, value any) *discordgo.ApplicationCommandInteractionDataOption {
+		return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: t, Value: value}
+	}
+
+	// Rotation is given in minutes and modules as a comma separated list
+	current := applySettings(horus.SessionSettings{}, []*discordgo.ApplicationCommandInteractionDataOption{
+		option("rotation", discordgo.ApplicationCommandOptionInteger, float64(90)),
+		option("modules", discordgo.ApplicationCommandOptionString, "ambient,config"),
+	})
+	assert.Equal(90*time.Minute, *current.IdleTimeout)
+	assert.Equal([]string{"ambient", "config"}, current.Modules)
+	assert.Equal("**New conversations:** after 1h30m0s of silence\n**Modules:** ambient, config\n**Available modules:** ambient, config, keepass",
+		describeSettings(current, []string{"ambient", "config", "keepass"}))
+
+	// Every module can be allowed again, and other settings are kept
+	current = applySettings(current, []*discordgo.ApplicationCommandInteractionDataOption{
+		option("modules", discordgo.ApplicationCommandOptionString, "All"),
+	})
+	assert.Nil(current.Modules)
+	assert.Equal(90*time.Minute, *current.IdleTimeout)
+
+	// A rotation of 0 never starts a new conversation
+	current = applySettings(current, []*discordgo.ApplicationCommandInteractionDataOption{
+		option("rotation", discordgo.ApplicationCommandOptionInteger, float64(0)),
+	})
+	assert.Equal("**New conversations:** never\n**Modules:** all\n**Available modules:** keepass", describeSettings(current, []string{"keepass"}))
+
+	// Resetting goes back to the defaults before applying other options
+	current = applySettings(current, []*discordgo.ApplicationCommandInteractionDataOption{
+		option("modules", discordgo.ApplicationCommandOptionString, "keepass"),
+		option("reset", discordgo.ApplicationCommandOptionBoolean, true),
+	})
+	assert.Nil(current.IdleTimeout)
+	assert.Equal([]string{"keepass"}, current.Modules)
+}
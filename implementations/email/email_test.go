@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap/backend/memory"
+	imap_client "github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/server"
+	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-smtp"
+	"github.com/ethanbaker/horus/implementations/common"
+	"github.com/ethanbaker/horus/implementations/common/commontest"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+/* ---- MAIL SERVERS ---- */
+
+// fakeSMTP is a local SMTP server that records the emails it receives
+type fakeSMTP struct {
+	mu     sync.Mutex
+	emails []sentEmail
+}
+
+// sentEmail is an email received by the fake SMTP server
+type sentEmail struct {
+	To   []string
+	Data string
+}
+
+func (f *fakeSMTP) Login(state *smtp.ConnectionState, username string, password string) (smtp.Session, error) {
+	return &fakeSession{server: f}, nil
+}
+
+func (f *fakeSMTP) AnonymousLogin(state *smtp.ConnectionState) (smtp.Session, error) {
+	return &fakeSession{server: f}, nil
+}
+
+// sent returns the emails received by the server
+func (f *fakeSMTP) sent() []sentEmail {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sentEmail{}, f.emails...)
+}
+
+// fakeSession is a session with the fake SMTP server
+type fakeSession struct {
+	server *fakeSMTP
+	to     []string
+}
+
+func (s *fakeSession) Reset()                                        {}
+func (s *fakeSession) Logout() error                                 { return nil }
+func (s *fakeSession) Mail(from string, opts smtp.MailOptions) error { return nil }
+
+func (s *fakeSession) Rcpt(to string) error {
+	s.to = append(s.to, to)
+	return nil
+}
+
+func (s *fakeSession) Data(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	s.server.mu.Lock()
+	defer s.server.mu.Unlock()
+	s.server.emails = append(s.server.emails, sentEmail{To: s.to, Data: string(data)})
+	return nil
+}
+
+// listen listens on a random local port
+func listen(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+/* ---- SETUP ---- */
+
+// setup starts local IMAP and SMTP servers and installs a fake Horus bot. It returns a function
+// that delivers a raw email to the mailbox
+func setup(t *testing.T) (*fakeSMTP, *commontest.Horus, func(raw string)) {
+	// Start the IMAP server
+	imapServer := server.New(memory.New())
+	imapServer.AllowInsecureAuth = true
+	imapListener := listen(t)
+	go imapServer.Serve(imapListener)
+	t.Cleanup(func() { imapServer.Close() })
+
+	// Start the SMTP server
+	fake := &fakeSMTP{}
+	smtpServer := smtp.NewServer(fake)
+	smtpServer.Domain = "localhost"
+	smtpServer.AllowInsecureAuth = true
+	smtpListener := listen(t)
+	go smtpServer.Serve(smtpListener)
+	t.Cleanup(func() { smtpServer.Close() })
+
+	h := commontest.New()
+	h.Replies["file"] = &types.Output{Message: "Attached", Attachments: []types.FileOutput{{Filename: "notes.txt", ContentType: "text/plain", Content: []byte("hello")}}}
+	h.Errors["fail"] = fmt.Errorf("failed <here>")
+	bot = h
+	inbox = &mailbox{addr: imapListener.Addr().String(), user: "username", passwd: "password", name: "INBOX"}
+	outbox = &mailer{addr: smtpListener.Addr().String(), from: "horus@example.com"}
+	runner = common.NewRunner(h, outbox)
+	ADDRESS = "horus@example.com"
+	ALLOWED_SENDERS = []string{"ethan@example.com"}
+	OUTREACH_TO = "ethan@example.com"
+
+	deliver := func(raw string) {
+		c, err := imap_client.Dial(inbox.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Logout()
+
+		if err = c.Login("username", "password"); err != nil {
+			t.Fatal(err)
+		}
+		raw = strings.ReplaceAll(raw, "\n", "\r\n")
+		if err = c.Append("INBOX", nil, time.Now(), bytes.NewBufferString(raw)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return fake, h, deliver
+}
+
+// email creates a raw plain text email
+func email(from string, id string, references string, body string) string {
+	raw := "From: " + from + "\nTo: horus@example.com\nSubject: Question\nMessage-ID: " + id + "\n"
+	if references != "" {
+		raw += "In-Reply-To: " + references[strings.LastIndex(references, "<"):] + "\nReferences: " + references + "\n"
+	}
+	return raw + "Content-Type: text/plain; charset=utf-8\n\n" + body + "\n"
+}
+
+// readEmail parses a sent email
+func readEmail(t *testing.T, data string) (*mail.Header, map[string]string) {
+	mr, err := mail.CreateReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := map[string]string{}
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		content, _ := io.ReadAll(p.Body)
+		switch h := p.Header.(type) {
+		case *mail.InlineHeader:
+			contentType, _, _ := h.ContentType()
+			parts[contentType] = string(content)
+		case *mail.AttachmentHeader:
+			filename, _ := h.Filename()
+			parts[filename] = string(content)
+		}
+	}
+
+	return &mr.Header, parts
+}
+
+/* ---- TESTS ---- */
+
+func TestThreads(t *testing.T) {
+	assert := assert.New(t)
+	fake, h, deliver := setup(t)
+
+	// A new email starts a conversation
+	deliver(email("Ethan <ethan@example.com>", "<1@example.com>", "", "Hi Horus"))
+	assert.Nil(inbox.poll(onEmail))
+
+	sent := fake.sent()
+	assert.Len(sent, 1)
+	assert.Equal([]string{"ethan@example.com"}, sent[0].To)
+
+	header, parts := readEmail(t, sent[0].Data)
+	subject, _ := header.Subject()
+	assert.Equal("Re: Question", subject)
+	inReplyTo, _ := header.MsgIDList("In-Reply-To")
+	assert.Equal([]string{"1@example.com"}, inReplyTo)
+	assert.Equal("echo: Hi Horus", parts["text/plain"])
+	assert.Equal("<html><body><strong>echo</strong>: Hi Horus</body></html>", parts["text/html"])
+	assert.Equal([]string{"email-ethan@example.com: Hi Horus"}, h.Messages["email-1@example.com"])
+
+	// Replies continue the conversation without the quoted text
+	reply, _ := header.MessageID()
+	deliver(email("ethan@example.com", "<2@example.com>", "<1@example.com> <"+reply+">", "Thanks\n\nOn Monday, Horus wrote:\n> echo: Hi Horus"))
+	assert.Nil(inbox.poll(onEmail))
+
+	assert.Len(fake.sent(), 2)
+	assert.Equal("email-ethan@example.com: Thanks", h.Messages["email-1@example.com"][1])
+
+	// Emails are only handled once
+	assert.Nil(inbox.poll(onEmail))
+	assert.Len(fake.sent(), 2)
+}
+
+func TestIgnored(t *testing.T) {
+	assert := assert.New(t)
+	fake, h, deliver := setup(t)
+
+	deliver(email("spam@example.com", "<1@example.com>", "", "Buy now"))
+	deliver(email("horus@example.com", "<2@example.com>", "", "Hi"))
+	assert.Nil(inbox.poll(onEmail))
+
+	assert.Len(fake.sent(), 0)
+	assert.Len(h.Messages, 0)
+}
+
+func TestOutputs(t *testing.T) {
+	assert := assert.New(t)
+	fake, _, deliver := setup(t)
+
+	deliver(email("ethan@example.com", "<1@example.com>", "", "file"))
+	deliver(email("ethan@example.com", "<2@example.com>", "", "fail"))
+	assert.Nil(inbox.poll(onEmail))
+
+	sent := fake.sent()
+	assert.Len(sent, 2)
+
+	_, parts := readEmail(t, sent[0].Data)
+	assert.Equal("hello", parts["notes.txt"])
+
+	_, parts = readEmail(t, sent[1].Data)
+	assert.Equal("Sorry, an error occurred: failed <here>", parts["text/plain"])
+}
+
+func TestOutreach(t *testing.T) {
+	assert := assert.New(t)
+	fake, h, deliver := setup(t)
+
+	runner.Outreach("Your <EM>daily<EM> digest")
+
+	sent := fake.sent()
+	assert.Len(sent, 1)
+	header, parts := readEmail(t, sent[0].Data)
+	subject, _ := header.Subject()
+	assert.Equal(OUTREACH_SUBJECT, subject)
+	assert.Equal("Your daily digest", parts["text/plain"])
+
+	id, _ := header.MessageID()
+	assert.Equal([]string{"assistant: Your <EM>daily<EM> digest"}, h.Messages["email-"+id])
+
+	// Replying to the outreach email continues its conversation
+	deliver(email("ethan@example.com", "<1@example.com>", "<"+id+">", "Thanks"))
+	assert.Nil(inbox.poll(onEmail))
+	assert.Len(h.Messages["email-"+id], 2)
+}
+
+func TestParseEmail(t *testing.T) {
+	assert := assert.New(t)
+
+	raw := "From: ethan@example.com\r\nMessage-ID: <1@example.com>\r\nContent-Type: text/html\r\n\r\n<p>Hi &amp; bye</p>\r\n"
+	e, err := parseEmail(strings.NewReader(raw))
+	assert.Nil(err)
+	assert.Equal("Hi & bye", e.Text)
+	assert.Equal("1@example.com", e.Thread())
+
+	_, err = parseEmail(strings.NewReader("From: ethan@example.com\r\n\r\nHi\r\n"))
+	assert.NotNil(err)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	imap_client "github.com/emersion/go-imap/client"
+	_ "github.com/emersion/go-message/charset"
+	"github.com/emersion/go-message/mail"
+)
+
+/* -------- TYPES -------- */
+
+// Email is a received email
+type Email struct {
+	UID        uint32   // The email's UID in the mailbox
+	From       string   // The sender's address
+	Subject    string   // The subject
+	MessageID  string   // The email's Message-ID
+	InReplyTo  []string // The emails this email replies to
+	References []string // The emails in this email's thread, oldest first
+	Text       string   // The plain text body
+}
+
+// Thread returns the Message-ID of the first email in the email's thread
+func (e *Email) Thread() string {
+	if len(e.References) > 0 {
+		return e.References[0]
+	}
+	if len(e.InReplyTo) > 0 {
+		return e.InReplyTo[0]
+	}
+	return e.MessageID
+}
+
+// mailbox is an IMAP mailbox that is polled for new emails
+type mailbox struct {
+	addr   string // The address of the IMAP server
+	user   string
+	passwd string
+	name   string // The name of the mailbox (ex: INBOX)
+	tls    bool   // Whether to connect with implicit TLS
+}
+
+/* -------- FUNCTIONS -------- */
+
+// poll fetches unseen emails, calls handle for each of them and marks them as seen. Emails
+// that cannot be parsed are skipped and marked as seen so they are not fetched again
+func (m *mailbox) poll(handle func(e *Email)) error {
+	var c *imap_client.Client
+	var err error
+	if m.tls {
+		c, err = imap_client.DialTLS(m.addr, nil)
+	} else {
+		c, err = imap_client.Dial(m.addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer c.Logout()
+
+	if err = c.Login(m.user, m.passwd); err != nil {
+		return err
+	}
+	if _, err = c.Select(m.name, false); err != nil {
+		return err
+	}
+
+	// Find unseen emails
+	criteria := imap.NewSearchCriteria()
+	criteria.WithoutFlags = []string{imap.SeenFlag}
+	uids, err := c.UidSearch(criteria)
+	if err != nil || len(uids) == 0 {
+		return err
+	}
+
+	set := new(imap.SeqSet)
+	set.AddNum(uids...)
+
+	// Fetch the emails without marking them as seen
+	section := &imap.BodySectionName{Peek: true}
+	messages := make(chan *imap.Message, len(uids))
+	if err = c.UidFetch(set, []imap.FetchItem{imap.FetchUid, section.FetchItem()}, messages); err != nil {
+		return err
+	}
+
+	for msg := range messages {
+		body := msg.GetBody(section)
+		if body == nil {
+			continue
+		}
+
+		e, err := parseEmail(body)
+		if err != nil {
+			log.Printf("[ERROR]: In email, error parsing email (err: %v)\n", err)
+			continue
+		}
+		e.UID = msg.Uid
+
+		handle(e)
+	}
+
+	// Mark the emails as seen
+	return c.UidStore(set, imap.FormatFlagsOp(imap.AddFlags, true), []interface{}{imap.SeenFlag}, nil)
+}
+
+// parseEmail parses a raw email
+func parseEmail(r io.Reader) (*Email, error) {
+	mr, err := mail.CreateReader(r)
+	if err != nil && mr == nil {
+		return nil, err
+	}
+	defer mr.Close()
+
+	e := &Email{}
+
+	from, err := mr.Header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return nil, fmt.Errorf("email has no sender")
+	}
+	e.From = from[0].Address
+
+	e.Subject, _ = mr.Header.Subject()
+	if e.MessageID, err = mr.Header.MessageID(); err != nil || e.MessageID == "" {
+		return nil, fmt.Errorf("email has no Message-ID")
+	}
+	e.InReplyTo, _ = mr.Header.MsgIDList("In-Reply-To")
+	e.References, _ = mr.Header.MsgIDList("References")
+
+	// Use the first plain text part, falling back to the first HTML part
+	htmlBody := ""
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		h, ok := p.Header.(*mail.InlineHeader)
+		if !ok {
+			continue
+		}
+
+		contentType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+		if contentType == "" {
+			contentType = "text/plain"
+		}
+
+		content, err := io.ReadAll(p.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		if contentType == "text/plain" && e.Text == "" {
+			e.Text = string(content)
+		} else if contentType == "text/html" && htmlBody == "" {
+			htmlBody = string(content)
+		}
+	}
+	if e.Text == "" {
+		e.Text = stripHTML(htmlBody)
+	}
+
+	// Only keep the new part of replies
+	if len(e.InReplyTo) > 0 {
+		e.Text = stripQuoted(e.Text)
+	}
+	e.Text = strings.TrimSpace(strings.ReplaceAll(e.Text, "\r\n", "\n"))
+
+	return e, nil
+}
+
+// stripQuoted removes quoted text from a reply, along with the line introducing it
+// (ex: 'On Mon, Jan 1, 2024 at 7:00 AM Horus <horus@example.com> wrote:')
+func stripQuoted(text string) string {
+	lines := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(line, ">") {
+			// Remove the introduction line and any blank lines before the quote
+			for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+				lines = lines[:len(lines)-1]
+			}
+			if len(lines) > 0 && strings.HasSuffix(strings.TrimSpace(lines[len(lines)-1]), "wrote:") {
+				lines = lines[:len(lines)-1]
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// stripHTML converts a HTML body to plain text by removing its tags
+func stripHTML(body string) string {
+	var sb strings.Builder
+
+	inTag := false
+	for _, c := range body {
+		switch {
+		case c == '<':
+			inTag = true
+		case c == '>' && inTag:
+			inTag = false
+		case !inTag:
+			sb.WriteRune(c)
+		}
+	}
+
+	return html.UnescapeString(sb.String())
+}
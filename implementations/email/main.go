@@ -0,0 +1,162 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	horus "github.com/ethanbaker/horus/bot"
+	"github.com/ethanbaker/horus/implementations/common"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- CONSTANTS -------- */
+
+// Email credentials
+var (
+	ADDRESS string = os.Getenv("EMAIL_ADDRESS") // The address Horus sends and receives emails with
+
+	IMAP_ADDR    string = os.Getenv("EMAIL_IMAP_ADDR")
+	IMAP_USER    string = os.Getenv("EMAIL_IMAP_USER")
+	IMAP_PASSWD  string = os.Getenv("EMAIL_IMAP_PASSWD")
+	IMAP_MAILBOX string = envOr("EMAIL_IMAP_MAILBOX", "INBOX")
+	IMAP_TLS     bool   = os.Getenv("EMAIL_IMAP_TLS") != "false" // Implicit TLS is used unless disabled
+
+	SMTP_ADDR   string = os.Getenv("EMAIL_SMTP_ADDR")
+	SMTP_USER   string = os.Getenv("EMAIL_SMTP_USER")
+	SMTP_PASSWD string = os.Getenv("EMAIL_SMTP_PASSWD")
+
+	ALLOWED_SENDERS  []string = strings.Split(os.Getenv("EMAIL_ALLOWED_SENDERS"), ",")
+	OUTREACH_TO      string   = os.Getenv("EMAIL_OUTREACH_TO")
+	OUTREACH_SUBJECT string   = envOr("EMAIL_OUTREACH_SUBJECT", "Message from Horus")
+)
+
+// How conversations are archived and cleaned up. Email threads are their own conversations, so
+// they are never rotated
+var SESSION_POLICY = horus.SessionPolicy{
+	ArchiveAfter: 30 * 24 * time.Hour, // How long until inactive conversations become read-only
+	Retention:    90 * 24 * time.Hour, // How long archived conversations are kept
+}
+
+// How long tool audit records are kept
+const AUDIT_RETENTION = 30 * 24 * time.Hour
+
+// How often the session policy and audit retention are applied
+const MAINTENANCE_INTERVAL = time.Hour
+
+// How often the mailbox is checked for new emails
+const POLL_INTERVAL = time.Minute
+
+/* -------- GLOBALS -------- */
+
+// The Horus bot
+var bot common.Horus
+
+// The runner connecting email to the Horus bot
+var runner *common.Runner
+
+// The mailbox emails are received in
+var inbox *mailbox
+
+// The mailer emails are sent with
+var outbox *mailer
+
+/* ------------------ FUNCTIONS ------------------ */
+
+// main starts the email bot
+func main() {
+	// Setup the bot and outreach
+	b, ch, err := common.NewBot(common.Setup{Method: types.Email, SessionPolicy: SESSION_POLICY, AuditRetention: AUDIT_RETENTION})
+	if err != nil {
+		log.Fatalf("[ERROR]: In email, error setting up horus (err: %v)\n", err)
+	}
+	bot = b
+
+	inbox = &mailbox{addr: IMAP_ADDR, user: IMAP_USER, passwd: IMAP_PASSWD, name: IMAP_MAILBOX, tls: IMAP_TLS}
+	outbox = &mailer{addr: SMTP_ADDR, user: SMTP_USER, passwd: SMTP_PASSWD, from: ADDRESS}
+
+	// Poll the mailbox until interrupted
+	runner = common.NewRunner(bot, outbox)
+	stop := make(chan struct{})
+	go run(ch, stop)
+
+	// Maintain the bot between messages
+	runner.Maintain(MAINTENANCE_INTERVAL)
+
+	// Make a channel to wait for an interrupt signal (keep the bot running)
+	log.Println("[STATUS]: Email is now running!  (Press CTRL-C to exit)")
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, os.Kill)
+	<-sc
+
+	close(stop)
+}
+
+// run polls the mailbox and handles outreach messages and queued work. All of them are handled
+// in this goroutine since the bot is not safe for concurrent use
+func run(ch chan string, stop chan struct{}) {
+	ticker := time.NewTicker(POLL_INTERVAL)
+	defer ticker.Stop()
+
+	check := func() {
+		if err := inbox.poll(onEmail); err != nil {
+			log.Printf("[ERROR]: In email, error polling mailbox (err: %v)\n", err)
+		}
+	}
+	check()
+
+	for {
+		select {
+		case <-ticker.C:
+			check()
+
+		case content := <-ch:
+			runner.Outreach(content)
+
+		case f := <-runner.Queue():
+			f()
+
+		case <-stop:
+			return
+		}
+	}
+}
+
+// onEmail handles an email from an allowed sender. Each email thread is its own conversation
+func onEmail(e *Email) {
+	// Ignore emails sent by the bot itself, emails from unknown senders and empty emails
+	if strings.EqualFold(e.From, ADDRESS) || !isAllowed(e.From) || len(e.Text) == 0 {
+		return
+	}
+
+	// Replies are sent in the email's thread, which is created if it is new
+	runner.Handle(common.Message{
+		Route:  common.Route{Key: "email-" + e.Thread(), Create: true},
+		Target: e,
+		Text:   e.Text,
+		Caller: "email-" + e.From,
+	})
+}
+
+/* ---- HELPERS ---- */
+
+// isAllowed returns whether emails from an address should be handled
+func isAllowed(address string) bool {
+	for _, allowed := range ALLOWED_SENDERS {
+		if strings.EqualFold(strings.TrimSpace(allowed), address) {
+			return true
+		}
+	}
+	return false
+}
+
+// envOr returns an environment variable or a fallback if it is unset
+func envOr(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/emersion/go-message/mail"
+	"github.com/ethanbaker/horus/utils/format"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- FUNCTIONS -------- */
+
+// composeEmail writes an output as an email. The message and blocks are sent as plain text and
+// HTML alternatives, attachments are attached and actions are listed as replies to choose from
+func composeEmail(h mail.Header, resp *types.Output) (string, error) {
+	parts := []string{}
+	if resp.Message != "" {
+		parts = append(parts, resp.Message)
+	}
+	for _, b := range resp.Blocks {
+		parts = append(parts, format.RenderBlock(b))
+	}
+	if len(resp.Actions) > 0 {
+		parts = append(parts, renderActions(resp.Actions))
+	}
+	markup := strings.Join(parts, "\n\n")
+
+	var sb strings.Builder
+	w, err := mail.CreateWriter(&sb, h)
+	if err != nil {
+		return "", err
+	}
+
+	// Write the text and HTML alternatives
+	iw, err := w.CreateInline()
+	if err != nil {
+		return "", err
+	}
+	if err = writePart(iw, "text/plain", format.Render(markup, format.Plain)); err != nil {
+		return "", err
+	}
+	if err = writePart(iw, "text/html", "<html><body>"+format.Render(markup, format.HTML)+"</body></html>"); err != nil {
+		return "", err
+	}
+	if err = iw.Close(); err != nil {
+		return "", err
+	}
+
+	// Attach files
+	for _, file := range resp.Files() {
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		var ah mail.AttachmentHeader
+		ah.Set("Content-Type", contentType)
+		ah.SetFilename(file.Filename)
+
+		aw, err := w.CreateAttachment(ah)
+		if err != nil {
+			return "", err
+		}
+		if _, err = aw.Write(file.Content); err != nil {
+			return "", err
+		}
+		if err = aw.Close(); err != nil {
+			return "", err
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// writePart writes an inline part with a content type
+func writePart(iw *mail.InlineWriter, contentType string, content string) error {
+	var h mail.InlineHeader
+	h.SetContentType(contentType, map[string]string{"charset": "utf-8"})
+
+	pw, err := iw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(pw, content); err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+// renderActions renders actions as a list of replies to choose from
+func renderActions(actions []types.Action) string {
+	lines := []string{"<STRONG>Reply with one of:<STRONG>"}
+	for _, a := range actions {
+		lines = append(lines, fmt.Sprintf("• <CODE>%v<CODE> (%v)", a.Value, a.Label))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-sasl"
+	"github.com/emersion/go-smtp"
+	"github.com/ethanbaker/horus/implementations/common"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- TYPES -------- */
+
+// mailer sends emails through an SMTP server
+type mailer struct {
+	addr   string // The address of the SMTP server
+	user   string // The user to authenticate as (no authentication if empty)
+	passwd string
+	from   string // The address emails are sent from
+}
+
+/* -------- IMPLEMENTATION -------- */
+
+// Name names the implementation
+func (m *mailer) Name() string {
+	return "email"
+}
+
+// Send replies to an email with an output. Targets are the emails being replied to
+func (m *mailer) Send(target any, output *types.Output) error {
+	e := target.(*Email)
+	_, err := m.send(e.From, "", e, output)
+	return err
+}
+
+// SendOutreach emails an outreach message. Replies to the email continue in its thread's
+// conversation
+func (m *mailer) SendOutreach(output *types.Output) (common.Route, error) {
+	id, err := m.send(OUTREACH_TO, OUTREACH_SUBJECT, nil, output)
+	if err != nil {
+		return common.Route{}, err
+	}
+	return common.Route{Key: "email-" + id}, nil
+}
+
+/* -------- FUNCTIONS -------- */
+
+// send sends an output as an email and returns the email's Message-ID. If a parent email is
+// given, the email is sent as a reply in the parent's thread
+func (m *mailer) send(to string, subject string, parent *Email, resp *types.Output) (string, error) {
+	h := mail.Header{}
+	h.SetDate(time.Now())
+	h.SetAddressList("From", []*mail.Address{{Name: "Horus", Address: m.from}})
+	h.SetAddressList("To", []*mail.Address{{Address: to}})
+
+	// Thread replies with the parent email
+	if parent != nil {
+		subject = replySubject(parent.Subject)
+		h.SetMsgIDList("In-Reply-To", []string{parent.MessageID})
+		h.SetMsgIDList("References", append(append([]string{}, parent.References...), parent.MessageID))
+	}
+	h.SetSubject(subject)
+
+	// Generate the Message-ID with the sender's domain
+	_, domain, _ := strings.Cut(m.from, "@")
+	if err := h.GenerateMessageIDWithHostname(domain); err != nil {
+		return "", err
+	}
+	id, err := h.MessageID()
+	if err != nil {
+		return "", err
+	}
+
+	body, err := composeEmail(h, resp)
+	if err != nil {
+		return "", err
+	}
+
+	var auth sasl.Client
+	if m.user != "" {
+		auth = sasl.NewPlainClient("", m.user, m.passwd)
+	}
+
+	return id, smtp.SendMail(m.addr, auth, m.from, []string{to}, strings.NewReader(body))
+}
+
+// replySubject returns the subject of a reply to an email
+func replySubject(subject string) string {
+	if strings.HasPrefix(strings.ToLower(subject), "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- TYPES -------- */
+
+// SyncResponse is the response of a sync request
+type SyncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join   map[string]JoinedRoom      `json:"join"`
+		Invite map[string]json.RawMessage `json:"invite"`
+	} `json:"rooms"`
+}
+
+// JoinedRoom holds the new events of a joined room
+type JoinedRoom struct {
+	Timeline struct {
+		Events []Event `json:"events"`
+	} `json:"timeline"`
+}
+
+// Event is a room event
+type Event struct {
+	Type    string       `json:"type"`
+	EventID string       `json:"event_id"`
+	Sender  string       `json:"sender"`
+	RoomID  string       `json:"room_id,omitempty"` // Filled in from the sync response
+	Content EventContent `json:"content"`
+}
+
+// EventContent is the content of an m.room.message event
+type EventContent struct {
+	MsgType       string     `json:"msgtype,omitempty"`
+	Body          string     `json:"body"`
+	Format        string     `json:"format,omitempty"`
+	FormattedBody string     `json:"formatted_body,omitempty"`
+	URL           string     `json:"url,omitempty"`
+	Filename      string     `json:"filename,omitempty"`
+	Info          *FileInfo  `json:"info,omitempty"`
+	RelatesTo     *RelatesTo `json:"m.relates_to,omitempty"`
+}
+
+// FileInfo describes an uploaded file
+type FileInfo struct {
+	Mimetype string `json:"mimetype,omitempty"`
+	Size     int    `json:"size"`
+}
+
+// RelatesTo relates an event to another event, such as the root of a thread
+type RelatesTo struct {
+	RelType       string     `json:"rel_type,omitempty"`
+	EventID       string     `json:"event_id,omitempty"`
+	IsFallingBack bool       `json:"is_falling_back,omitempty"`
+	InReplyTo     *InReplyTo `json:"m.in_reply_to,omitempty"`
+}
+
+// InReplyTo is the event an event replies to
+type InReplyTo struct {
+	EventID string `json:"event_id"`
+}
+
+// ThreadID returns the root event of the thread an event is in, or an empty string
+func (e *Event) ThreadID() string {
+	if e.Content.RelatesTo != nil && e.Content.RelatesTo.RelType == REL_THREAD {
+		return e.Content.RelatesTo.EventID
+	}
+	return ""
+}
+
+// matrixError is the error body of a failed request
+type matrixError struct {
+	ErrCode string `json:"errcode"`
+	Error   string `json:"error"`
+}
+
+/* -------- CONSTANTS -------- */
+
+// The relation type of threads
+const REL_THREAD = "m.thread"
+
+// Client-server API paths
+const (
+	CLIENT_PATH = "/_matrix/client/v3"
+	MEDIA_PATH  = "/_matrix/media/v3"
+)
+
+/* -------- CLIENT -------- */
+
+// matrixAPI is a minimal client for the Matrix client-server API
+type matrixAPI struct {
+	homeserver string
+	token      string
+	client     *http.Client
+	txn        atomic.Int64 // Used to create unique transaction IDs
+}
+
+// newMatrixAPI creates a client for a homeserver
+func newMatrixAPI(homeserver string, token string) *matrixAPI {
+	return &matrixAPI{
+		homeserver: homeserver,
+		token:      token,
+		client:     &http.Client{Timeout: 2 * SYNC_TIMEOUT},
+	}
+}
+
+// do sends a request to the homeserver and decodes the JSON response into v
+func (a *matrixAPI) do(method string, path string, query url.Values, contentType string, body io.Reader, v any) error {
+	u := a.homeserver + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var e matrixError
+		json.NewDecoder(res.Body).Decode(&e)
+		return fmt.Errorf("%v %v failed (status %v, %v): %v", method, path, res.StatusCode, e.ErrCode, e.Error)
+	}
+
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// call sends a JSON request to the client API
+func (a *matrixAPI) call(method string, path string, params any, v any) error {
+	var body io.Reader
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+
+	return a.do(method, CLIENT_PATH+path, nil, "application/json", body, v)
+}
+
+// whoami returns the user ID of the access token
+func (a *matrixAPI) whoami() (string, error) {
+	var res struct {
+		UserID string `json:"user_id"`
+	}
+	return res.UserID, a.call(http.MethodGet, "/account/whoami", nil, &res)
+}
+
+// sync waits for events after a batch token
+func (a *matrixAPI) sync(since string, timeout time.Duration) (*SyncResponse, error) {
+	query := url.Values{"timeout": {strconv.FormatInt(timeout.Milliseconds(), 10)}}
+	if since != "" {
+		query.Set("since", since)
+	}
+
+	res := &SyncResponse{}
+	return res, a.do(http.MethodGet, CLIENT_PATH+"/sync", query, "", nil, res)
+}
+
+// join joins a room
+func (a *matrixAPI) join(roomID string) error {
+	return a.call(http.MethodPost, "/join/"+url.PathEscape(roomID), map[string]any{}, nil)
+}
+
+// sendMessage sends an m.room.message event to a room and returns its event ID
+func (a *matrixAPI) sendMessage(roomID string, content EventContent) (string, error) {
+	txn := fmt.Sprintf("horus-%v-%v", time.Now().UnixNano(), a.txn.Add(1))
+
+	var res struct {
+		EventID string `json:"event_id"`
+	}
+	err := a.call(http.MethodPut, "/rooms/"+url.PathEscape(roomID)+"/send/m.room.message/"+txn, content, &res)
+	return res.EventID, err
+}
+
+// upload uploads a file to the media repository and returns its content URI
+func (a *matrixAPI) upload(file types.FileOutput) (string, error) {
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	var res struct {
+		ContentURI string `json:"content_uri"`
+	}
+	err := a.do(http.MethodPost, MEDIA_PATH+"/upload", url.Values{"filename": {file.Filename}}, contentType, bytes.NewReader(file.Content), &res)
+	return res.ContentURI, err
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	horus "github.com/ethanbaker/horus/bot"
+	"github.com/ethanbaker/horus/implementations/common"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- CONSTANTS -------- */
+
+// Matrix credentials
+var (
+	HOMESERVER string = os.Getenv("MATRIX_HOMESERVER")
+	TOKEN      string = os.Getenv("MATRIX_TOKEN")
+
+	BOT_OPEN_ROOMS   []string = strings.Split(os.Getenv("MATRIX_BOT_OPEN_ROOMS"), ",")
+	BOT_THREAD_ROOMS []string = strings.Split(os.Getenv("MATRIX_BOT_THREAD_ROOMS"), ",")
+	OUTREACH_ROOM    string   = os.Getenv("MATRIX_OUTREACH_ROOM")
+)
+
+// How conversations in bot rooms are rotated and cleaned up
+var SESSION_POLICY = horus.SessionPolicy{
+	IdleTimeout:  6 * time.Hour,       // How long until a new conversation begins in bot rooms
+	ArchiveAfter: 7 * 24 * time.Hour,  // How long until inactive conversations become read-only
+	Retention:    90 * 24 * time.Hour, // How long archived conversations are kept
+}
+
+// How long tool audit records are kept
+const AUDIT_RETENTION = 30 * 24 * time.Hour
+
+// How often the session policy and audit retention are applied
+const MAINTENANCE_INTERVAL = time.Hour
+
+// How long a sync request waits for new events
+const SYNC_TIMEOUT = 30 * time.Second
+
+// How long to wait before syncing again after an error
+const SYNC_RETRY_DELAY = 5 * time.Second
+
+// The command used to start a new conversation in a thread
+const CONVERSATION_COMMAND = "!conversation"
+
+/* -------- GLOBALS -------- */
+
+// The Horus bot
+var bot common.Horus
+
+// The runner connecting Matrix to the Horus bot
+var runner *common.Runner
+
+// The user ID of the Matrix account
+var userID string
+
+/* ------------------ FUNCTIONS ------------------ */
+
+// main starts the matrix bot
+func main() {
+	// Setup the bot and outreach
+	b, ch, err := common.NewBot(common.Setup{Method: types.Matrix, SessionPolicy: SESSION_POLICY, AuditRetention: AUDIT_RETENTION})
+	if err != nil {
+		log.Fatalf("[ERROR]: In matrix, error setting up horus (err: %v)\n", err)
+	}
+	bot = b
+
+	// Find the account's user ID so its own messages are ignored
+	api := newMatrixAPI(HOMESERVER, TOKEN)
+	if userID, err = api.whoami(); err != nil {
+		log.Fatalf("[ERROR]: In matrix, error getting user ID (err: %v)\n", err)
+	}
+
+	// Sync events until interrupted
+	runner = common.NewRunner(bot, &matrix{api: api})
+	stop := make(chan struct{})
+	go run(api, ch, stop)
+
+	// Maintain the bot between messages
+	runner.Maintain(MAINTENANCE_INTERVAL)
+
+	// Make a channel to wait for an interrupt signal (keep the bot running)
+	log.Println("[STATUS]: Matrix is now running!  (Press CTRL-C to exit)")
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, os.Kill)
+	<-sc
+
+	close(stop)
+}
+
+// run syncs events and handles them alongside outreach messages and queued work. All of them
+// are handled in this goroutine since the bot is not safe for concurrent use
+func run(api *matrixAPI, ch chan string, stop chan struct{}) {
+	events := make(chan Event)
+	go syncEvents(api, events, stop)
+
+	for {
+		select {
+		case e := <-events:
+			handleEvent(api, e)
+
+		case content := <-ch:
+			runner.Outreach(content)
+
+		case f := <-runner.Queue():
+			f()
+
+		case <-stop:
+			return
+		}
+	}
+}
+
+// syncEvents long polls the homeserver for events. Events from before the first sync are skipped
+// so old messages are not answered again, and invites to bot rooms are accepted
+func syncEvents(api *matrixAPI, events chan Event, stop chan struct{}) {
+	since := ""
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		timeout := SYNC_TIMEOUT
+		if since == "" {
+			timeout = 0
+		}
+
+		res, err := api.sync(since, timeout)
+		if err != nil {
+			log.Printf("[ERROR]: In matrix, error syncing (err: %v)\n", err)
+
+			select {
+			case <-time.After(SYNC_RETRY_DELAY):
+				continue
+			case <-stop:
+				return
+			}
+		}
+
+		// Join rooms the bot is invited to
+		for roomID := range res.Rooms.Invite {
+			if !isBotRoom(roomID) {
+				continue
+			}
+			if err := api.join(roomID); err != nil {
+				log.Printf("[ERROR]: In matrix, error joining room '%v' (err: %v)\n", roomID, err)
+			}
+		}
+
+		if since != "" {
+			for roomID, room := range res.Rooms.Join {
+				for _, e := range room.Timeline.Events {
+					e.RoomID = roomID
+
+					select {
+					case events <- e:
+					case <-stop:
+						return
+					}
+				}
+			}
+		}
+		since = res.NextBatch
+	}
+}
+
+// handleEvent handles a single room event
+func handleEvent(api *matrixAPI, e Event) {
+	// Ignore events that aren't text messages and messages created by the bot itself. Encrypted
+	// rooms aren't supported, so their events are never m.room.message events
+	if e.Type != "m.room.message" || e.Content.MsgType != "m.text" || e.Sender == userID || len(e.Content.Body) == 0 {
+		return
+	}
+
+	if thread := e.ThreadID(); thread != "" {
+		onThreadMessage(api, e, thread)
+		return
+	}
+
+	if strings.TrimSpace(e.Content.Body) == CONVERSATION_COMMAND {
+		onCommand(api, e)
+		return
+	}
+
+	onMessage(api, e)
+}
+
+// onMessage handles any message sent in a bot room
+func onMessage(api *matrixAPI, e Event) {
+	// Ignore messages not in one of the specified bot rooms
+	if !contains(BOT_OPEN_ROOMS, e.RoomID) && e.RoomID != OUTREACH_ROOM {
+		return
+	}
+
+	runner.Handle(common.Message{
+		Route:  common.Route{Session: "matrix-" + e.RoomID},
+		Target: room{id: e.RoomID},
+		Text:   e.Content.Body,
+		Caller: "matrix-" + e.Sender,
+	})
+}
+
+// onThreadMessage handles any message sent in threads
+func onThreadMessage(api *matrixAPI, e Event, thread string) {
+	// Threads have their own conversation, which is only answered if it was started by the bot
+	runner.Handle(common.Message{
+		Route:  common.Route{Key: "matrix-" + e.RoomID + "-" + thread},
+		Target: room{id: e.RoomID, thread: thread},
+		Text:   e.Content.Body,
+		Caller: "matrix-" + e.Sender,
+	})
+}
+
+// onCommand starts a new conversation in a thread
+func onCommand(api *matrixAPI, e Event) {
+	// Ignore commands not in one of the specified bot rooms
+	if !contains(BOT_THREAD_ROOMS, e.RoomID) {
+		return
+	}
+
+	// Create a new message to host the thread
+	thread, err := sendText(api, e.RoomID, "", "Hello! What do you need help with today? Reply in this message's thread.")
+	if err != nil {
+		return
+	}
+
+	// Register the thread to the bot
+	if err := bot.AddConversation("matrix-" + e.RoomID + "-" + thread); err != nil {
+		runner.Error(room{id: e.RoomID}, err)
+	}
+}
+
+/* ---- HELPERS ---- */
+
+// isBotRoom returns whether the bot should be in a room
+func isBotRoom(roomID string) bool {
+	return roomID == OUTREACH_ROOM || contains(BOT_OPEN_ROOMS, roomID) || contains(BOT_THREAD_ROOMS, roomID)
+}
+
+// contains returns whether a list contains a value
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
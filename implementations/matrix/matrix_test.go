@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethanbaker/horus/implementations/common"
+	"github.com/ethanbaker/horus/implementations/common/commontest"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeHomeserver is a local stand-in for a Matrix homeserver
+type fakeHomeserver struct {
+	mu      sync.Mutex
+	batches []*SyncResponse // Sync responses returned in order
+	sent    []sentEvent
+	joined  []string
+	uploads map[string]string
+	syncs   []string // The since tokens of sync requests
+}
+
+// sentEvent is a message sent to the fake homeserver
+type sentEvent struct {
+	RoomID  string
+	EventID string
+	Content EventContent
+}
+
+func newFakeHomeserver() *fakeHomeserver {
+	return &fakeHomeserver{uploads: map[string]string{}}
+}
+
+func (f *fakeHomeserver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer TOKEN" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(matrixError{ErrCode: "M_UNKNOWN_TOKEN", Error: "Invalid access token"})
+		return
+	}
+
+	// Return no events after a short wait instead of long polling
+	if r.URL.Path == CLIENT_PATH+"/sync" {
+		f.mu.Lock()
+		empty := len(f.batches) == 0
+		f.mu.Unlock()
+		if empty {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, CLIENT_PATH)
+	switch {
+	case path == "/account/whoami":
+		json.NewEncoder(w).Encode(map[string]string{"user_id": "@horus:local"})
+
+	case path == "/sync":
+		f.syncs = append(f.syncs, r.URL.Query().Get("since"))
+
+		res := &SyncResponse{NextBatch: r.URL.Query().Get("since")}
+		if len(f.batches) > 0 {
+			res, f.batches = f.batches[0], f.batches[1:]
+		}
+		json.NewEncoder(w).Encode(res)
+
+	case strings.HasPrefix(path, "/join/"):
+		f.joined = append(f.joined, strings.TrimPrefix(path, "/join/"))
+		json.NewEncoder(w).Encode(map[string]string{})
+
+	case strings.HasPrefix(path, "/rooms/") && r.Method == http.MethodPut:
+		roomID, _, _ := strings.Cut(strings.TrimPrefix(path, "/rooms/"), "/")
+
+		var content EventContent
+		json.NewDecoder(r.Body).Decode(&content)
+		id := fmt.Sprintf("$event%v", len(f.sent))
+		f.sent = append(f.sent, sentEvent{RoomID: roomID, EventID: id, Content: content})
+		json.NewEncoder(w).Encode(map[string]string{"event_id": id})
+
+	case r.URL.Path == MEDIA_PATH+"/upload":
+		content, _ := io.ReadAll(r.Body)
+		uri := "mxc://local/" + r.URL.Query().Get("filename")
+		f.uploads[uri] = string(content)
+		json.NewEncoder(w).Encode(map[string]string{"content_uri": uri})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(matrixError{ErrCode: "M_UNRECOGNIZED", Error: "Unrecognized request"})
+	}
+}
+
+// events returns the events sent to the homeserver
+func (f *fakeHomeserver) events() []sentEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sentEvent{}, f.sent...)
+}
+
+// setup starts a fake homeserver and installs a fake Horus bot
+func setup(t *testing.T) (*fakeHomeserver, *matrixAPI, *commontest.Horus) {
+	fake := newFakeHomeserver()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	h := commontest.New()
+	h.Replies["file"] = &types.Output{Attachments: []types.FileOutput{{Filename: "cat.png", ContentType: "image/png", Content: []byte("meow")}}}
+	h.Replies["choose"] = &types.Output{Message: "Pick one", Actions: []types.Action{{Label: "Yes", Value: "yes"}}}
+	api := newMatrixAPI(server.URL, "TOKEN")
+	bot = h
+	runner = common.NewRunner(h, &matrix{api: api})
+	userID = "@horus:local"
+	BOT_OPEN_ROOMS = []string{"!open:local"}
+	BOT_THREAD_ROOMS = []string{"!threads:local"}
+	OUTREACH_ROOM = "!dm:local"
+
+	return fake, api, h
+}
+
+func text(roomID string, sender string, body string) Event {
+	return Event{Type: "m.room.message", EventID: "$in", Sender: sender, RoomID: roomID, Content: EventContent{MsgType: "m.text", Body: body}}
+}
+
+func TestMessage(t *testing.T) {
+	assert := assert.New(t)
+	fake, api, h := setup(t)
+
+	handleEvent(api, text("!open:local", "@ethan:local", "hi <3"))
+
+	sent := fake.events()
+	assert.Len(sent, 1)
+	assert.Equal("!open:local", sent[0].RoomID)
+	assert.Equal("echo: hi <3", sent[0].Content.Body)
+	assert.Equal(HTML_FORMAT, sent[0].Content.Format)
+	assert.Equal("<strong>echo</strong>: hi &lt;3", sent[0].Content.FormattedBody)
+	assert.Equal([]string{"matrix-@ethan:local: hi <3"}, h.Messages["matrix-!open:local#0"])
+
+	// Messages from the bot itself and outside of bot rooms are ignored
+	handleEvent(api, text("!open:local", "@horus:local", "hi"))
+	handleEvent(api, text("!other:local", "@ethan:local", "hi"))
+	assert.Len(fake.events(), 1)
+
+	// Errors are reported to the room
+	handleEvent(api, text("!open:local", "@ethan:local", "fail"))
+	assert.Equal("Sorry, an error occurred: failed", fake.events()[1].Content.Body)
+}
+
+func TestThreads(t *testing.T) {
+	assert := assert.New(t)
+	fake, api, h := setup(t)
+
+	// Threads are only created in thread rooms
+	handleEvent(api, text("!open:local", "@ethan:local", CONVERSATION_COMMAND))
+	assert.Len(h.Messages, 0)
+
+	handleEvent(api, text("!threads:local", "@ethan:local", CONVERSATION_COMMAND))
+	sent := fake.events()
+	thread := sent[len(sent)-1].EventID
+	assert.True(h.IsConversation("matrix-!threads:local-" + thread))
+
+	// Messages in the thread go to its conversation and replies stay in the thread
+	e := text("!threads:local", "@ethan:local", "hello")
+	e.Content.RelatesTo = &RelatesTo{RelType: REL_THREAD, EventID: thread}
+	handleEvent(api, e)
+
+	assert.Equal([]string{"matrix-@ethan:local: hello"}, h.Messages["matrix-!threads:local-"+thread])
+	sent = fake.events()
+	assert.Equal(REL_THREAD, sent[len(sent)-1].Content.RelatesTo.RelType)
+	assert.Equal(thread, sent[len(sent)-1].Content.RelatesTo.EventID)
+
+	// Unknown threads are ignored
+	e.Content.RelatesTo.EventID = "$unknown"
+	handleEvent(api, e)
+	assert.Len(fake.events(), len(sent))
+}
+
+func TestOutputs(t *testing.T) {
+	assert := assert.New(t)
+	fake, api, _ := setup(t)
+
+	handleEvent(api, text("!open:local", "@ethan:local", "file"))
+	sent := fake.events()
+	assert.Len(sent, 1)
+	assert.Equal("m.image", sent[0].Content.MsgType)
+	assert.Equal("mxc://local/cat.png", sent[0].Content.URL)
+	assert.Equal("meow", fake.uploads["mxc://local/cat.png"])
+
+	handleEvent(api, text("!open:local", "@ethan:local", "choose"))
+	assert.Equal("Pick one\n\nReply with one of:\n• yes (Yes)", fake.events()[1].Content.Body)
+}
+
+func TestOutreach(t *testing.T) {
+	assert := assert.New(t)
+	fake, _, h := setup(t)
+
+	runner.Outreach("Good <EM>morning<EM>")
+
+	sent := fake.events()
+	assert.Len(sent, 1)
+	assert.Equal("!dm:local", sent[0].RoomID)
+	assert.Equal("Good <em>morning</em>", sent[0].Content.FormattedBody)
+	assert.Equal([]string{"assistant: Good <EM>morning<EM>"}, h.Messages["matrix-!dm:local#1"])
+}
+
+func TestSync(t *testing.T) {
+	assert := assert.New(t)
+	fake, api, h := setup(t)
+
+	// Events from the initial sync are skipped
+	initial := &SyncResponse{NextBatch: "s1"}
+	initial.Rooms.Join = map[string]JoinedRoom{"!open:local": {}}
+	initial.Rooms.Invite = map[string]json.RawMessage{"!dm:local": nil, "!spam:local": nil}
+	room := initial.Rooms.Join["!open:local"]
+	room.Timeline.Events = []Event{text("", "@ethan:local", "old")}
+	initial.Rooms.Join["!open:local"] = room
+
+	next := &SyncResponse{NextBatch: "s2"}
+	next.Rooms.Join = map[string]JoinedRoom{"!open:local": {}}
+	room = next.Rooms.Join["!open:local"]
+	room.Timeline.Events = []Event{text("", "@ethan:local", "new")}
+	next.Rooms.Join["!open:local"] = room
+
+	fake.batches = []*SyncResponse{initial, next}
+
+	stop := make(chan struct{})
+	go run(api, make(chan string), stop)
+	defer close(stop)
+
+	assert.Eventually(func() bool { return len(fake.events()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal([]string{"matrix-@ethan:local: new"}, h.Messages["matrix-!open:local#0"])
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	assert.Equal([]string{"!dm:local"}, fake.joined)
+	assert.Equal([]string{"", "s1"}, fake.syncs[:2])
+}
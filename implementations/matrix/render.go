@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ethanbaker/horus/implementations/common"
+	"github.com/ethanbaker/horus/utils/format"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- CONSTANTS -------- */
+
+// The format of HTML message bodies
+const HTML_FORMAT = "org.matrix.custom.html"
+
+/* -------- IMPLEMENTATION -------- */
+
+// room is where replies are sent: a room and the thread in it, if any
+type room struct {
+	id     string
+	thread string
+}
+
+// matrix sends outputs to Matrix rooms. Targets are rooms
+type matrix struct {
+	api *matrixAPI
+}
+
+// Name names the implementation
+func (m *matrix) Name() string {
+	return "matrix"
+}
+
+// Send sends an output to a room
+func (m *matrix) Send(target any, output *types.Output) error {
+	r := target.(room)
+	return sendOutput(m.api, r.id, r.thread, output)
+}
+
+// SendOutreach sends an outreach message to the outreach room. Replies continue in the room's
+// session
+func (m *matrix) SendOutreach(output *types.Output) (common.Route, error) {
+	return common.Route{Session: "matrix-" + OUTREACH_ROOM}, sendOutput(m.api, OUTREACH_ROOM, "", output)
+}
+
+/* -------- FUNCTIONS -------- */
+
+// sendOutput sends an output to a room or thread. The message and blocks are sent as one HTML
+// message, attachments are uploaded and sent as files and actions are listed as replies to choose
+// from since Matrix has no buttons
+func sendOutput(api *matrixAPI, roomID string, thread string, resp *types.Output) error {
+	parts := []string{}
+	if resp.Message != "" {
+		parts = append(parts, resp.Message)
+	}
+	for _, b := range resp.Blocks {
+		parts = append(parts, format.RenderBlock(b))
+	}
+	if len(resp.Actions) > 0 {
+		parts = append(parts, renderActions(resp.Actions))
+	}
+
+	if len(parts) > 0 {
+		markup := strings.Join(parts, "\n\n")
+		if _, err := api.sendMessage(roomID, withThread(EventContent{
+			MsgType:       "m.text",
+			Body:          format.Render(markup, format.Plain),
+			Format:        HTML_FORMAT,
+			FormattedBody: renderHTML(markup),
+		}, thread)); err != nil {
+			return err
+		}
+	}
+
+	// Upload and send files
+	for _, file := range resp.Files() {
+		uri, err := api.upload(file)
+		if err != nil {
+			return fmt.Errorf("cannot upload file '%v': %w", file.Filename, err)
+		}
+
+		msgType := "m.file"
+		if strings.HasPrefix(file.ContentType, "image/") {
+			msgType = "m.image"
+		}
+
+		_, err = api.sendMessage(roomID, withThread(EventContent{
+			MsgType:  msgType,
+			Body:     file.Filename,
+			Filename: file.Filename,
+			URL:      uri,
+			Info:     &FileInfo{Mimetype: file.ContentType, Size: len(file.Content)},
+		}, thread))
+		if err != nil {
+			return fmt.Errorf("cannot send file '%v': %w", file.Filename, err)
+		}
+	}
+
+	return nil
+}
+
+// renderHTML renders markup as HTML, using Matrix's spoiler attribute
+func renderHTML(markup string) string {
+	return strings.ReplaceAll(format.Render(markup, format.HTML), `<span class="spoiler">`, "<span data-mx-spoiler>")
+}
+
+// renderActions renders actions as a list of replies to choose from
+func renderActions(actions []types.Action) string {
+	lines := []string{"<STRONG>Reply with one of:<STRONG>"}
+	for _, a := range actions {
+		lines = append(lines, fmt.Sprintf("• <CODE>%v<CODE> (%v)", a.Value, a.Label))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// withThread adds a thread relation to event content if a thread is given. Clients without
+// thread support show the event as a reply to the thread's root
+func withThread(content EventContent, thread string) EventContent {
+	if thread != "" {
+		content.RelatesTo = &RelatesTo{
+			RelType:       REL_THREAD,
+			EventID:       thread,
+			IsFallingBack: true,
+			InReplyTo:     &InReplyTo{EventID: thread},
+		}
+	}
+	return content
+}
+
+// sendText sends plain text to a room or thread and returns its event ID
+func sendText(api *matrixAPI, roomID string, thread string, text string) (string, error) {
+	id, err := api.sendMessage(roomID, withThread(EventContent{MsgType: "m.text", Body: text}, thread))
+	if err != nil {
+		log.Printf("[ERROR]: In matrix, error sending message (err: %v)\n", err)
+	}
+	return id, err
+}
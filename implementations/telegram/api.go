@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- TYPES -------- */
+
+// Update is an incoming update from the Telegram Bot API
+type Update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+}
+
+// Message is a Telegram message
+type Message struct {
+	MessageID       int64  `json:"message_id"`
+	MessageThreadID int64  `json:"message_thread_id,omitempty"` // The forum topic the message is in
+	IsTopicMessage  bool   `json:"is_topic_message,omitempty"`
+	From            *User  `json:"from,omitempty"`
+	Chat            Chat   `json:"chat"`
+	Text            string `json:"text,omitempty"`
+}
+
+// Chat is a Telegram chat
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// User is a Telegram user
+type User struct {
+	ID       int64  `json:"id"`
+	IsBot    bool   `json:"is_bot"`
+	Username string `json:"username,omitempty"`
+}
+
+// CallbackQuery is sent when a user presses an inline keyboard button
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    User     `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+// InlineKeyboardMarkup is a keyboard shown under a message
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// InlineKeyboardButton is a button in an inline keyboard
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// SendMessageRequest holds the parameters of sendMessage
+type SendMessageRequest struct {
+	ChatID          int64                 `json:"chat_id"`
+	MessageThreadID int64                 `json:"message_thread_id,omitempty"`
+	Text            string                `json:"text"`
+	ParseMode       string                `json:"parse_mode,omitempty"`
+	ReplyMarkup     *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// apiResponse is the envelope of every Bot API response
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+	ErrorCode   int             `json:"error_code"`
+}
+
+/* -------- CLIENT -------- */
+
+// telegramAPI is a minimal client for the Telegram Bot API
+type telegramAPI struct {
+	url    string // The base URL of the bot's methods
+	client *http.Client
+}
+
+// newTelegramAPI creates a client for a bot. The base URL can point to a local Bot API server
+func newTelegramAPI(baseURL string, token string) *telegramAPI {
+	return &telegramAPI{
+		url:    baseURL + "/bot" + token + "/",
+		client: &http.Client{Timeout: 2 * POLL_TIMEOUT},
+	}
+}
+
+// call calls a Bot API method with JSON parameters and decodes its result into v
+func (a *telegramAPI) call(method string, params any, v any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	res, err := a.client.Post(a.url+method, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	return decodeResponse(method, res, v)
+}
+
+// decodeResponse decodes a Bot API response
+func decodeResponse(method string, res *http.Response, v any) error {
+	defer res.Body.Close()
+
+	var apiRes apiResponse
+	if err := json.NewDecoder(res.Body).Decode(&apiRes); err != nil {
+		return fmt.Errorf("cannot decode %v response (status %v): %w", method, res.StatusCode, err)
+	}
+	if !apiRes.OK {
+		return fmt.Errorf("%v failed (code %v): %v", method, apiRes.ErrorCode, apiRes.Description)
+	}
+
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(apiRes.Result, v)
+}
+
+// getUpdates waits for updates after an offset
+func (a *telegramAPI) getUpdates(offset int64, timeout time.Duration) ([]Update, error) {
+	updates := []Update{}
+	return updates, a.call("getUpdates", map[string]any{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message", "callback_query"},
+	}, &updates)
+}
+
+// sendMessage sends a text message
+func (a *telegramAPI) sendMessage(req SendMessageRequest) error {
+	return a.call("sendMessage", req, nil)
+}
+
+// sendDocument sends a file as a document
+func (a *telegramAPI) sendDocument(chatID int64, threadID int64, file types.FileOutput) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	w.WriteField("chat_id", strconv.FormatInt(chatID, 10))
+	if threadID != 0 {
+		w.WriteField("message_thread_id", strconv.FormatInt(threadID, 10))
+	}
+
+	part, err := w.CreateFormFile("document", file.Filename)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, bytes.NewReader(file.Content)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	res, err := a.client.Post(a.url+"sendDocument", w.FormDataContentType(), &body)
+	if err != nil {
+		return err
+	}
+
+	return decodeResponse("sendDocument", res, nil)
+}
+
+// answerCallbackQuery acknowledges a button press
+func (a *telegramAPI) answerCallbackQuery(id string) error {
+	return a.call("answerCallbackQuery", map[string]any{"callback_query_id": id}, nil)
+}
+
+// removeKeyboard removes the inline keyboard from a message
+func (a *telegramAPI) removeKeyboard(chatID int64, messageID int64) error {
+	return a.call("editMessageReplyMarkup", map[string]any{
+		"chat_id":      chatID,
+		"message_id":   messageID,
+		"reply_markup": InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{}},
+	}, nil)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	horus "github.com/ethanbaker/horus/bot"
+	"github.com/ethanbaker/horus/implementations/common"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- CONSTANTS -------- */
+
+// Telegram credentials
+var (
+	TOKEN         string   = os.Getenv("TELEGRAM_TOKEN")
+	API_URL       string   = envOr("TELEGRAM_API_URL", "https://api.telegram.org") // Can point to a local Bot API server
+	ALLOWED_CHATS []string = strings.Split(os.Getenv("TELEGRAM_ALLOWED_CHATS"), ",")
+	OUTREACH_CHAT string   = os.Getenv("TELEGRAM_OUTREACH_CHAT")
+)
+
+// The permissions of users, as a semicolon separated list (ex: "12345:all;67890:gpt,public"), and
+// of users in the allowed chats that aren't listed
+var (
+	USERS               string = os.Getenv("TELEGRAM_USERS")
+	DEFAULT_PERMISSIONS string = envOr("TELEGRAM_DEFAULT_PERMISSIONS", "gpt,public")
+)
+
+// How conversations in chats are rotated and cleaned up
+var SESSION_POLICY = horus.SessionPolicy{
+	IdleTimeout:  6 * time.Hour,       // How long until a new conversation begins in a chat
+	ArchiveAfter: 7 * 24 * time.Hour,  // How long until inactive conversations become read-only
+	Retention:    90 * 24 * time.Hour, // How long archived conversations are kept
+}
+
+// How long tool audit records are kept
+const AUDIT_RETENTION = 30 * 24 * time.Hour
+
+// How often the session policy and audit retention are applied
+const MAINTENANCE_INTERVAL = time.Hour
+
+// How long a getUpdates call waits for new updates
+const POLL_TIMEOUT = 30 * time.Second
+
+// How long to wait before polling again after an error
+const POLL_RETRY_DELAY = 5 * time.Second
+
+/* -------- GLOBALS -------- */
+
+// The Horus bot
+var bot common.Horus
+
+// The runner connecting Telegram to the Horus bot
+var runner *common.Runner
+
+// The permissions of Telegram users
+var permissions userPermissions
+
+/* ------------------ FUNCTIONS ------------------ */
+
+// main starts the telegram bot
+func main() {
+	// Read the permissions of users
+	var err error
+	if permissions, err = parseUserPermissions(USERS, DEFAULT_PERMISSIONS, OUTREACH_CHAT); err != nil {
+		log.Fatalf("[ERROR]: In telegram, invalid TELEGRAM_USERS or TELEGRAM_DEFAULT_PERMISSIONS (err: %v)\n", err)
+	}
+
+	// Setup the bot and outreach
+	b, ch, err := common.NewBot(common.Setup{Method: types.Telegram, SessionPolicy: SESSION_POLICY, AuditRetention: AUDIT_RETENTION})
+	if err != nil {
+		log.Fatalf("[ERROR]: In telegram, error setting up horus (err: %v)\n", err)
+	}
+	bot = b
+
+	// Poll for updates until interrupted
+	api := newTelegramAPI(API_URL, TOKEN)
+	runner = common.NewRunner(bot, &telegram{api: api})
+	stop := make(chan struct{})
+	go run(api, ch, stop)
+
+	// Maintain the bot between messages
+	runner.Maintain(MAINTENANCE_INTERVAL)
+
+	// Make a channel to wait for an interrupt signal (keep the bot running)
+	log.Println("[STATUS]: Telegram is now running!  (Press CTRL-C to exit)")
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, os.Kill)
+	<-sc
+
+	close(stop)
+}
+
+// run polls for updates and handles them alongside outreach messages and queued work. All of
+// them are handled in this goroutine since the bot is not safe for concurrent use
+func run(api *telegramAPI, ch chan string, stop chan struct{}) {
+	updates := make(chan Update)
+	go poll(api, updates, stop)
+
+	for {
+		select {
+		case u := <-updates:
+			handleUpdate(api, u)
+
+		case content := <-ch:
+			runner.Outreach(content)
+
+		case f := <-runner.Queue():
+			f()
+
+		case <-stop:
+			return
+		}
+	}
+}
+
+// poll long polls the Bot API for updates
+func poll(api *telegramAPI, updates chan Update, stop chan struct{}) {
+	var offset int64
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		batch, err := api.getUpdates(offset, POLL_TIMEOUT)
+		if err != nil {
+			log.Printf("[ERROR]: In telegram, error getting updates (err: %v)\n", err)
+
+			select {
+			case <-time.After(POLL_RETRY_DELAY):
+				continue
+			case <-stop:
+				return
+			}
+		}
+
+		for _, u := range batch {
+			offset = u.UpdateID + 1
+
+			select {
+			case updates <- u:
+			case <-stop:
+				return
+			}
+		}
+	}
+}
+
+// handleUpdate handles a single update
+func handleUpdate(api *telegramAPI, u Update) {
+	switch {
+	case u.Message != nil:
+		onMessage(api, u.Message)
+	case u.CallbackQuery != nil:
+		onAction(api, u.CallbackQuery)
+	}
+}
+
+// onMessage handles any message sent in an allowed chat
+func onMessage(api *telegramAPI, m *Message) {
+	// Ignore messages from bots, messages with 0 length, messages outside the allowed chats and
+	// users that can't talk to Horus
+	if (m.From != nil && m.From.IsBot) || len(m.Text) == 0 || !isAllowed(m.Chat.ID) || !permissions.canTalk(m.From) {
+		return
+	}
+
+	key := sessionKey(m)
+
+	// Handle commands
+	text := m.Text
+	if command, ok := parseCommand(text); ok {
+		switch command {
+		case "start":
+			sendText(api, m, "Hello! What do you need help with today?")
+			return
+
+		case "new":
+			// Start a new conversation in this chat
+			if _, err := bot.RotateSession(key); err != nil {
+				sendText(api, m, fmt.Sprintf("Sorry, an error occurred: %v", err))
+				return
+			}
+			sendText(api, m, "Started a new conversation.")
+			return
+		}
+	}
+
+	runner.Handle(common.Message{
+		Route:       common.Route{Session: key},
+		Target:      chatOf(m),
+		Text:        text,
+		Caller:      callerID(m.From),
+		Permissions: permissions.of(m.From),
+	})
+}
+
+/* ---- HELPERS ---- */
+
+// sessionKey returns the session key of the chat a message was sent in. Forum topics have
+// their own sessions
+func sessionKey(m *Message) string {
+	key := "telegram-" + strconv.FormatInt(m.Chat.ID, 10)
+	if m.IsTopicMessage && m.MessageThreadID != 0 {
+		key += "-" + strconv.FormatInt(m.MessageThreadID, 10)
+	}
+	return key
+}
+
+// callerID returns the caller ID of a Telegram user
+func callerID(u *User) string {
+	if u == nil {
+		return "telegram"
+	}
+	return "telegram-" + strconv.FormatInt(u.ID, 10)
+}
+
+// isAllowed returns whether messages in a chat should be handled. The outreach chat is always allowed
+func isAllowed(chatID int64) bool {
+	id := strconv.FormatInt(chatID, 10)
+	if id == OUTREACH_CHAT {
+		return true
+	}
+
+	for _, allowed := range ALLOWED_CHATS {
+		if id == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCommand returns the name of a bot command (ex: '/new@horus_bot' -> 'new')
+func parseCommand(text string) (string, bool) {
+	text, ok := strings.CutPrefix(text, "/")
+	if !ok {
+		return "", false
+	}
+
+	command, _, _ := strings.Cut(text, " ")
+	command, _, _ = strings.Cut(command, "@")
+	return strings.ToLower(command), true
+}
+
+// sendText sends plain text to the chat a message was sent in
+func sendText(api *telegramAPI, m *Message, text string) {
+	err := api.sendMessage(SendMessageRequest{
+		ChatID:          m.Chat.ID,
+		MessageThreadID: m.MessageThreadID,
+		Text:            text,
+	})
+	if err != nil {
+		log.Printf("[ERROR]: In telegram, error sending message (err: %v)\n", err)
+	}
+}
+
+// envOr returns an environment variable or a fallback if it is unset
+func envOr(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
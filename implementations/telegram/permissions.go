@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	horus "github.com/ethanbaker/horus/bot"
+)
+
+// userPermissions maps Telegram users to their permissions
+type userPermissions struct {
+	users    map[int64]byte // The permissions of each listed user
+	fallback byte           // The permissions of users that aren't listed
+}
+
+// parseUserPermissions parses a semicolon separated list of users and their permissions (ex:
+// "12345:all;67890:gpt,public") along with the permissions of users that aren't listed. Outreach
+// is sent to the bot's owner, so the user of a private outreach chat has every permission unless
+// they are listed
+func parseUserPermissions(users string, fallback string, outreachChat string) (userPermissions, error) {
+	p := userPermissions{users: map[int64]byte{}}
+
+	var err error
+	if p.fallback, err = horus.ParsePermissions(fallback); err != nil {
+		return userPermissions{}, fmt.Errorf("default permissions: %w", err)
+	}
+
+	// Private chats have the ID of their user, while group chats have negative IDs
+	if id, err := strconv.ParseInt(outreachChat, 10, 64); err == nil && id > 0 {
+		p.users[id] = horus.PERMISSIONS_ALL
+	}
+
+	listed := map[int64]bool{}
+	for _, entry := range strings.Split(users, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		user, permissions, ok := strings.Cut(entry, ":")
+		if !ok {
+			return userPermissions{}, fmt.Errorf("user '%v' has no permissions (ex: '%v:gpt,public')", entry, entry)
+		}
+
+		id, err := strconv.ParseInt(strings.TrimSpace(user), 10, 64)
+		if err != nil {
+			return userPermissions{}, fmt.Errorf("'%v' is not a Telegram user id", strings.TrimSpace(user))
+		} else if listed[id] {
+			return userPermissions{}, fmt.Errorf("user %v is listed more than once", id)
+		}
+		listed[id] = true
+
+		if p.users[id], err = horus.ParsePermissions(permissions); err != nil {
+			return userPermissions{}, fmt.Errorf("user %v: %w", id, err)
+		}
+	}
+
+	return p, nil
+}
+
+// of returns the permissions of a user. Messages without a user (ex: channel posts) have the
+// permissions of users that aren't listed
+func (p userPermissions) of(u *User) byte {
+	if u != nil {
+		if permissions, ok := p.users[u.ID]; ok {
+			return permissions
+		}
+	}
+	return p.fallback
+}
+
+// canTalk returns whether a user can talk to Horus
+func (p userPermissions) canTalk(u *User) bool {
+	return p.of(u)&horus.PERMISSIONS_GPT != 0
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ethanbaker/horus/implementations/common"
+	"github.com/ethanbaker/horus/utils/format"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- CONSTANTS -------- */
+
+// Callback data prefix for buttons created from output actions
+const ACTION_PREFIX = "horus-action:"
+
+// Telegram message limits
+const (
+	MAX_MESSAGE_LENGTH  = 4096
+	MAX_BUTTONS_PER_ROW = 4
+	MAX_CALLBACK_DATA   = 64
+)
+
+// The parse mode used for formatted messages
+const PARSE_MODE = "MarkdownV2"
+
+/* -------- IMPLEMENTATION -------- */
+
+// chat is where replies are sent: a chat and the forum topic in it, if any
+type chat struct {
+	id     int64
+	thread int64
+}
+
+// chatOf returns the chat a message was sent in
+func chatOf(m *Message) chat {
+	return chat{id: m.Chat.ID, thread: m.MessageThreadID}
+}
+
+// telegram sends outputs to Telegram chats. Targets are chats
+type telegram struct {
+	api *telegramAPI
+}
+
+// Name names the implementation
+func (t *telegram) Name() string {
+	return "telegram"
+}
+
+// Send sends an output to a chat
+func (t *telegram) Send(target any, output *types.Output) error {
+	c := target.(chat)
+	return sendOutput(t.api, c.id, c.thread, output)
+}
+
+// SendOutreach sends an outreach message to the outreach chat. Replies continue in the chat's
+// session
+func (t *telegram) SendOutreach(output *types.Output) (common.Route, error) {
+	chatID, err := strconv.ParseInt(OUTREACH_CHAT, 10, 64)
+	if err != nil {
+		return common.Route{}, fmt.Errorf("invalid outreach chat '%v': %w", OUTREACH_CHAT, err)
+	}
+
+	return common.Route{Session: "telegram-" + OUTREACH_CHAT}, sendOutput(t.api, chatID, 0, output)
+}
+
+/* -------- FUNCTIONS -------- */
+
+// sendOutput sends an output to a chat. The message and blocks are rendered as MarkdownV2,
+// attachments are sent as documents and actions are rendered as an inline keyboard
+func sendOutput(api *telegramAPI, chatID int64, threadID int64, resp *types.Output) error {
+	parts := []string{}
+	if resp.Message != "" {
+		parts = append(parts, resp.Message)
+	}
+	for _, b := range resp.Blocks {
+		parts = append(parts, format.RenderBlock(b))
+	}
+
+	// Send the text in chunks, attaching the keyboard to the last one
+	chunks := splitMessage(strings.Join(parts, "\n\n"), MAX_MESSAGE_LENGTH)
+	keyboard := renderActions(resp.Actions)
+	for i, chunk := range chunks {
+		req := SendMessageRequest{
+			ChatID:          chatID,
+			MessageThreadID: threadID,
+			Text:            format.Render(chunk, format.Telegram),
+			ParseMode:       PARSE_MODE,
+		}
+		if i == len(chunks)-1 {
+			req.ReplyMarkup = keyboard
+		}
+
+		// Fall back to plain text if the formatted text is rejected or too long once escaped
+		if err := api.sendMessage(req); err != nil {
+			log.Printf("[WARNING]: In telegram, sending plain text after formatted message failed (err: %v)\n", err)
+
+			req.Text = format.Render(chunk, format.Plain)
+			req.ParseMode = ""
+			if err := api.sendMessage(req); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Send files as documents
+	for _, file := range resp.Files() {
+		if err := api.sendDocument(chatID, threadID, file); err != nil {
+			return fmt.Errorf("cannot send file '%v': %w", file.Filename, err)
+		}
+	}
+
+	// Send the keyboard on its own if there was no text to attach it to
+	if len(chunks) == 0 && keyboard != nil {
+		return api.sendMessage(SendMessageRequest{
+			ChatID:          chatID,
+			MessageThreadID: threadID,
+			Text:            "Choose an option:",
+			ReplyMarkup:     keyboard,
+		})
+	}
+
+	return nil
+}
+
+// splitMessage splits markup into chunks of at most limit characters, preferring to split on
+// line boundaries
+func splitMessage(markup string, limit int) []string {
+	chunks := []string{}
+
+	var current strings.Builder
+	flush := func() {
+		if strings.TrimSpace(current.String()) != "" {
+			chunks = append(chunks, strings.TrimRight(current.String(), "\n"))
+		}
+		current.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(markup, "\n") {
+		if utf8.RuneCountInString(current.String())+utf8.RuneCountInString(line) > limit {
+			flush()
+		}
+
+		// Hard split lines that are longer than the limit
+		for utf8.RuneCountInString(line) > limit {
+			runes := []rune(line)
+			chunks = append(chunks, string(runes[:limit]))
+			line = string(runes[limit:])
+		}
+		current.WriteString(line)
+	}
+	flush()
+
+	return chunks
+}
+
+// renderActions renders actions as an inline keyboard
+func renderActions(actions []types.Action) *InlineKeyboardMarkup {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	keyboard := &InlineKeyboardMarkup{}
+
+	row := []InlineKeyboardButton{}
+	for _, a := range actions {
+		if len(row) == MAX_BUTTONS_PER_ROW {
+			keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, row)
+			row = []InlineKeyboardButton{}
+		}
+
+		// Callback data has a maximum length
+		data := ACTION_PREFIX + a.Value
+		if len(data) > MAX_CALLBACK_DATA {
+			data = truncate(data, MAX_CALLBACK_DATA)
+		}
+
+		row = append(row, InlineKeyboardButton{
+			Text:         a.Label,
+			CallbackData: data,
+		})
+	}
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, row)
+
+	return keyboard
+}
+
+// truncate truncates a string to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// onAction handles buttons created from output actions by sending the action's value to the
+// chat's conversation
+func onAction(api *telegramAPI, q *CallbackQuery) {
+	if !strings.HasPrefix(q.Data, ACTION_PREFIX) || q.Message == nil {
+		return
+	}
+	value := strings.TrimPrefix(q.Data, ACTION_PREFIX)
+
+	if err := api.answerCallbackQuery(q.ID); err != nil {
+		log.Printf("[ERROR]: In telegram, error responding to action (err: %v)\n", err)
+		return
+	}
+
+	// Remove the keyboard so the action can only be chosen once
+	if err := api.removeKeyboard(q.Message.Chat.ID, q.Message.MessageID); err != nil {
+		log.Printf("[ERROR]: In telegram, error removing action keyboard (err: %v)\n", err)
+	}
+
+	if !isAllowed(q.Message.Chat.ID) || !permissions.canTalk(&q.From) {
+		return
+	}
+
+	// Send the action to the chat's conversation
+	runner.Handle(common.Message{
+		Route:       common.Route{Session: sessionKey(q.Message)},
+		Target:      chatOf(q.Message),
+		Text:        value,
+		Caller:      callerID(&q.From),
+		Permissions: permissions.of(&q.From),
+	})
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	horus "github.com/ethanbaker/horus/bot"
+	"github.com/ethanbaker/horus/implementations/common"
+	"github.com/ethanbaker/horus/implementations/common/commontest"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBotAPI is a local Telegram Bot API server that records the requests it receives
+type fakeBotAPI struct {
+	mu       sync.Mutex
+	requests []fakeRequest
+	updates  []Update
+}
+
+// fakeRequest is a recorded Bot API request
+type fakeRequest struct {
+	Method   string
+	Params   map[string]any
+	Filename string
+	Content  string
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method, ok := strings.CutPrefix(r.URL.Path, "/botTOKEN/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": 404, "description": "Not Found"})
+		return
+	}
+
+	req := fakeRequest{Method: method, Params: map[string]any{}}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.ParseMultipartForm(1 << 20)
+		for key, values := range r.MultipartForm.Value {
+			req.Params[key] = values[0]
+		}
+		file, header, _ := r.FormFile("document")
+		content, _ := io.ReadAll(file)
+		req.Filename, req.Content = header.Filename, string(content)
+	} else {
+		json.NewDecoder(r.Body).Decode(&req.Params)
+	}
+
+	// Wait a little before returning no updates instead of long polling
+	if method == "getUpdates" {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+
+	// Reject malformed MarkdownV2 like the real API
+	if text, _ := req.Params["text"].(string); req.Params["parse_mode"] == PARSE_MODE && strings.Contains(text, "bad") {
+		json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": 400, "description": "Bad Request: can't parse entities"})
+		return
+	}
+
+	var result any = true
+	if method == "getUpdates" {
+		result, f.updates = f.updates, []Update{}
+	}
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+// sent returns the recorded requests of a method
+func (f *fakeBotAPI) sent(method string) []fakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	requests := []fakeRequest{}
+	for _, r := range f.requests {
+		if r.Method == method {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// setup starts a fake Bot API server and installs a fake Horus bot
+func setup(t *testing.T) (*fakeBotAPI, *telegramAPI, *commontest.Horus) {
+	fake := &fakeBotAPI{}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	h := commontest.New()
+	h.Replies["file"] = &types.Output{Message: "Here you go", Attachments: []types.FileOutput{{Filename: "notes.txt", Content: []byte("hello")}}}
+	h.Replies["choose"] = &types.Output{Message: "Pick one", Actions: []types.Action{{Label: "Yes", Value: "yes"}, {Label: "No", Value: "no"}}}
+	api := newTelegramAPI(server.URL, "TOKEN")
+	bot = h
+	runner = common.NewRunner(h, &telegram{api: api})
+	ALLOWED_CHATS = []string{"10"}
+	OUTREACH_CHAT = "20"
+	permissions = userPermissions{users: map[int64]byte{5: horus.PERMISSIONS_ALL, 6: horus.PERMISSIONS_NONE}, fallback: horus.PERMISSIONS_GPT | horus.PERMISSIONS_PUBMODULES}
+
+	return fake, api, h
+}
+
+func message(chatID int64, text string) *Message {
+	return &Message{MessageID: 1, From: &User{ID: 5}, Chat: Chat{ID: chatID}, Text: text}
+}
+
+func TestMessage(t *testing.T) {
+	assert := assert.New(t)
+	fake, api, h := setup(t)
+
+	onMessage(api, message(10, "hi."))
+
+	sent := fake.sent("sendMessage")
+	assert.Len(sent, 1)
+	assert.Equal(float64(10), sent[0].Params["chat_id"])
+	assert.Equal(`*echo*: hi\.`, sent[0].Params["text"])
+	assert.Equal(PARSE_MODE, sent[0].Params["parse_mode"])
+	assert.Equal([]string{"telegram-5: hi."}, h.Messages["telegram-10#0"])
+
+	// Messages outside of the allowed chats are ignored
+	onMessage(api, message(11, "hi"))
+	assert.Len(fake.sent("sendMessage"), 1)
+
+	// Forum topics have their own conversation
+	topic := message(10, "hi")
+	topic.IsTopicMessage, topic.MessageThreadID = true, 7
+	onMessage(api, topic)
+	assert.Len(h.Messages["telegram-10-7#0"], 1)
+	assert.Equal(float64(7), fake.sent("sendMessage")[1].Params["message_thread_id"])
+}
+
+func TestPermissions(t *testing.T) {
+	assert := assert.New(t)
+	fake, api, h := setup(t)
+
+	// Listed users have their own permissions, and other users have the default ones
+	onMessage(api, message(10, "permissions"))
+	other := message(10, "permissions")
+	other.From.ID = 7
+	onMessage(api, other)
+
+	sent := fake.sent("sendMessage")
+	assert.Len(sent, 2)
+	assert.Equal("permissions: 11111111", sent[0].Params["text"])
+	assert.Equal("permissions: 00000101", sent[1].Params["text"])
+
+	// Users that can't talk to Horus are ignored, along with their actions and commands
+	blocked := message(10, "hi")
+	blocked.From.ID = 6
+	onMessage(api, blocked)
+	blocked.Text = "/new"
+	onMessage(api, blocked)
+	onAction(api, &CallbackQuery{ID: "q", From: User{ID: 6}, Message: message(10, "Pick one"), Data: "horus-action:yes"})
+	assert.Len(fake.sent("sendMessage"), 2)
+	assert.Zero(h.Sessions["telegram-10"])
+	assert.Len(h.Messages["telegram-10#0"], 2)
+}
+
+func TestParseUserPermissions(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := parseUserPermissions(" 5:all; 6:gpt, private ;", "gpt,public", "")
+	assert.Nil(err)
+	assert.Equal(byte(horus.PERMISSIONS_ALL), p.of(&User{ID: 5}))
+	assert.Equal(byte(horus.PERMISSIONS_GPT|horus.PERMISSIONS_PRVMODULES), p.of(&User{ID: 6}))
+	assert.Equal(byte(horus.PERMISSIONS_GPT|horus.PERMISSIONS_PUBMODULES), p.of(&User{ID: 7}))
+	assert.Equal(byte(horus.PERMISSIONS_GPT|horus.PERMISSIONS_PUBMODULES), p.of(nil))
+
+	// The user of a private outreach chat has every permission unless they are listed
+	p, err = parseUserPermissions("6:gpt", "none", "20")
+	assert.Nil(err)
+	assert.Equal(byte(horus.PERMISSIONS_ALL), p.of(&User{ID: 20}))
+	assert.Equal(byte(horus.PERMISSIONS_GPT), p.of(&User{ID: 6}))
+	assert.False(p.canTalk(&User{ID: 7}))
+
+	p, err = parseUserPermissions("20:gpt", "none", "20")
+	assert.Nil(err)
+	assert.Equal(byte(horus.PERMISSIONS_GPT), p.of(&User{ID: 20}))
+
+	// Group outreach chats don't give anyone permissions
+	p, err = parseUserPermissions("", "none", "-100")
+	assert.Nil(err)
+	assert.Empty(p.users)
+
+	for _, users := range []string{"5", "five:all", "5:all;5:gpt", "5:everything"} {
+		_, err = parseUserPermissions(users, "gpt", "")
+		assert.NotNil(err, users)
+	}
+	_, err = parseUserPermissions("", "gpt,admin", "")
+	assert.ErrorContains(err, "default permissions: unknown permission 'admin'")
+}
+
+func TestErrors(t *testing.T) {
+	assert := assert.New(t)
+	fake, api, _ := setup(t)
+
+	onMessage(api, message(10, "fail"))
+	sent := fake.sent("sendMessage")
+	assert.Len(sent, 1)
+	assert.Equal("Sorry, an error occurred: failed", sent[0].Params["text"])
+
+	// Rejected formatting falls back to plain text
+	onMessage(api, message(10, "bad"))
+	sent = fake.sent("sendMessage")
+	assert.Len(sent, 3)
+	assert.Equal("echo: bad", sent[2].Params["text"])
+	assert.Nil(sent[2].Params["parse_mode"])
+}
+
+func TestCommands(t *testing.T) {
+	assert := assert.New(t)
+	fake, api, h := setup(t)
+
+	onMessage(api, message(10, "/new@horus_bot"))
+	assert.Equal(1, h.Sessions["telegram-10"])
+	assert.Equal("Started a new conversation.", fake.sent("sendMessage")[0].Params["text"])
+
+	onMessage(api, message(10, "hello"))
+	assert.Len(h.Messages["telegram-10#1"], 1)
+}
+
+func TestFiles(t *testing.T) {
+	assert := assert.New(t)
+	fake, api, _ := setup(t)
+
+	onMessage(api, message(10, "file"))
+
+	documents := fake.sent("sendDocument")
+	assert.Len(documents, 1)
+	assert.Equal("10", documents[0].Params["chat_id"])
+	assert.Equal("notes.txt", documents[0].Filename)
+	assert.Equal("hello", documents[0].Content)
+}
+
+func TestActions(t *testing.T) {
+	assert := assert.New(t)
+	fake, api, h := setup(t)
+
+	onMessage(api, message(10, "choose"))
+
+	sent := fake.sent("sendMessage")
+	assert.Len(sent, 1)
+	markup, _ := json.Marshal(sent[0].Params["reply_markup"])
+	assert.JSONEq(`{"inline_keyboard":[[{"text":"Yes","callback_data":"horus-action:yes"},{"text":"No","callback_data":"horus-action:no"}]]}`, string(markup))
+
+	// Choosing an action sends its value to the conversation and removes the keyboard
+	onAction(api, &CallbackQuery{ID: "q", From: User{ID: 5}, Message: message(10, "Pick one"), Data: "horus-action:yes"})
+	assert.Len(fake.sent("answerCallbackQuery"), 1)
+	assert.Len(fake.sent("editMessageReplyMarkup"), 1)
+	assert.Equal("telegram-5: yes", h.Messages["telegram-10#0"][1])
+	assert.Len(fake.sent("sendMessage"), 2)
+}
+
+func TestOutreach(t *testing.T) {
+	assert := assert.New(t)
+	fake, _, h := setup(t)
+
+	runner.Outreach("Good <EM>morning<EM>!")
+
+	sent := fake.sent("sendMessage")
+	assert.Len(sent, 1)
+	assert.Equal(float64(20), sent[0].Params["chat_id"])
+	assert.Equal(`Good _morning_\!`, sent[0].Params["text"])
+	assert.Equal([]string{"assistant: Good <EM>morning<EM>!"}, h.Messages["telegram-20#1"])
+}
+
+func TestPoll(t *testing.T) {
+	assert := assert.New(t)
+	fake, api, _ := setup(t)
+	fake.updates = []Update{{UpdateID: 3, Message: message(10, "polled")}}
+
+	stop := make(chan struct{})
+	ch := make(chan string)
+	go run(api, ch, stop)
+	defer close(stop)
+
+	assert.Eventually(func() bool { return len(fake.sent("sendMessage")) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Eventually(func() bool { return len(fake.sent("getUpdates")) > 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(float64(4), fake.sent("getUpdates")[1].Params["offset"])
+}
+
+func TestSplitMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"abc\ndef"}, splitMessage("abc\ndef", 10))
+	assert.Equal([]string{"abc", "defgh"}, splitMessage("abc\ndefgh", 6))
+	assert.Equal([]string{"abcd", "ef"}, splitMessage("abcdef", 4))
+	assert.Equal([]string{}, splitMessage("", 4))
+}
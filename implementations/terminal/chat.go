@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/format"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/peterh/liner"
+)
+
+/* -------- CONSTANTS -------- */
+
+const CHAT_HELP = `Type a message to send it to the bot, or the number of a suggested action to choose it.
+
+Commands:
+  /new [conversation]   Start a new conversation
+  /use <conversation>   Switch to another conversation
+  /list                 List the bot's conversations
+  /history              Show the current conversation's messages
+  /help                 Show this help
+  /quit                 Stop chatting
+`
+
+/* -------- TYPES -------- */
+
+// lineReader reads lines of input
+type lineReader interface {
+	Prompt(prompt string) (string, error)
+	AppendHistory(line string)
+	Close() error
+}
+
+// editor reads lines from a terminal with line editing and history
+type editor struct {
+	*liner.State
+	history string // The file history is kept in
+}
+
+// Close saves the history and restores the terminal
+func (e *editor) Close() error {
+	if e.history != "" {
+		if err := os.MkdirAll(filepath.Dir(e.history), 0700); err == nil {
+			if f, err := os.OpenFile(e.history, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600); err == nil {
+				e.WriteHistory(f)
+				f.Close()
+			}
+		}
+	}
+	return e.State.Close()
+}
+
+// scanner reads lines from input that is not a terminal
+type scanner struct {
+	*bufio.Scanner
+	out io.Writer
+}
+
+// Prompt writes the prompt and reads a line
+func (s *scanner) Prompt(prompt string) (string, error) {
+	fmt.Fprint(s.out, prompt)
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return s.Text(), nil
+}
+
+func (s *scanner) AppendHistory(line string) {}
+func (s *scanner) Close() error              { return nil }
+
+/* -------- FUNCTIONS -------- */
+
+// newLineReader creates a line reader for the input. Line editing is only used for terminals
+func (t *terminal) newLineReader(stdin io.Reader) lineReader {
+	if f, ok := stdin.(*os.File); ok && f == os.Stdin && liner.TerminalSupported() && t.out == io.Writer(os.Stdout) {
+		e := &editor{State: liner.NewLiner(), history: t.config.History}
+		e.SetCtrlCAborts(true)
+		t.interactive = true
+
+		if e.history != "" {
+			if f, err := os.Open(e.history); err == nil {
+				e.ReadHistory(f)
+				f.Close()
+			}
+		}
+		return e
+	}
+
+	return &scanner{Scanner: bufio.NewScanner(stdin), out: t.out}
+}
+
+// chat chats with the bot interactively. Outreach messages are printed as they arrive
+func (t *terminal) chat(ctx context.Context, stdin io.Reader) error {
+	if err := t.ensureConversation(ctx, t.config.Conversation); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Print outreach messages while chatting
+	if t.config.Outreach != "" {
+		messages, err := t.client.SubscribeOutreach(ctx, types.OutreachMethod(t.config.Outreach))
+		if err != nil {
+			t.printf("Not receiving outreach messages: %v\n", err)
+		} else {
+			go func() {
+				for m := range messages {
+					t.notify(m)
+				}
+			}()
+		}
+	}
+
+	r := t.newLineReader(stdin)
+	defer r.Close()
+
+	t.printf("Chatting in '%v'. Type /help for help\n", t.config.Conversation)
+
+	actions := []types.Action{}
+	for {
+		line, err := t.prompt(r, t.config.Conversation+"> ")
+		if errors.Is(err, io.EOF) || errors.Is(err, liner.ErrPromptAborted) {
+			t.printf("\n")
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		r.AppendHistory(line)
+
+		// Handle chat commands
+		if strings.HasPrefix(line, "/") {
+			quit, err := t.command(ctx, line)
+			if err != nil {
+				t.printf("Error: %v\n", err)
+			}
+			if quit {
+				return nil
+			}
+			continue
+		}
+
+		// Numbers choose one of the last reply's actions
+		if i, err := strconv.Atoi(line); err == nil && i >= 1 && i <= len(actions) {
+			line = actions[i-1].Value
+		}
+
+		output, err := t.bot.SendMessage(ctx, t.config.Conversation, &types.Input{Message: line})
+		if err != nil {
+			t.printf("Error: %v\n", err)
+			continue
+		} else if output.Error != nil {
+			t.printf("Error: %v\n", output.Error)
+			continue
+		}
+
+		t.printOutput(output)
+		actions = output.Actions
+	}
+}
+
+// command runs a chat command and returns whether the chat should stop
+func (t *terminal) command(ctx context.Context, line string) (bool, error) {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "/quit", "/exit":
+		return true, nil
+
+	case "/help":
+		t.printf("%v", CHAT_HELP)
+
+	case "/new":
+		name := t.config.Conversation + "-" + time.Now().Format("20060102-150405")
+		if len(fields) > 1 {
+			name = fields[1]
+		}
+		if err := t.bot.AddConversation(ctx, name); err != nil {
+			return false, err
+		}
+		t.config.Conversation = name
+		t.printf("Started conversation '%v'\n", name)
+
+	case "/use":
+		if len(fields) < 2 {
+			return false, fmt.Errorf("no conversation given")
+		}
+		exists, err := t.bot.IsConversation(ctx, fields[1])
+		if err != nil {
+			return false, err
+		} else if !exists {
+			return false, fmt.Errorf("conversation '%v' does not exist", fields[1])
+		}
+		t.config.Conversation = fields[1]
+		t.printf("Switched to conversation '%v'\n", fields[1])
+
+	case "/list":
+		return false, t.conversations(ctx, []string{"list"})
+
+	case "/history":
+		messages, err := t.bot.History(ctx, t.config.Conversation)
+		if err != nil {
+			return false, err
+		}
+		for _, m := range messages {
+			if m.Content == "" {
+				continue
+			}
+			t.printf("%v: %v\n", format.Render("<STRONG>"+format.Escape(m.Role)+"<STRONG>", format.ANSI), format.Render(m.Content, format.ANSI))
+		}
+
+	default:
+		return false, fmt.Errorf("unknown command '%v' (type /help for help)", fields[0])
+	}
+
+	return false, nil
+}
+
+// prompt reads a line, remembering the prompt so notifications can redraw it
+func (t *terminal) prompt(r lineReader, prompt string) (string, error) {
+	t.mu.Lock()
+	t.prompting = prompt
+	t.mu.Unlock()
+
+	line, err := r.Prompt(prompt)
+
+	t.mu.Lock()
+	t.prompting = ""
+	t.mu.Unlock()
+
+	return line, err
+}
+
+// notify prints an outreach message above the prompt
+func (t *terminal) notify(m types.APIOutreach) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	message := format.Render("<STRONG>[outreach]<STRONG> ", format.ANSI) + format.Render(m.Message, format.ANSI)
+	if t.prompting == "" || !t.interactive {
+		fmt.Fprintln(t.out, message)
+		return
+	}
+
+	// The terminal is in raw mode while prompting, so lines need carriage returns. The input
+	// typed so far is redrawn on the next key press
+	fmt.Fprint(t.out, "\r\033[2K"+strings.ReplaceAll(message, "\n", "\r\n")+"\r\n"+t.prompting)
+}
+
+// printf prints to the output
+func (t *terminal) printf(layout string, a ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.out, layout, a...)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+/* -------- TYPES -------- */
+
+// Config configures the terminal client. Values are read from the config file, then from the
+// environment and then from flags, with later sources taking precedence
+type Config struct {
+	URL          string `yaml:"url"`          // The Horus API server to connect to
+	APIKey       string `yaml:"api_key"`      // The key issued to the terminal (see horus-server keys)
+	Bot          string `yaml:"bot"`          // The bot to talk to
+	Conversation string `yaml:"conversation"` // The conversation chat and send use by default
+	Outreach     string `yaml:"outreach"`     // The outreach method printed while chatting (empty to disable)
+	History      string `yaml:"history"`      // The file the chat's input history is kept in (empty to disable)
+	Downloads    string `yaml:"downloads"`    // The directory files sent by the bot are saved to (empty to disable)
+}
+
+/* -------- FUNCTIONS -------- */
+
+// defaultConfig returns the configuration used when nothing else is set
+func defaultConfig() Config {
+	config := Config{
+		URL:          "http://localhost:8080",
+		Bot:          "horus-main",
+		Conversation: "terminal",
+		Outreach:     "terminal",
+	}
+
+	if dir, err := configDir(); err == nil {
+		config.History = filepath.Join(dir, "history")
+	}
+
+	return config
+}
+
+// configDir returns the directory the terminal's files are kept in by default
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "horus"), nil
+}
+
+// loadConfig reads a config file over the defaults and applies environment overrides. A missing
+// config file is only an error if it was given explicitly
+func loadConfig(path string) (Config, error) {
+	config := defaultConfig()
+
+	explicit := path != ""
+	if !explicit {
+		if dir, err := configDir(); err == nil {
+			path = filepath.Join(dir, "terminal.yaml")
+		}
+	}
+
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil && (explicit || !errors.Is(err, fs.ErrNotExist)) {
+			return config, err
+		}
+		if err == nil {
+			if err = yaml.Unmarshal(raw, &config); err != nil {
+				return config, err
+			}
+		}
+	}
+
+	// Apply environment overrides
+	for env, field := range map[string]*string{
+		"HORUS_API_URL":      &config.URL,
+		"HORUS_API_KEY":      &config.APIKey,
+		"HORUS_BOT":          &config.Bot,
+		"HORUS_CONVERSATION": &config.Conversation,
+	} {
+		if value := os.Getenv(env); value != "" {
+			*field = value
+		}
+	}
+
+	return config, nil
+}
@@ -1,28 +1,36 @@
 module github.com/ethanbaker/horus/implementations/terminal
 
-replace github.com/ethanbaker/horus/bot => ../../bot
+replace github.com/ethanbaker/horus/client => ../../client
 
 replace github.com/ethanbaker/horus/utils => ../../utils
 
 go 1.20
 
 require (
-	github.com/ethanbaker/horus/bot v0.0.0-00010101000000-000000000000
+	github.com/ethanbaker/horus/client v0.0.0-00010101000000-000000000000
 	github.com/ethanbaker/horus/utils v0.0.0-00010101000000-000000000000
-	github.com/go-sql-driver/mysql v1.8.1
-	github.com/sashabaranov/go-openai v1.22.0
+	github.com/gdamore/tcell/v2 v2.7.4
+	github.com/mattn/go-runewidth v0.0.15
+	github.com/peterh/liner v1.2.2
+	github.com/stretchr/testify v1.9.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/bwmarrin/discordgo v0.28.1 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/gdamore/encoding v1.0.0 // indirect
 	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rivo/uniseg v0.4.3 // indirect
 	github.com/robfig/cron/v3 v3.0.1 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	golang.org/x/crypto v0.0.0-20210421170649-83a5a9bb288b // indirect
-	golang.org/x/sys v0.0.0-20201119102817-f84b799fce68 // indirect
-	gorm.io/driver/mysql v1.5.6 // indirect
-	gorm.io/gorm v1.25.9 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/term v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	gorm.io/gorm v1.25.10 // indirect
 )
@@ -1,213 +1,221 @@
-// This file is a test-runner to directly test the horus 'Bot' object
+// The horus command is a terminal client for a Horus bot served by the Horus API (see /server)
 package main
 
 import (
-	"bufio"
+	"context"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"strings"
-	"time"
+	"sync"
+	"text/tabwriter"
 
-	horus "github.com/ethanbaker/horus/bot"
-	module_ambient "github.com/ethanbaker/horus/bot/module_ambient"
-	module_config "github.com/ethanbaker/horus/bot/module_config"
-	module_keepass "github.com/ethanbaker/horus/bot/module_keepass"
+	"github.com/ethanbaker/horus/client"
 	"github.com/ethanbaker/horus/utils/types"
-	mysql_driver "github.com/go-sql-driver/mysql"
-	"github.com/sashabaranov/go-openai"
+	"github.com/gdamore/tcell/v2"
 )
 
 /* -------- CONSTANTS -------- */
 
-const ASK_PROMPT = `Select an action:
-1 - Add Conversation
-2 - Remove Conversation
-3 - Send Message
-4 - Send Multiple Messages
-5 - Quit
-`
-
-const INTERACTABLE = false
+const USAGE = `Usage: horus [flags] <command> [args]
 
-/* -------- GLOBALS -------- */
-
-// The config for the SQL database
-var config = mysql_driver.Config{
-	User:      "horus_library_test",
-	Passwd:    "X4UMTqprnJqPOxnLntG8qhIg4ezDcu3s",
-	Net:       "tcp",
-	Addr:      "127.0.0.1:3306",
-	DBName:    "horus_library_test",
-	ParseTime: true,
-	Loc:       time.Local,
-}
+Commands:
+  chat [conversation]                  Chat with the bot interactively
+  tui [conversation]                   Chat in a full-screen interface with a conversation sidebar
+  send [-c conversation] <message>     Send a single message and print the reply
+  conversations list                   List the bot's conversations
+  conversations new <conversation>     Create a conversation
+  conversations rm <conversation>      Delete a conversation
 
-// The OpenAI client
-var client *openai.Client
-
-// The horus bot
-var bot *horus.Bot
-
-// Scanner to read user input
-var scanner *bufio.Scanner
+Flags:
+`
 
-/* -------- METHODS -------- */
+/* -------- MAIN -------- */
 
-func addConversation(name string) {
-	// Create the new conversation
-	if err := bot.AddConversation(name); err != nil {
-		fmt.Printf("Error adding conversation: %v\n", err.Error())
-	} else {
-		fmt.Println("Successfully created conversation!")
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "horus: %v\n", err)
+		os.Exit(1)
 	}
 }
 
-func removeConversation(name string) {
-	// Create the new conversation
-	if err := bot.DeleteConversation(name); err != nil {
-		fmt.Printf("Error removing conversation: %v\n", err.Error())
-	} else {
-		fmt.Println("Successfully removed conversation!")
+// run parses flags and runs a command
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("horus", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), USAGE)
+		flags.PrintDefaults()
 	}
-}
 
-func sendMessage(name string, content string) {
-	// Send the message
-	output, err := bot.SendMessage(name, &types.Input{
-		Message: content,
-	})
+	configPath := flags.String("config", "", "path to the config file (default $XDG_CONFIG_HOME/horus/terminal.yaml)")
+	url := flags.String("url", "", "the Horus API server to connect to")
+	key := flags.String("key", "", "the API key to authenticate with")
+	botName := flags.String("bot", "", "the bot to talk to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
 
+	config, err := loadConfig(*configPath)
 	if err != nil {
-		fmt.Printf("Error sending message: %v\n", err.Error())
-	} else {
-		fmt.Println(output.Message)
+		return fmt.Errorf("cannot load config: %w", err)
 	}
-}
-
-func sendMultiMessage(name string) {
-	for content := ""; content != "stop"; content = scanner.Text() {
-		// Send the message
-		output, err := bot.SendMessage(name, &types.Input{
-			Message: content,
-		})
 
-		if err != nil {
-			fmt.Printf("Error sending message: %v\n", err.Error())
-		} else {
-			fmt.Println(output.Message)
-		}
-
-		scanner.Scan()
+	// Flags take precedence over the config file and environment
+	if *url != "" {
+		config.URL = *url
 	}
-}
-
-/* -------- MAIN -------- */
-
-func main() {
-	var err error
-
-	// Initialize the SQl
-	if err = horus.InitSQL(config.FormatDSN()); err != nil {
-		log.Fatal(err)
+	if *key != "" {
+		config.APIKey = *key
 	}
-
-	// Create the OpenAI client
-	client = openai.NewClient(os.Getenv("OPENAI_TOKEN"))
-
-	// Try to get a bot that we've already created
-	bot, err = horus.GetBotByName("horus-testing")
-	if err != nil {
-		log.Fatal(err)
+	if *botName != "" {
+		config.Bot = *botName
 	}
 
-	// If the bot is nil, we need to create one
-	if bot == nil {
-		bot, err = horus.NewBot("horus-testing", horus.PERMISSIONS_ALL)
-		if err != nil {
-			log.Fatal(err)
-		}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("no command given")
 	}
 
-	// Setup the bot
-	module_ambient.NewModule(bot, true)
-	module_config.NewModule(bot, true)
-	module_keepass.NewModule(bot, true)
-	bot.Setup(client)
+	// Connect to the API server
+	c := client.New(config.URL)
+	c.APIKey = config.APIKey
+	t := &terminal{
+		config: config,
+		client: c,
+		bot:    c.Bot(config.Bot),
+		out:    stdout,
+	}
 
-	// Read user input
-	scanner = bufio.NewScanner(os.Stdin)
-	for INTERACTABLE {
-		fmt.Print(ASK_PROMPT)
+	ctx := context.Background()
+	command, args := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "chat":
+		if len(args) > 0 {
+			t.config.Conversation = args[0]
+		}
+		return t.chat(ctx, stdin)
 
-		// Get the user's respose
-		scanner.Scan()
-		text := scanner.Text()
-		fmt.Println()
+	case "tui":
+		if len(args) > 0 {
+			t.config.Conversation = args[0]
+		}
+		screen, err := tcell.NewScreen()
+		if err != nil {
+			return fmt.Errorf("cannot open the terminal: %w", err)
+		}
+		return t.newTUI(ctx, screen).run()
 
-		// Perform an action based on the user's choice
-		switch text {
-		case "1":
-			// Get the conversation name
-			fmt.Print("Enter the conversation's name: ")
+	case "send":
+		return t.send(ctx, args)
 
-			scanner.Scan()
-			name := scanner.Text()
+	case "conversations", "convos":
+		return t.conversations(ctx, args)
 
-			addConversation(name)
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown command '%v'", command)
+	}
+}
 
-		case "2":
-			// Get the conversation name
-			fmt.Print("Enter the conversation's name to delete: ")
+/* -------- COMMANDS -------- */
 
-			scanner.Scan()
-			name := scanner.Text()
+// terminal runs commands against a bot
+type terminal struct {
+	config Config
+	client *client.Client
+	bot    *client.Bot
 
-			removeConversation(name)
+	mu          sync.Mutex // Serializes writes to the output
+	out         io.Writer
+	interactive bool   // Whether input is read from a terminal with line editing
+	prompting   string // The prompt being shown while waiting for input, if any
+}
 
-		case "3":
-			// Get the conversation name to send a message to
-			fmt.Print("Enter the conversation's name: ")
+// send sends a single message and prints the reply
+func (t *terminal) send(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("send", flag.ContinueOnError)
+	flags.SetOutput(t.out)
+	conversation := flags.String("c", t.config.Conversation, "the conversation to send the message in")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
 
-			scanner.Scan()
-			name := scanner.Text()
+	message := strings.Join(flags.Args(), " ")
+	if message == "" {
+		return fmt.Errorf("no message given")
+	}
 
-			// Get the message to send to Horus
-			fmt.Printf("Enter your message content: ")
+	if err := t.ensureConversation(ctx, *conversation); err != nil {
+		return err
+	}
 
-			scanner.Scan()
-			content := strings.TrimSpace(scanner.Text())
+	output, err := t.bot.SendMessage(ctx, *conversation, &types.Input{Message: message})
+	if err != nil {
+		return err
+	} else if output.Error != nil {
+		return output.Error
+	}
 
-			fmt.Printf("%#v\n", content)
+	t.printOutput(output)
+	return nil
+}
 
-			sendMessage(name, content)
+// conversations lists, creates and deletes conversations
+func (t *terminal) conversations(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
 
-		case "4":
-			// Get the conversation name to send a message to
-			fmt.Print("Enter the conversation's name: ")
+	switch args[0] {
+	case "list", "ls":
+		conversations, err := t.bot.Conversations(ctx)
+		if err != nil {
+			return err
+		}
 
-			scanner.Scan()
-			name := scanner.Text()
+		w := tabwriter.NewWriter(t.out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CONVERSATION\tMESSAGES\tCREATED\tSTATUS")
+		for _, c := range conversations {
+			status := "active"
+			if c.ArchivedAt != nil {
+				status = "archived"
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", c.Key, c.Messages, c.CreatedAt.Local().Format("2006-01-02 15:04"), status)
+		}
+		return w.Flush()
 
-			fmt.Printf("Now chatting under conversation '%v'. Type 'stop' to stop\n", name)
-			sendMultiMessage(name)
+	case "new", "add":
+		if len(args) < 2 {
+			return fmt.Errorf("no conversation given")
+		}
+		if err := t.bot.AddConversation(ctx, args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(t.out, "Created conversation '%v'\n", args[1])
+		return nil
 
-		case "5":
-			return
+	case "rm", "delete":
+		if len(args) < 2 {
+			return fmt.Errorf("no conversation given")
 		}
+		if err := t.bot.DeleteConversation(ctx, args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(t.out, "Deleted conversation '%v'\n", args[1])
+		return nil
 
-		fmt.Println()
+	default:
+		return fmt.Errorf("unknown conversations command '%v'", args[0])
 	}
+}
 
-	// Do debug testing here
-	removeConversation("test-003")
-	addConversation("test-003")
-	sendMessage("test-003", "Create a new keepass password")
-	sendMessage("test-003", "testing")
-	sendMessage("test-003", "/path/")
-	sendMessage("test-003", "username")
-	sendMessage("test-003", "password")
-	sendMessage("test-003", "url")
-	sendMessage("test-003", "notes")
-	sendMessage("test-003", "yes")
+// ensureConversation creates a conversation if it does not exist
+func (t *terminal) ensureConversation(ctx context.Context, name string) error {
+	exists, err := t.bot.IsConversation(ctx, name)
+	if err != nil || exists {
+		return err
+	}
+	return t.bot.AddConversation(ctx, name)
 }
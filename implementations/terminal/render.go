@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethanbaker/horus/utils/format"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+// printOutput prints an output to the terminal. Tables are drawn with box characters, cards
+// and lists are indented and actions are shown as numbered suggestions. Files are saved to the
+// downloads directory if one is configured
+func (t *terminal) printOutput(output *types.Output) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	writeOutput(t.out, output)
+
+	for _, file := range output.Files() {
+		fmt.Fprintf(t.out, "\n[file] %v (%v bytes)", format.StripControl(file.Filename), len(file.Content))
+		if t.config.Downloads != "" {
+			if path, err := saveFile(t.config.Downloads, file); err != nil {
+				fmt.Fprintf(t.out, " could not be saved: %v", err)
+			} else {
+				fmt.Fprintf(t.out, " saved to %v", path)
+			}
+		}
+		fmt.Fprintln(t.out)
+	}
+
+	if len(output.Actions) > 0 {
+		fmt.Fprintln(t.out)
+		for i, a := range output.Actions {
+			fmt.Fprintf(t.out, "  [%v] %v\n", i+1, format.StripControl(a.Label))
+		}
+	}
+}
+
+// writeOutput writes an output's message and blocks
+func writeOutput(w io.Writer, output *types.Output) {
+	if output.Message != "" {
+		fmt.Fprintln(w, format.Render(output.Message, format.ANSI))
+	}
+
+	for _, b := range output.Blocks {
+		// Skip blocks without content
+		if format.RenderBlock(b) == "" {
+			continue
+		}
+
+		fmt.Fprintln(w)
+		if b.Title != "" {
+			fmt.Fprintln(w, format.Render("<STRONG>"+b.Title+"<STRONG>", format.ANSI))
+		}
+
+		switch b.Type {
+		case types.TableBlock:
+			if len(b.Columns) > 0 || len(b.Rows) > 0 {
+				fmt.Fprintln(w, renderBoxTable(b.Columns, b.Rows))
+			}
+
+		case types.CardBlock:
+			for _, f := range b.Fields {
+				fmt.Fprintf(w, "  %v: %v\n", format.Render(f.Name, format.ANSI), format.Render(f.Value, format.ANSI))
+			}
+
+		default:
+			// Text and lists are already readable as plain text
+			b.Title = ""
+			for _, line := range strings.Split(format.Render(format.RenderBlock(b), format.ANSI), "\n") {
+				fmt.Fprintln(w, "  "+line)
+			}
+		}
+	}
+}
+
+// saveFile saves a file to a directory and returns its path
+func saveFile(dir string, file types.FileOutput) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, filepath.Base(file.Filename))
+	return path, os.WriteFile(path, file.Content, 0600)
+}
+
+// renderBoxTable draws a table with box drawing characters. Control characters are removed from
+// every cell, like they are from rendered markup
+func renderBoxTable(columns []string, rows [][]string) string {
+	columns = stripCells(columns)
+	stripped := make([][]string, len(rows))
+	for i, row := range rows {
+		stripped[i] = stripCells(row)
+	}
+	rows = stripped
+
+	widths := format.ColumnWidths(columns, rows)
+
+	border := func(left, middle, right string) string {
+		parts := make([]string, len(widths))
+		for i, w := range widths {
+			parts[i] = strings.Repeat("─", w+2)
+		}
+		return left + strings.Join(parts, middle) + right
+	}
+
+	line := func(cells []string) string {
+		parts := make([]string, len(widths))
+		for i, w := range widths {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			parts[i] = " " + cell + strings.Repeat(" ", w-len([]rune(cell))) + " "
+		}
+		return "│" + strings.Join(parts, "│") + "│"
+	}
+
+	// Tables without column names only show their rows
+	lines := []string{border("┌", "┬", "┐")}
+	if len(columns) > 0 {
+		lines = append(lines, line(columns), border("├", "┼", "┤"))
+	}
+	for _, row := range rows {
+		lines = append(lines, line(row))
+	}
+	lines = append(lines, border("└", "┴", "┘"))
+
+	return strings.Join(lines, "\n")
+}
+
+// stripCells removes control characters from a row of cells. Newlines and tabs are replaced with
+// spaces so they can't break the table's lines
+func stripCells(cells []string) []string {
+	stripped := make([]string, len(cells))
+	for i, cell := range cells {
+		stripped[i] = strings.NewReplacer("\n", " ", "\t", " ").Replace(format.StripControl(cell))
+	}
+	return stripped
+}
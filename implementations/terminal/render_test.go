@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// The path of the golden file holding the expected rendering of each output
+const EXPECTED_PATH = "./testing/expected.json"
+
+// Rewrite the golden file with the current output instead of comparing against it
+var update = flag.Bool("update", false, "update the golden file")
+
+// Compile a list of all outputs rendered in the tests
+var OUTPUTS = map[string]*types.Output{
+	"Message only": {Message: "Hello, <STRONG>world<STRONG>!"},
+	"Blocks": {Message: "Entry found", Blocks: []types.Block{
+		{Type: types.TextBlock, Title: "Note", Text: "Some <EM>text<EM>"},
+		{Type: types.CardBlock, Title: "github", Fields: []types.Field{
+			{Name: "Username", Value: "horus", Inline: true},
+			{Name: "URL", Value: "https://github.com"},
+		}},
+		{Type: types.ListBlock, Title: "Steps", Items: []string{"First", "Second"}, Ordered: true},
+	}},
+	"Table with uneven rows": {Blocks: []types.Block{
+		{Type: types.TableBlock, Title: "Values", Columns: []string{"Name", "Value"}, Rows: [][]string{
+			{"short"},
+			{"a", "b", "extra cell"},
+			{"éèê", "日本"},
+		}},
+	}},
+	"Table without columns": {Blocks: []types.Block{
+		{Type: types.TableBlock, Rows: [][]string{{"a", "b"}, {"c"}}},
+	}},
+	"Empty blocks": {Message: "Nothing to show", Blocks: []types.Block{
+		{Type: types.TextBlock},
+		{Type: types.CardBlock},
+		{Type: types.TableBlock, Title: "Empty table"},
+	}},
+	"Actions": {Message: "Should I go ahead?", Actions: types.ConfirmActions},
+	"Form": {
+		Message: "What should the entry be called?",
+		Actions: []types.Action{{Label: "Cancel", Value: "cancel", Style: types.DangerAction}},
+		Form: &types.Form{ID: "entry", Title: "New entry", Fields: []types.FormField{
+			{Name: "title", Label: "Title", Required: true},
+		}},
+	},
+	"Files": {
+		Message:     "Here is the report",
+		Attachments: []types.FileOutput{{Filename: "report.csv", ContentType: "text/csv", Content: []byte("a,b")}},
+	},
+}
+
+func TestPrintOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	expected := map[string]string{}
+	if !*update {
+		data, err := os.ReadFile(EXPECTED_PATH)
+		assert.Nil(err)
+		assert.Nil(json.Unmarshal(data, &expected))
+	}
+
+	got := map[string]string{}
+	for name, output := range OUTPUTS {
+		var out bytes.Buffer
+		term := &terminal{out: &out}
+		term.printOutput(output)
+
+		got[name] = out.String()
+		if !*update {
+			assert.Equal(expected[name], got[name], name)
+		}
+	}
+
+	if *update {
+		var sb strings.Builder
+		encoder := json.NewEncoder(&sb)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "    ")
+		assert.Nil(encoder.Encode(got))
+		assert.Nil(os.WriteFile(EXPECTED_PATH, []byte(sb.String()), 0644))
+	}
+}
+
+func TestPrintOutputStripsControl(t *testing.T) {
+	assert := assert.New(t)
+
+	// Each escape sets the terminal's title or starts a control sequence
+	osc := "\x1b]0;title\x07"
+	csi := "\u009b2J"
+	output := &types.Output{
+		Message: "Message " + osc,
+		Blocks: []types.Block{
+			{Type: types.TableBlock, Title: "Table " + csi, Columns: []string{"Name " + osc}, Rows: [][]string{{"cell " + osc, "multi\nline"}, {csi}}},
+			{Type: types.CardBlock, Title: "Card", Fields: []types.Field{{Name: "Field " + osc, Value: "value " + csi}}},
+			{Type: types.ListBlock, Items: []string{"item " + osc}},
+		},
+		Actions:     []types.Action{{Label: "Action " + osc, Value: "yes"}},
+		Attachments: []types.FileOutput{{Filename: "file " + csi + ".txt", Content: []byte("a")}},
+	}
+
+	var out bytes.Buffer
+	term := &terminal{out: &out}
+	term.printOutput(output)
+
+	// Only the renderer's own styles are left
+	text := out.String()
+	assert.NotContains(text, "\x1b]")
+	assert.NotContains(text, "\x07")
+	assert.NotContains(text, "\u009b")
+	for _, s := range []string{"Message", "Table", "Name", "cell", "Field", "value", "item", "Action", "file 2J.txt"} {
+		assert.Contains(text, s)
+	}
+
+	// Cells stay on one line so the table keeps its shape
+	assert.Contains(text, "│ cell ]0;title │ multi line │")
+}
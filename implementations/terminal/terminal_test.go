@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAPI is an in-memory Horus API server for a single bot
+type fakeAPI struct {
+	mu            sync.Mutex
+	conversations map[string][]string
+	outreach      chan string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api/stream/terminal" {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		for {
+			select {
+			case m := <-f.outreach:
+				data, _ := json.Marshal(types.APIOutreach{Method: types.Terminal, Message: m})
+				fmt.Fprintf(w, "event: %v\ndata: %s\n\n", types.EVENT_OUTREACH, data)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/bots/horus-main/conversations"), "/")
+	switch {
+	case len(path) == 1 && r.Method == http.MethodGet:
+		conversations := []types.APIConversation{}
+		for key, messages := range f.conversations {
+			conversations = append(conversations, types.APIConversation{Key: key, Messages: len(messages), CreatedAt: time.Unix(0, 0)})
+		}
+		sort.Slice(conversations, func(i, j int) bool { return conversations[i].Key < conversations[j].Key })
+		json.NewEncoder(w).Encode(conversations)
+
+	case len(path) == 1 && r.Method == http.MethodPost:
+		var req types.APIConversationRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.conversations[req.Key] = append([]string{}, f.conversations[req.Fork]...)
+		w.WriteHeader(http.StatusCreated)
+
+	case len(path) == 2 && f.conversations[path[1]] == nil:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(types.APIError{Error: "conversation not found"})
+
+	case len(path) == 2 && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(types.APIConversation{Key: path[1]})
+
+	case len(path) == 2 && r.Method == http.MethodPatch:
+		var req types.APIConversationUpdate
+		json.NewDecoder(r.Body).Decode(&req)
+		f.conversations[req.Key] = f.conversations[path[1]]
+		delete(f.conversations, path[1])
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(path) == 2 && r.Method == http.MethodDelete:
+		delete(f.conversations, path[1])
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(path) == 3 && r.Method == http.MethodGet:
+		messages := []types.APIMessage{}
+		for _, m := range f.conversations[path[1]] {
+			messages = append(messages,
+				types.APIMessage{Role: "user", Content: m},
+				types.APIMessage{Role: "assistant", ToolCalls: []types.APIToolCall{{Name: "echo", Arguments: `{"text":"` + m + `"}`}}},
+				types.APIMessage{Role: "tool", Name: "echo", Content: m},
+				types.APIMessage{Role: "assistant", Content: "<STRONG>echo<STRONG>: " + m},
+			)
+		}
+		json.NewEncoder(w).Encode(messages)
+
+	case len(path) == 3 && r.Method == http.MethodPost:
+		var req types.APIMessageRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.conversations[path[1]] = append(f.conversations[path[1]], req.Message)
+
+		output := types.APIOutput{Message: "<STRONG>echo<STRONG>: " + req.Message}
+		if req.Message == "choose" {
+			output.Actions = []types.Action{{Label: "Yes please", Value: "yes"}}
+		}
+		json.NewEncoder(w).Encode(output)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// setup starts a fake API server and returns the flags to connect to it
+func setup(t *testing.T) (*fakeAPI, []string) {
+	fake := &fakeAPI{conversations: map[string][]string{}, outreach: make(chan string)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	// Don't read the user's config file
+	config := filepath.Join(t.TempDir(), "terminal.yaml")
+	os.WriteFile(config, []byte("bot: horus-main\noutreach: \"\"\n"), 0600)
+
+	return fake, []string{"-config", config, "-url", server.URL}
+}
+
+func TestConversations(t *testing.T) {
+	assert := assert.New(t)
+	fake, flags := setup(t)
+
+	var out strings.Builder
+	assert.Nil(run(append(flags, "conversations", "new", "notes"), nil, &out))
+	assert.Equal("Created conversation 'notes'\n", out.String())
+	assert.NotNil(fake.conversations["notes"])
+
+	out.Reset()
+	assert.Nil(run(append(flags, "conversations", "list"), nil, &out))
+	assert.Contains(out.String(), "notes")
+
+	out.Reset()
+	assert.Nil(run(append(flags, "conversations", "rm", "notes"), nil, &out))
+	assert.Nil(fake.conversations["notes"])
+
+	assert.NotNil(run(append(flags, "conversations", "rename"), nil, &out))
+	assert.NotNil(run(append(flags, "dance"), nil, &out))
+}
+
+func TestSend(t *testing.T) {
+	assert := assert.New(t)
+	fake, flags := setup(t)
+
+	var out strings.Builder
+	assert.Nil(run(append(flags, "send", "-c", "quick", "hello", "there"), nil, &out))
+	assert.Equal("\x1b[1mecho\x1b[0m: hello there\n", out.String())
+	assert.Equal([]string{"hello there"}, fake.conversations["quick"])
+}
+
+func TestChat(t *testing.T) {
+	assert := assert.New(t)
+	fake, flags := setup(t)
+
+	input := strings.NewReader("hi\nchoose\n1\n/new other\nbye\n/nope\n/quit\n")
+	var out strings.Builder
+	assert.Nil(run(append(flags, "chat"), input, &out))
+
+	assert.Equal([]string{"hi", "choose", "yes"}, fake.conversations["terminal"])
+	assert.Equal([]string{"bye"}, fake.conversations["other"])
+	assert.Contains(out.String(), "  [1] Yes please\n")
+	assert.Contains(out.String(), "Error: unknown command '/nope'")
+}
+
+func TestOutreach(t *testing.T) {
+	assert := assert.New(t)
+	fake, flags := setup(t)
+
+	// Receive outreach messages while chatting
+	config := filepath.Join(t.TempDir(), "terminal.yaml")
+	os.WriteFile(config, []byte("outreach: terminal\n"), 0600)
+	flags[1] = config
+
+	// Keep the chat open until the outreach message is printed
+	r, w, _ := os.Pipe()
+	defer w.Close()
+
+	out := &syncBuilder{}
+	done := make(chan error)
+	go func() { done <- run(append(flags, "chat"), r, out) }()
+
+	fake.outreach <- "Time to <EM>stretch<EM>"
+	assert.Eventually(func() bool { return strings.Contains(out.String(), "Time to \x1b[3mstretch\x1b[0m") }, time.Second, 10*time.Millisecond)
+
+	w.Write([]byte("/quit\n"))
+	assert.Nil(<-done)
+}
+
+// syncBuilder is a strings.Builder that is safe for concurrent use
+type syncBuilder struct {
+	mu sync.Mutex
+	sb strings.Builder
+}
+
+func (b *syncBuilder) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sb.Write(p)
+}
+
+func (b *syncBuilder) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sb.String()
+}
@@ -0,0 +1,581 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethanbaker/horus/utils/format"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/gdamore/tcell/v2"
+)
+
+/* -------- CONSTANTS -------- */
+
+// Layout of the TUI
+const (
+	SIDEBAR_WIDTH      = 28 // The width of the conversation sidebar
+	NOTIFICATION_LINES = 6  // The height of the outreach notification pane
+	MAX_NOTIFICATIONS  = 20 // How many outreach messages are kept
+)
+
+const TUI_HELP = "Tab focus · Enter send/open · ^N new · ^F fork · ^R rename · ^D delete · ^T tools · PgUp/PgDn scroll · ^C quit"
+
+/* -------- TYPES -------- */
+
+// focus is the part of the TUI that receives key presses
+type focus int
+
+const (
+	focusInput focus = iota
+	focusSidebar
+	focusTranscript
+)
+
+// entry is an entry in the transcript. Tool entries are collapsed to a single line unless they
+// are expanded
+type entry struct {
+	label string // Who the entry is from (ex: you, horus, tool)
+	text  string // The entry's content
+	tool  bool   // Whether the entry is a tool call or result
+}
+
+// prompt asks for a value in place of the input box
+type prompt struct {
+	label string
+	done  func(value string)
+}
+
+// tui is a full-screen terminal interface for a bot
+type tui struct {
+	*terminal
+	ctx    context.Context
+	cancel context.CancelFunc // Stops the TUI's requests when it quits
+	screen tcell.Screen
+	async  chan func() // Results of requests, applied on the TUI's goroutine
+
+	conversations []types.APIConversation
+	selected      int    // The conversation selected in the sidebar
+	current       string // The conversation shown in the transcript
+
+	transcript []entry
+	actions    []types.Action // The actions suggested by the last reply
+	expanded   map[int]bool   // The expanded tool entries
+	allTools   bool           // Whether every tool entry is expanded
+	cursor     int            // The selected tool entry when the transcript is focused
+	scroll     int            // How many lines the transcript is scrolled up
+
+	notifications []string
+	input         lineEdit
+	prompt        *prompt
+	focus         focus
+	status        string
+	sending       bool // Whether a message is waiting for a reply
+	refreshes     int  // Counts refreshes so only the latest one is applied
+	loads         int  // Counts loads so only the latest one is applied
+}
+
+/* -------- FUNCTIONS -------- */
+
+// newTUI creates a TUI for a screen
+func (t *terminal) newTUI(ctx context.Context, screen tcell.Screen) *tui {
+	ctx, cancel := context.WithCancel(ctx)
+	return &tui{
+		terminal: t,
+		ctx:      ctx,
+		cancel:   cancel,
+		screen:   screen,
+		async:    make(chan func(), 16),
+		current:  t.config.Conversation,
+		expanded: map[int]bool{},
+		cursor:   -1,
+		status:   TUI_HELP,
+	}
+}
+
+// run runs the TUI until the user quits
+func (u *tui) run() error {
+	t, screen, ctx := u.terminal, u.screen, u.ctx
+	if err := screen.Init(); err != nil {
+		return err
+	}
+	defer screen.Fini()
+	defer u.cancel()
+
+	if err := t.ensureConversation(ctx, u.current); err != nil {
+		return err
+	}
+	u.refresh()
+	u.load(u.current)
+
+	// Show outreach messages in the notification pane
+	if t.config.Outreach != "" {
+		if messages, err := t.client.SubscribeOutreach(ctx, types.OutreachMethod(t.config.Outreach)); err == nil {
+			go func() {
+				for m := range messages {
+					m := m
+					u.post(func() { u.notify(m) })
+				}
+			}()
+		} else {
+			u.status = fmt.Sprintf("Not receiving outreach messages: %v", err)
+		}
+	}
+
+	// Read terminal events on their own goroutine
+	events := make(chan tcell.Event)
+	go func() {
+		for {
+			ev := screen.PollEvent()
+			if ev == nil {
+				return
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		u.draw()
+
+		select {
+		case ev := <-events:
+			switch ev := ev.(type) {
+			case *tcell.EventKey:
+				if quit := u.handleKey(ev); quit {
+					return nil
+				}
+			case *tcell.EventResize:
+				screen.Sync()
+			}
+
+		case f := <-u.async:
+			f()
+		}
+	}
+}
+
+// post applies a function on the TUI's goroutine
+func (u *tui) post(f func()) {
+	select {
+	case u.async <- f:
+	case <-u.ctx.Done():
+	}
+}
+
+// request runs a request in the background and applies its result on the TUI's goroutine
+func (u *tui) request(status string, do func() error, then func()) {
+	u.status = status
+
+	go func() {
+		err := do()
+		u.post(func() {
+			if err != nil {
+				u.status = "Error: " + err.Error()
+				return
+			}
+			u.status = TUI_HELP
+			if then != nil {
+				then()
+			}
+		})
+	}()
+}
+
+/* ---- ACTIONS ---- */
+
+// refresh reloads the conversation list
+func (u *tui) refresh() {
+	u.refreshes++
+	n := u.refreshes
+
+	var conversations []types.APIConversation
+	u.request("Loading conversations...", func() (err error) {
+		conversations, err = u.bot.Conversations(u.ctx)
+		return err
+	}, func() {
+		if n != u.refreshes {
+			return
+		}
+		u.conversations = conversations
+		for i, c := range conversations {
+			if c.Key == u.current {
+				u.selected = i
+			}
+		}
+		if u.selected >= len(conversations) {
+			u.selected = len(conversations) - 1
+		}
+	})
+}
+
+// load shows a conversation's history in the transcript
+func (u *tui) load(key string) {
+	u.loads++
+	n := u.loads
+
+	var messages []types.APIMessage
+	u.request("Loading "+key+"...", func() (err error) {
+		messages, err = u.bot.History(u.ctx, key)
+		return err
+	}, func() {
+		if n != u.loads {
+			return
+		}
+		u.current = key
+		u.transcript = transcriptOf(messages)
+		u.actions = nil
+		u.expanded = map[int]bool{}
+		u.cursor = -1
+		u.scroll = 0
+	})
+}
+
+// send sends a message to the current conversation
+func (u *tui) send(message string) {
+	// Numbers choose one of the last reply's actions
+	if i, err := strconv.Atoi(message); err == nil && i >= 1 && i <= len(u.actions) {
+		message = u.actions[i-1].Value
+	}
+
+	key := u.current
+	u.transcript = append(u.transcript, entry{label: "you", text: message})
+	u.scroll = 0
+	u.sending = true
+
+	// The history is reloaded after the reply to show the tool calls the reply made
+	var output *types.Output
+	var messages []types.APIMessage
+	u.request("Waiting for "+u.config.Bot+"...", func() (err error) {
+		defer u.post(func() { u.sending = false })
+
+		output, err = u.bot.SendMessage(u.ctx, key, &types.Input{Message: message})
+		if err == nil && output.Error != nil {
+			err = output.Error
+		}
+		if err != nil {
+			return err
+		}
+
+		messages, err = u.bot.History(u.ctx, key)
+		return err
+	}, func() {
+		u.refresh()
+		if key != u.current {
+			return
+		}
+
+		// Replace the history of any load still waiting, then add the parts of the reply the history doesn't keep
+		u.loads++
+		u.transcript = append(transcriptOf(messages), outputEntries(output)...)
+		for _, file := range output.Files() {
+			text := fmt.Sprintf("%v (%v bytes)", file.Filename, len(file.Content))
+			if u.config.Downloads != "" {
+				if path, err := saveFile(u.config.Downloads, file); err != nil {
+					text += fmt.Sprintf(" could not be saved: %v", err)
+				} else {
+					text += " saved to " + path
+				}
+			}
+			u.transcript = append(u.transcript, entry{label: "file", text: text})
+		}
+		u.actions = output.Actions
+	})
+}
+
+// create creates a conversation, copying another conversation if fork is set
+func (u *tui) create(key string, fork string) {
+	u.request("Creating "+key+"...", func() error {
+		if fork != "" {
+			return u.bot.ForkConversation(u.ctx, fork, key)
+		}
+		return u.bot.AddConversation(u.ctx, key)
+	}, func() {
+		u.current = key
+		u.refresh()
+		u.load(key)
+	})
+}
+
+// rename renames the selected conversation
+func (u *tui) rename(key string, newKey string) {
+	u.request("Renaming "+key+"...", func() error {
+		return u.bot.RenameConversation(u.ctx, key, newKey)
+	}, func() {
+		if u.current == key {
+			u.current = newKey
+		}
+		u.refresh()
+	})
+}
+
+// remove deletes the selected conversation
+func (u *tui) remove(key string) {
+	u.request("Deleting "+key+"...", func() error {
+		return u.bot.DeleteConversation(u.ctx, key)
+	}, func() {
+		if u.current == key {
+			u.current = ""
+			u.transcript = nil
+		}
+		u.refresh()
+	})
+}
+
+// notify adds an outreach message to the notification pane
+func (u *tui) notify(m types.APIOutreach) {
+	text := m.SentAt.Local().Format("15:04") + " " + format.Render(m.Message, format.Plain)
+	u.notifications = append(u.notifications, text)
+	if len(u.notifications) > MAX_NOTIFICATIONS {
+		u.notifications = u.notifications[1:]
+	}
+}
+
+// selectedKey returns the conversation selected in the sidebar, or the current conversation
+func (u *tui) selectedKey() string {
+	if u.focus == focusSidebar && u.selected >= 0 && u.selected < len(u.conversations) {
+		return u.conversations[u.selected].Key
+	}
+	return u.current
+}
+
+// ask replaces the input box with a prompt
+func (u *tui) ask(label string, initial string, done func(value string)) {
+	u.prompt = &prompt{label: label, done: done}
+	u.input.set(initial)
+}
+
+/* ---- KEYS ---- */
+
+// handleKey handles a key press and returns whether the TUI should quit
+func (u *tui) handleKey(ev *tcell.EventKey) bool {
+	// Prompts take every key until they are answered or canceled
+	if u.prompt != nil {
+		switch ev.Key() {
+		case tcell.KeyEscape, tcell.KeyCtrlC:
+			u.prompt = nil
+			u.input.set("")
+		case tcell.KeyEnter:
+			p, value := u.prompt, strings.TrimSpace(u.input.String())
+			u.prompt = nil
+			u.input.set("")
+			if value != "" {
+				p.done(value)
+			}
+		default:
+			u.input.handleKey(ev)
+		}
+		return false
+	}
+
+	switch ev.Key() {
+	case tcell.KeyCtrlC:
+		return true
+
+	case tcell.KeyTab, tcell.KeyBacktab:
+		if ev.Key() == tcell.KeyTab {
+			u.focus = (u.focus + 1) % 3
+		} else {
+			u.focus = (u.focus + 2) % 3
+		}
+		if u.focus == focusTranscript {
+			u.cursor = u.nextTool(len(u.transcript), -1)
+		}
+		return false
+
+	case tcell.KeyPgUp:
+		u.scroll += 10
+		return false
+
+	case tcell.KeyPgDn:
+		u.scroll = clamp(u.scroll-10, 0, u.scroll)
+		return false
+
+	case tcell.KeyCtrlT:
+		u.allTools = !u.allTools
+		u.expanded = map[int]bool{}
+		return false
+
+	case tcell.KeyCtrlN:
+		u.ask("New conversation", "", func(key string) { u.create(key, "") })
+		return false
+
+	case tcell.KeyCtrlF:
+		from := u.selectedKey()
+		u.ask("Fork '"+from+"' as", from+"-fork", func(key string) { u.create(key, from) })
+		return false
+
+	case tcell.KeyCtrlR:
+		from := u.selectedKey()
+		u.ask("Rename '"+from+"' to", from, func(key string) { u.rename(from, key) })
+		return false
+
+	case tcell.KeyCtrlD:
+		from := u.selectedKey()
+		u.ask("Delete '"+from+"'? (y/n)", "", func(answer string) {
+			if strings.EqualFold(answer, "y") {
+				u.remove(from)
+			}
+		})
+		return false
+	}
+
+	switch u.focus {
+	case focusSidebar:
+		switch ev.Key() {
+		case tcell.KeyUp:
+			u.selected = clamp(u.selected-1, 0, len(u.conversations)-1)
+		case tcell.KeyDown:
+			u.selected = clamp(u.selected+1, 0, len(u.conversations)-1)
+		case tcell.KeyEnter:
+			if key := u.selectedKey(); key != "" {
+				u.load(key)
+				u.focus = focusInput
+			}
+		}
+
+	case focusTranscript:
+		switch ev.Key() {
+		case tcell.KeyUp:
+			u.cursor = u.nextTool(u.cursor, -1)
+		case tcell.KeyDown:
+			u.cursor = u.nextTool(u.cursor, 1)
+		case tcell.KeyEnter:
+			if u.cursor >= 0 {
+				u.expanded[u.cursor] = !u.expanded[u.cursor]
+			}
+		}
+
+	case focusInput:
+		if ev.Key() == tcell.KeyEnter {
+			message := strings.TrimSpace(u.input.String())
+			if message != "" && !u.sending && u.current != "" {
+				u.input.set("")
+				u.send(message)
+			}
+			return false
+		}
+		u.input.handleKey(ev)
+	}
+
+	return false
+}
+
+// nextTool finds the next tool entry from an index in a direction, staying put if there is none
+func (u *tui) nextTool(from int, direction int) int {
+	for i := from + direction; i >= 0 && i < len(u.transcript); i += direction {
+		if u.transcript[i].tool {
+			return i
+		}
+	}
+	if from >= len(u.transcript) {
+		return -1
+	}
+	return from
+}
+
+/* ---- TRANSCRIPT ---- */
+
+// transcriptOf builds transcript entries from a conversation's history
+func transcriptOf(messages []types.APIMessage) []entry {
+	entries := []entry{}
+	for _, m := range messages {
+		switch m.Role {
+		case "user":
+			entries = append(entries, entry{label: "you", text: m.Content})
+
+		case "assistant":
+			for _, call := range m.ToolCalls {
+				entries = append(entries, entry{label: "call " + call.Name, text: call.Arguments, tool: true})
+			}
+			if m.Content != "" {
+				entries = append(entries, entry{label: "horus", text: format.Render(m.Content, format.Plain)})
+			}
+
+		case "tool":
+			entries = append(entries, entry{label: "result " + m.Name, text: m.Content, tool: true})
+		}
+	}
+
+	return entries
+}
+
+// outputEntries builds transcript entries for the parts of an output that aren't kept in the
+// conversation's history
+func outputEntries(output *types.Output) []entry {
+	entries := []entry{}
+	for _, b := range output.Blocks {
+		entries = append(entries, entry{label: "block", text: format.Render(format.RenderBlock(b), format.Plain)})
+	}
+	if len(output.Actions) > 0 {
+		lines := []string{}
+		for i, a := range output.Actions {
+			lines = append(lines, fmt.Sprintf("[%v] %v", i+1, a.Label))
+		}
+		entries = append(entries, entry{label: "actions", text: strings.Join(lines, "\n")})
+	}
+
+	return entries
+}
+
+/* ---- LINE EDITING ---- */
+
+// lineEdit is an editable line of text
+type lineEdit struct {
+	runes []rune
+	pos   int
+}
+
+// String returns the line's text
+func (l *lineEdit) String() string {
+	return string(l.runes)
+}
+
+// set replaces the line's text and moves the cursor to its end
+func (l *lineEdit) set(text string) {
+	l.runes = []rune(text)
+	l.pos = len(l.runes)
+}
+
+// handleKey edits the line
+func (l *lineEdit) handleKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyRune:
+		l.runes = append(l.runes[:l.pos], append([]rune{ev.Rune()}, l.runes[l.pos:]...)...)
+		l.pos++
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if l.pos > 0 {
+			l.runes = append(l.runes[:l.pos-1], l.runes[l.pos:]...)
+			l.pos--
+		}
+	case tcell.KeyDelete:
+		if l.pos < len(l.runes) {
+			l.runes = append(l.runes[:l.pos], l.runes[l.pos+1:]...)
+		}
+	case tcell.KeyLeft:
+		l.pos = clamp(l.pos-1, 0, len(l.runes))
+	case tcell.KeyRight:
+		l.pos = clamp(l.pos+1, 0, len(l.runes))
+	case tcell.KeyHome, tcell.KeyCtrlA:
+		l.pos = 0
+	case tcell.KeyEnd, tcell.KeyCtrlE:
+		l.pos = len(l.runes)
+	case tcell.KeyCtrlU:
+		l.runes, l.pos = l.runes[l.pos:], 0
+	}
+}
+
+// clamp limits a value to a range, preferring the lower bound when the range is empty
+func clamp(value int, low int, high int) int {
+	if value > high {
+		value = high
+	}
+	if value < low {
+		value = low
+	}
+	return value
+}
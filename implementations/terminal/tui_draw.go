@@ -0,0 +1,276 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+/* -------- STYLES -------- */
+
+var (
+	styleDefault  = tcell.StyleDefault
+	styleBorder   = tcell.StyleDefault.Foreground(tcell.ColorGray)
+	styleFocused  = tcell.StyleDefault.Foreground(tcell.ColorTeal).Bold(true)
+	styleLabel    = tcell.StyleDefault.Bold(true)
+	styleTool     = tcell.StyleDefault.Foreground(tcell.ColorGray)
+	styleSelected = tcell.StyleDefault.Reverse(true)
+	styleStatus   = tcell.StyleDefault.Foreground(tcell.ColorGray)
+)
+
+/* -------- DRAWING -------- */
+
+// line is a line of the transcript
+type line struct {
+	text  string
+	style tcell.Style
+	entry int // The entry the line belongs to
+}
+
+// draw draws the whole TUI
+func (u *tui) draw() {
+	s := u.screen
+	s.Clear()
+	width, height := s.Size()
+
+	// The input box and status line are at the bottom, the sidebar and notifications on the left
+	// and the transcript fills the rest
+	body := height - 4
+	sidebar := SIDEBAR_WIDTH
+	if sidebar > width/2 {
+		sidebar = width / 2
+	}
+	notifications := NOTIFICATION_LINES
+	if notifications > body/2 {
+		notifications = body / 2
+	}
+
+	u.drawSidebar(0, 0, sidebar, body-notifications)
+	u.drawNotifications(0, body-notifications, sidebar, notifications)
+	u.drawTranscript(sidebar, 0, width-sidebar, body)
+	u.drawInput(0, body, width, 3)
+	drawText(s, 0, height-1, width, styleStatus, u.status)
+
+	s.Show()
+}
+
+// drawSidebar draws the conversation list
+func (u *tui) drawSidebar(x, y, width, height int) {
+	drawBox(u.screen, x, y, width, height, "Conversations", u.focus == focusSidebar)
+
+	// Keep the selected conversation in view
+	visible := height - 2
+	start := 0
+	if u.selected >= visible {
+		start = u.selected - visible + 1
+	}
+
+	for i := start; i < len(u.conversations) && i-start < visible; i++ {
+		c := u.conversations[i]
+		style := styleDefault
+		if c.Key == u.current {
+			style = styleLabel
+		}
+		if i == u.selected && u.focus == focusSidebar {
+			style = styleSelected
+		}
+
+		name := " " + c.Key
+		if c.ArchivedAt != nil {
+			name += " (archived)"
+		}
+		drawText(u.screen, x+1, y+1+i-start, width-2, style, pad(name, width-2))
+	}
+}
+
+// drawNotifications draws the latest outreach messages
+func (u *tui) drawNotifications(x, y, width, height int) {
+	drawBox(u.screen, x, y, width, height, "Outreach", false)
+
+	lines := []string{}
+	for _, n := range u.notifications {
+		lines = append(lines, wrap(n, width-2)...)
+	}
+	if visible := height - 2; len(lines) > visible {
+		lines = lines[len(lines)-visible:]
+	}
+
+	for i, l := range lines {
+		drawText(u.screen, x+1, y+1+i, width-2, styleDefault, l)
+	}
+}
+
+// drawTranscript draws the current conversation
+func (u *tui) drawTranscript(x, y, width, height int) {
+	title := u.current
+	if title == "" {
+		title = "No conversation"
+	}
+	drawBox(u.screen, x, y, width, height, title, u.focus == focusTranscript)
+
+	lines := u.transcriptLines(width - 3)
+
+	// Scroll up from the bottom of the transcript
+	visible := height - 2
+	u.scroll = clamp(u.scroll, 0, len(lines)-visible)
+	start := clamp(len(lines)-visible-u.scroll, 0, len(lines))
+
+	for i := start; i < len(lines) && i-start < visible; i++ {
+		l := lines[i]
+		style := l.style
+		if u.focus == focusTranscript && l.entry == u.cursor && u.transcript[l.entry].tool {
+			style = style.Reverse(true)
+		}
+		drawText(u.screen, x+2, y+1+i-start, width-3, style, l.text)
+	}
+}
+
+// transcriptLines lays out the transcript's entries into lines of a width
+func (u *tui) transcriptLines(width int) []line {
+	lines := []line{}
+	for i, e := range u.transcript {
+		if i > 0 {
+			lines = append(lines, line{entry: i})
+		}
+
+		// Tool entries show only their label unless they are expanded
+		if e.tool {
+			expanded := u.allTools != u.expanded[i]
+			marker := "▸ "
+			if expanded {
+				marker = "▾ "
+			}
+			lines = append(lines, line{text: marker + e.label, style: styleTool, entry: i})
+			if expanded {
+				for _, l := range wrap(e.text, width-2) {
+					lines = append(lines, line{text: "  " + l, style: styleTool, entry: i})
+				}
+			}
+			continue
+		}
+
+		lines = append(lines, line{text: e.label, style: styleLabel, entry: i})
+		for _, l := range wrap(e.text, width) {
+			lines = append(lines, line{text: l, style: styleDefault, entry: i})
+		}
+	}
+
+	return lines
+}
+
+// drawInput draws the input box, or the prompt being answered
+func (u *tui) drawInput(x, y, width, height int) {
+	title := "Message"
+	if u.prompt != nil {
+		title = u.prompt.label
+	}
+	drawBox(u.screen, x, y, width, height, title, u.focus == focusInput || u.prompt != nil)
+
+	// Scroll the input horizontally to keep the cursor in view
+	inner := width - 4
+	runes := u.input.runes
+	start := 0
+	if u.input.pos > inner {
+		start = u.input.pos - inner
+	}
+	drawText(u.screen, x+2, y+1, inner, styleDefault, string(runes[start:]))
+
+	if u.focus == focusInput || u.prompt != nil {
+		u.screen.ShowCursor(x+2+runewidth.StringWidth(string(runes[start:u.input.pos])), y+1)
+	} else {
+		u.screen.HideCursor()
+	}
+}
+
+/* ---- HELPERS ---- */
+
+// drawText draws a single line of text, cut off at a width
+func drawText(s tcell.Screen, x, y, width int, style tcell.Style, text string) {
+	end := x + width
+	for _, r := range text {
+		w := runewidth.RuneWidth(r)
+		if w == 0 {
+			continue
+		}
+		if x+w > end {
+			return
+		}
+		s.SetContent(x, y, r, nil, style)
+		x += w
+	}
+}
+
+// drawBox draws a border with a title
+func drawBox(s tcell.Screen, x, y, width, height int, title string, focused bool) {
+	if width < 2 || height < 2 {
+		return
+	}
+
+	style := styleBorder
+	if focused {
+		style = styleFocused
+	}
+
+	for i := x + 1; i < x+width-1; i++ {
+		s.SetContent(i, y, tcell.RuneHLine, nil, style)
+		s.SetContent(i, y+height-1, tcell.RuneHLine, nil, style)
+	}
+	for i := y + 1; i < y+height-1; i++ {
+		s.SetContent(x, i, tcell.RuneVLine, nil, style)
+		s.SetContent(x+width-1, i, tcell.RuneVLine, nil, style)
+	}
+	s.SetContent(x, y, tcell.RuneULCorner, nil, style)
+	s.SetContent(x+width-1, y, tcell.RuneURCorner, nil, style)
+	s.SetContent(x, y+height-1, tcell.RuneLLCorner, nil, style)
+	s.SetContent(x+width-1, y+height-1, tcell.RuneLRCorner, nil, style)
+
+	if title != "" {
+		drawText(s, x+2, y, width-4, style, " "+title+" ")
+	}
+}
+
+// wrap splits text into lines no wider than a width, breaking on spaces where possible
+func wrap(text string, width int) []string {
+	if width < 1 {
+		return nil
+	}
+
+	lines := []string{}
+	for _, paragraph := range strings.Split(text, "\n") {
+		current := ""
+		for _, word := range strings.Split(paragraph, " ") {
+			// Break words that are too long for a line of their own
+			for runewidth.StringWidth(word) > width {
+				if current != "" {
+					lines = append(lines, current)
+					current = ""
+				}
+				cut := runewidth.Truncate(word, width, "")
+				lines = append(lines, cut)
+				word = word[len(cut):]
+			}
+
+			switch {
+			case current == "":
+				current = word
+			case runewidth.StringWidth(current)+1+runewidth.StringWidth(word) <= width:
+				current += " " + word
+			default:
+				lines = append(lines, current)
+				current = word
+			}
+		}
+		lines = append(lines, current)
+	}
+
+	return lines
+}
+
+// pad pads text with spaces to a width
+func pad(text string, width int) string {
+	if w := runewidth.StringWidth(text); w < width {
+		return text + strings.Repeat(" ", width-w)
+	}
+	return text
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethanbaker/horus/client"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// simulation is a TUI running on a simulated screen
+type simulation struct {
+	*tui
+	screen tcell.SimulationScreen
+	done   chan error
+}
+
+// startTUI runs the TUI on a simulated screen against a fake API server
+func startTUI(t *testing.T, outreach string) (*fakeAPI, *simulation) {
+	fake, flags := setup(t)
+
+	c := client.New(flags[3])
+	term := &terminal{
+		config: Config{Bot: "horus-main", Conversation: "terminal", Outreach: outreach},
+		client: c,
+		bot:    c.Bot("horus-main"),
+	}
+
+	screen := tcell.NewSimulationScreen("")
+	screen.SetSize(100, 30)
+
+	s := &simulation{tui: term.newTUI(context.Background(), screen), screen: screen, done: make(chan error, 1)}
+	go func() { s.done <- s.run() }()
+	t.Cleanup(func() { s.press(tcell.KeyCtrlC) })
+
+	return fake, s
+}
+
+// press presses a key
+func (s *simulation) press(key tcell.Key) {
+	s.screen.InjectKey(key, 0, tcell.ModNone)
+}
+
+// typeText types text followed by enter
+func (s *simulation) typeText(text string) {
+	for _, r := range text {
+		s.screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+	s.press(tcell.KeyEnter)
+}
+
+// contents returns the text shown on the screen. The screen is read on the TUI's goroutine so it
+// isn't read while being drawn
+func (s *simulation) contents() string {
+	result := make(chan string, 1)
+	s.post(func() { result <- screenText(s.screen) })
+	select {
+	case text := <-result:
+		return text
+	case <-time.After(time.Second):
+		return ""
+	}
+}
+
+// shows waits for text to be shown on the screen
+func (s *simulation) shows(t *testing.T, text string) bool {
+	return assert.Eventually(t, func() bool { return strings.Contains(s.contents(), text) }, time.Second, 10*time.Millisecond, text)
+}
+
+// sidebarRow returns how a conversation is shown in the sidebar
+func sidebarRow(key string) string {
+	return "│" + pad(" "+key, SIDEBAR_WIDTH-2) + "│"
+}
+
+// screenText returns the text shown on a simulated screen
+func screenText(screen tcell.SimulationScreen) string {
+	cells, width, _ := screen.GetContents()
+
+	var sb strings.Builder
+	for i, c := range cells {
+		if i > 0 && i%width == 0 {
+			sb.WriteRune('\n')
+		}
+		if len(c.Runes) > 0 {
+			sb.WriteRune(c.Runes[0])
+		}
+	}
+	return sb.String()
+}
+
+func TestTUIChat(t *testing.T) {
+	assert := assert.New(t)
+	fake, s := startTUI(t, "")
+
+	s.shows(t, "Conversations")
+	s.typeText("hello")
+	s.shows(t, "echo: hello")
+
+	// Tool calls are collapsed until they are toggled
+	s.shows(t, "▸ call echo")
+	assert.NotContains(s.contents(), `{"text":"hello"}`)
+	s.press(tcell.KeyCtrlT)
+	s.shows(t, `{"text":"hello"}`)
+
+	fake.mu.Lock()
+	assert.Equal([]string{"hello"}, fake.conversations["terminal"])
+	fake.mu.Unlock()
+}
+
+func TestTUIConversations(t *testing.T) {
+	assert := assert.New(t)
+	fake, s := startTUI(t, "")
+
+	s.shows(t, sidebarRow("terminal"))
+	s.typeText("first")
+	s.shows(t, "echo: first")
+
+	// Fork the conversation, then rename the fork
+	s.press(tcell.KeyCtrlF)
+	s.shows(t, "Fork 'terminal' as")
+	s.typeText("")
+	s.shows(t, sidebarRow("terminal-fork"))
+
+	s.press(tcell.KeyCtrlR)
+	s.shows(t, "Rename 'terminal-fork' to")
+	s.press(tcell.KeyCtrlU)
+	s.typeText("copy")
+	s.shows(t, sidebarRow("copy"))
+
+	// Delete the original from the sidebar
+	s.press(tcell.KeyTab)
+	s.press(tcell.KeyDown)
+	s.press(tcell.KeyCtrlD)
+	s.shows(t, "Delete 'terminal'? (y/n)")
+	s.typeText("y")
+	assert.Eventually(func() bool {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		return fake.conversations["terminal"] == nil
+	}, time.Second, 10*time.Millisecond)
+
+	fake.mu.Lock()
+	assert.Equal([]string{"first"}, fake.conversations["copy"])
+	assert.Nil(fake.conversations["terminal-fork"])
+	fake.mu.Unlock()
+}
+
+func TestTUIOutreach(t *testing.T) {
+	fake, s := startTUI(t, string(types.Terminal))
+
+	fake.outreach <- "Time to <EM>stretch<EM>"
+	s.shows(t, "Time to stretch")
+
+	s.press(tcell.KeyCtrlC)
+	assert.Nil(t, <-s.done)
+}
+
+func TestWrap(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"one two", "three"}, wrap("one two three", 8))
+	assert.Equal([]string{"abcd", "efgh", "ij"}, wrap("abcdefghij", 4))
+	assert.Equal([]string{"a", "", "b"}, wrap("a\n\nb", 4))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ethanbaker/horus/implementations/common"
+	"github.com/ethanbaker/horus/server"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- CONSTANTS -------- */
+
+// Web config
+var (
+	ADDR     string = envOr("WEB_ADDR", ":8081")
+	PASSWORD string = os.Getenv("WEB_PASSWORD") // The password used to log in
+)
+
+// How long tool audit records are kept
+const AUDIT_RETENTION = 30 * 24 * time.Hour
+
+// How often audit retention is applied
+const MAINTENANCE_INTERVAL = time.Hour
+
+/* ------------------ FUNCTIONS ------------------ */
+
+// main starts the web interface
+func main() {
+	if PASSWORD == "" {
+		log.Fatal("[ERROR]: In web, WEB_PASSWORD must be set")
+	}
+
+	// Setup the bot and outreach
+	b, ch, err := common.NewBot(common.Setup{Method: types.Web, AuditRetention: AUDIT_RETENTION})
+	if err != nil {
+		log.Fatalf("[ERROR]: In web, error setting up horus (err: %v)\n", err)
+	}
+
+	// Serve the bot over the Horus API for the browser to use. The API is only reachable
+	// through the web interface's login
+	api := server.New(server.FromHorus(b))
+	api.AddOutreach(types.Web, ch)
+
+	// Maintain the bot between requests, since bots cannot be used concurrently
+	api.Every(b.Name, MAINTENANCE_INTERVAL, func(server.Bot) {
+		if err := b.Maintain(); err != nil {
+			log.Printf("[ERROR]: In web, error maintaining bot (err: %v)\n", err)
+		}
+	})
+
+	log.Printf("[STATUS]: Web is now running on %v\n", ADDR)
+	if err := http.ListenAndServe(ADDR, newWeb(api, b.Name, PASSWORD)); err != nil {
+		log.Fatalf("[ERROR]: In web, error serving (err: %v)\n", err)
+	}
+}
+
+// envOr returns an environment variable or a fallback if it is unset
+func envOr(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ethanbaker/horus/utils/format"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+// renderItem is a message to render as HTML
+type renderItem struct {
+	Markup string        `json:"markup,omitempty"` // The message's Horus markup
+	Blocks []types.Block `json:"blocks,omitempty"` // The message's blocks
+}
+
+// render renders messages as HTML for the page, since bots reply in Horus markup. The request is
+// a JSON list of messages and the response is a list of HTML fragments in the same order
+func (w *web) render(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var items []renderItem
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, MAX_BODY_SIZE)).Decode(&items); err != nil {
+		writeJSON(rw, http.StatusBadRequest, types.APIError{Error: "invalid request body: " + err.Error()})
+		return
+	}
+
+	html := make([]string, len(items))
+	for i, item := range items {
+		html[i] = renderHTML(item.Markup, item.Blocks)
+	}
+
+	writeJSON(rw, http.StatusOK, html)
+}
+
+// renderHTML renders a message and its blocks as HTML
+func renderHTML(markup string, blocks []types.Block) string {
+	parts := []string{}
+	if markup != "" {
+		parts = append(parts, format.Render(markup, format.HTML))
+	}
+	for _, b := range blocks {
+		parts = append(parts, `<div class="block">`+format.Render(format.RenderBlock(b), format.HTML)+`</div>`)
+	}
+
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,289 @@
+// The browser side of the Horus web interface. Conversations, messages and files go through the
+// Horus API, replies are streamed over the API's event stream and bot markup is rendered as HTML
+// by the web server
+"use strict";
+
+const BOT = document.body.dataset.bot;
+const STREAM = document.body.dataset.stream;
+
+const $ = (selector) => document.querySelector(selector);
+const conversationList = $("#conversations");
+const notificationList = $("#notifications ul");
+const transcript = $("#transcript");
+const composer = $("#composer");
+const input = $("#message");
+
+let current = null; // The open conversation
+let pending = null; // The reply being streamed, if any
+
+/* ---- REQUESTS ---- */
+
+// request calls the web server and returns the decoded JSON response
+async function request(method, path, body) {
+  const res = await fetch(path, {
+    method,
+    headers: { "Content-Type": "application/json", "X-Horus-Web": "1" },
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+
+  if (res.status === 401) {
+    location.href = "/login";
+    throw new Error("not logged in");
+  }
+  const data = res.status === 204 ? null : await res.json();
+  if (!res.ok) {
+    throw new Error(data && data.error ? data.error : res.statusText);
+  }
+  return data;
+}
+
+// botPath returns the API path of one of the bot's resources
+function botPath(...parts) {
+  return "/api/bots/" + [BOT, ...parts].map(encodeURIComponent).join("/");
+}
+
+// render renders messages of Horus markup (and blocks) as HTML
+function render(items) {
+  return items.length === 0 ? Promise.resolve([]) : request("POST", "/render", items);
+}
+
+/* ---- CONVERSATIONS ---- */
+
+// loadConversations shows the bot's conversations, newest first
+async function loadConversations() {
+  const conversations = await request("GET", botPath("conversations"));
+  conversations.sort((a, b) => new Date(b.created_at) - new Date(a.created_at));
+
+  conversationList.replaceChildren(...conversations.map((c) => {
+    const item = document.createElement("li");
+    item.classList.toggle("active", c.key === current);
+    item.classList.toggle("archived", !!c.archived_at);
+
+    const open = document.createElement("button");
+    open.className = "link";
+    open.textContent = c.key;
+    open.addEventListener("click", () => openConversation(c.key));
+
+    const remove = document.createElement("button");
+    remove.className = "link delete";
+    remove.title = "Delete";
+    remove.textContent = "×";
+    remove.addEventListener("click", () => deleteConversation(c.key));
+
+    item.append(open, remove);
+    return item;
+  }));
+}
+
+// openConversation shows a conversation's history
+async function openConversation(key) {
+  current = key;
+  pending = null;
+  $("#title").textContent = key;
+  input.disabled = false;
+  composer.querySelector("button").disabled = false;
+
+  const history = (await request("GET", botPath("conversations", key, "messages")))
+    .filter((m) => (m.role === "user" || m.role === "assistant") && m.content);
+  const html = await render(history.filter((m) => m.role === "assistant").map((m) => ({ markup: m.content })));
+
+  if (key !== current) {
+    return;
+  }
+  transcript.replaceChildren();
+  for (const m of history) {
+    if (m.role === "user") {
+      addMessage("user").textContent = m.content;
+    } else {
+      addMessage("assistant").innerHTML = html.shift();
+    }
+  }
+
+  await loadConversations();
+  input.focus();
+}
+
+// newConversation asks for a name and creates a conversation
+async function newConversation() {
+  const key = prompt("Name the new conversation", "web-" + new Date().toISOString().slice(0, 16).replace(":", ""));
+  if (!key) {
+    return;
+  }
+
+  try {
+    await request("POST", botPath("conversations"), { key });
+    await openConversation(key);
+  } catch (err) {
+    alert("Cannot create conversation: " + err.message);
+  }
+}
+
+// deleteConversation deletes a conversation after asking
+async function deleteConversation(key) {
+  if (!confirm(`Delete the conversation '${key}'?`)) {
+    return;
+  }
+
+  try {
+    await request("DELETE", botPath("conversations", key));
+    if (key === current) {
+      current = null;
+      $("#title").textContent = "Choose a conversation";
+      transcript.replaceChildren();
+      input.disabled = true;
+    }
+    await loadConversations();
+  } catch (err) {
+    alert("Cannot delete conversation: " + err.message);
+  }
+}
+
+/* ---- CHAT ---- */
+
+// addMessage adds a message to the transcript and returns its content element
+function addMessage(role) {
+  const message = document.createElement("div");
+  message.className = "message " + role;
+  transcript.append(message);
+  transcript.scrollTop = transcript.scrollHeight;
+  return message;
+}
+
+// send sends a message to the open conversation and shows the reply as it is streamed
+async function send(text) {
+  const key = current;
+  addMessage("user").textContent = text;
+
+  const reply = addMessage("assistant streaming");
+  pending = { key, element: reply };
+
+  try {
+    const output = await request("POST", botPath("conversations", key, "messages"), {
+      message: text,
+      caller: "web",
+      stream: STREAM,
+    });
+    await showOutput(reply, output);
+  } catch (err) {
+    reply.className = "message error";
+    reply.textContent = "Sorry, an error occurred: " + err.message;
+  } finally {
+    if (pending && pending.element === reply) {
+      pending = null;
+    }
+  }
+  loadConversations();
+}
+
+// showOutput replaces a streamed reply with the complete output
+async function showOutput(element, output) {
+  element.classList.remove("streaming");
+  if (output.error) {
+    element.className = "message error";
+    element.textContent = "Sorry, an error occurred: " + output.error;
+    return;
+  }
+
+  const [html] = await render([{ markup: output.message, blocks: output.blocks }]);
+  element.innerHTML = html;
+
+  // Files are downloaded from the API
+  for (const file of output.files || []) {
+    const link = document.createElement("a");
+    link.className = "file";
+    link.href = file.url;
+    link.download = file.filename;
+    link.textContent = `${file.filename} (${formatSize(file.size)})`;
+    element.append(link);
+  }
+
+  // Actions send their value as the next message
+  if (output.actions && output.actions.length > 0) {
+    const actions = document.createElement("div");
+    actions.className = "actions";
+    for (const action of output.actions) {
+      const button = document.createElement("button");
+      button.className = action.style || "secondary";
+      button.textContent = action.label;
+      button.addEventListener("click", () => {
+        actions.remove();
+        send(action.value);
+      });
+      actions.append(button);
+    }
+    element.append(actions);
+  }
+
+  transcript.scrollTop = transcript.scrollHeight;
+}
+
+// formatSize formats a size in bytes
+function formatSize(size) {
+  if (size < 1024) {
+    return size + " B";
+  }
+  return size < 1024 * 1024 ? (size / 1024).toFixed(1) + " KB" : (size / 1024 / 1024).toFixed(1) + " MB";
+}
+
+/* ---- EVENTS ---- */
+
+// listen receives streamed replies and outreach messages. The browser reconnects on its own and
+// resumes from the last event it received
+function listen() {
+  const events = new EventSource("/api/stream/" + encodeURIComponent(STREAM));
+
+  events.addEventListener("token", (e) => {
+    const token = JSON.parse(e.data);
+    if (pending && token.conversation === pending.key && token.bot === BOT) {
+      pending.element.textContent += token.token;
+      transcript.scrollTop = transcript.scrollHeight;
+    }
+  });
+
+  events.addEventListener("outreach", async (e) => {
+    const outreach = JSON.parse(e.data);
+    const [html] = await render([{ markup: outreach.message }]);
+
+    const item = document.createElement("li");
+    const time = document.createElement("time");
+    time.textContent = new Date(outreach.sent_at).toLocaleTimeString([], { hour: "2-digit", minute: "2-digit" });
+    const content = document.createElement("div");
+    content.innerHTML = html;
+    item.append(time, content);
+    notificationList.prepend(item);
+
+    if ("Notification" in window && Notification.permission === "granted") {
+      new Notification(BOT, { body: content.textContent });
+    }
+  });
+}
+
+/* ---- SETUP ---- */
+
+composer.addEventListener("submit", (e) => {
+  e.preventDefault();
+  const text = input.value.trim();
+  if (text && current) {
+    input.value = "";
+    send(text);
+  }
+});
+
+input.addEventListener("keydown", (e) => {
+  if (e.key === "Enter" && !e.shiftKey) {
+    e.preventDefault();
+    composer.requestSubmit();
+  }
+});
+
+$("#new-conversation").addEventListener("click", newConversation);
+
+// Ask to show outreach as notifications the first time the page is used
+document.addEventListener("click", () => {
+  if ("Notification" in window && Notification.permission === "default") {
+    Notification.requestPermission();
+  }
+}, { once: true });
+
+loadConversations();
+listen();
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* -------- CONSTANTS -------- */
+
+// The cookie holding a browser's session
+const SESSION_COOKIE = "horus_session"
+
+// How long a login lasts
+const SESSION_TTL = 7 * 24 * time.Hour
+
+// How long a failed login waits before responding, to slow down guessing
+const LOGIN_FAILURE_DELAY = time.Second
+
+// The header scripts send with requests that change state. Browsers only send custom headers
+// from the page's own origin, so other sites cannot make requests with a user's session
+const REQUEST_HEADER = "X-Horus-Web"
+
+// The largest render request accepted
+const MAX_BODY_SIZE = 1 << 20
+
+/* -------- ASSETS -------- */
+
+//go:embed static
+var assets embed.FS
+
+// Pages rendered with the bot's name and login state
+var pages = template.Must(template.ParseFS(assets, "static/*.html"))
+
+/* -------- SESSIONS -------- */
+
+// sessions keeps the browsers that are logged in
+type sessions struct {
+	mu      sync.Mutex
+	expires map[string]time.Time // When each session token expires
+}
+
+// create starts a session and returns its token
+func (s *sessions) create() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop expired sessions while we're here
+	now := time.Now()
+	for t, expires := range s.expires {
+		if now.After(expires) {
+			delete(s.expires, t)
+		}
+	}
+
+	s.expires[token] = now.Add(SESSION_TTL)
+	return token, nil
+}
+
+// valid returns true if a session token is logged in
+func (s *sessions) valid(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires, ok := s.expires[token]
+	return ok && time.Now().Before(expires)
+}
+
+// end logs a session out
+func (s *sessions) end(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.expires, token)
+}
+
+/* -------- WEB -------- */
+
+// web serves the browser interface for a bot. Logged in browsers use the Horus API to list
+// conversations, chat, download files and receive outreach
+type web struct {
+	api      http.Handler // The Horus API serving the bot
+	bot      string       // The name of the bot being served
+	password [32]byte     // The SHA-256 hash of the login password
+	sessions *sessions
+	mux      *http.ServeMux
+}
+
+// newWeb creates the web interface for a bot served by an API
+func newWeb(api http.Handler, bot string, password string) *web {
+	w := &web{
+		api:      api,
+		bot:      bot,
+		password: sha256.Sum256([]byte(password)),
+		sessions: &sessions{expires: map[string]time.Time{}},
+		mux:      http.NewServeMux(),
+	}
+
+	static, _ := fs.Sub(assets, "static")
+	w.mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(static))))
+	w.mux.HandleFunc("/login", w.login)
+	w.mux.HandleFunc("/logout", w.logout)
+	w.mux.Handle("/render", w.requireSession(http.HandlerFunc(w.render)))
+	w.mux.Handle("/api/", w.requireSession(api))
+	w.mux.HandleFunc("/", w.index)
+
+	return w
+}
+
+// ServeHTTP serves a request
+func (w *web) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("X-Frame-Options", "DENY")
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	w.mux.ServeHTTP(rw, r)
+}
+
+// loggedIn returns true if a request has a valid session
+func (w *web) loggedIn(r *http.Request) bool {
+	cookie, err := r.Cookie(SESSION_COOKIE)
+	return err == nil && w.sessions.valid(cookie.Value)
+}
+
+// requireSession only passes on requests from logged in browsers. Requests that change state must
+// also come from the page's scripts
+func (w *web) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !w.loggedIn(r) {
+			writeJSON(rw, http.StatusUnauthorized, types.APIError{Error: "not logged in"})
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Header.Get(REQUEST_HEADER) == "" {
+			writeJSON(rw, http.StatusForbidden, types.APIError{Error: "missing " + REQUEST_HEADER + " header"})
+			return
+		}
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// index serves the chat page, or sends the browser to log in
+func (w *web) index(rw http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(rw, r)
+		return
+	}
+	if !w.loggedIn(r) {
+		http.Redirect(rw, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	w.page(rw, "index.html", map[string]any{"Bot": w.bot, "Stream": types.Web})
+}
+
+// login shows the login page and logs browsers in
+func (w *web) login(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.page(rw, "login.html", map[string]any{"Bot": w.bot, "Failed": r.URL.Query().Has("failed")})
+
+	case http.MethodPost:
+		hash := sha256.Sum256([]byte(r.PostFormValue("password")))
+		if subtle.ConstantTimeCompare(hash[:], w.password[:]) != 1 {
+			time.Sleep(LOGIN_FAILURE_DELAY)
+			http.Redirect(rw, r, "/login?failed", http.StatusSeeOther)
+			return
+		}
+
+		token, err := w.sessions.create()
+		if err != nil {
+			log.Printf("[ERROR]: In web, error creating session (err: %v)\n", err)
+			http.Error(rw, "cannot log in", http.StatusInternalServerError)
+			return
+		}
+
+		http.SetCookie(rw, &http.Cookie{
+			Name:     SESSION_COOKIE,
+			Value:    token,
+			Path:     "/",
+			MaxAge:   int(SESSION_TTL.Seconds()),
+			HttpOnly: true,
+			Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+			SameSite: http.SameSiteStrictMode,
+		})
+		http.Redirect(rw, r, "/", http.StatusSeeOther)
+
+	default:
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// logout ends a browser's session
+func (w *web) logout(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if cookie, err := r.Cookie(SESSION_COOKIE); err == nil {
+		w.sessions.end(cookie.Value)
+	}
+	http.SetCookie(rw, &http.Cookie{Name: SESSION_COOKIE, Path: "/", MaxAge: -1})
+	http.Redirect(rw, r, "/login", http.StatusSeeOther)
+}
+
+// page renders a page template
+func (w *web) page(rw http.ResponseWriter, name string, data any) {
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.ExecuteTemplate(rw, name, data); err != nil {
+		log.Printf("[ERROR]: In web, error rendering %v (err: %v)\n", name, err)
+	}
+}
+
+// writeJSON writes a JSON response
+func writeJSON(rw http.ResponseWriter, status int, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		log.Printf("[ERROR]: In web, error writing response (err: %v)\n", err)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAPI records the requests passed on to the Horus API
+type fakeAPI struct {
+	paths []string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.paths = append(f.paths, r.Method+" "+r.URL.Path)
+	writeJSON(w, http.StatusOK, []types.APIConversation{{Key: "web"}})
+}
+
+// setup starts the web interface and returns a client that keeps cookies without following
+// redirects
+func setup(t *testing.T) (*fakeAPI, *httptest.Server, *http.Client) {
+	api := &fakeAPI{}
+	server := httptest.NewServer(newWeb(api, "horus-main", "hunter2"))
+	t.Cleanup(server.Close)
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Jar:           jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	return api, server, client
+}
+
+// login logs a client in with a password and returns where it was redirected
+func login(client *http.Client, server *httptest.Server, password string) string {
+	res, err := client.PostForm(server.URL+"/login", url.Values{"password": {password}})
+	if err != nil {
+		return ""
+	}
+	res.Body.Close()
+	return res.Header.Get("Location")
+}
+
+func TestLogin(t *testing.T) {
+	assert := assert.New(t)
+	api, server, client := setup(t)
+
+	// Browsers that aren't logged in are sent to log in and can't use the API
+	res, _ := client.Get(server.URL + "/")
+	assert.Equal(http.StatusSeeOther, res.StatusCode)
+	assert.Equal("/login", res.Header.Get("Location"))
+
+	res, _ = client.Get(server.URL + "/api/bots/horus-main/conversations")
+	assert.Equal(http.StatusUnauthorized, res.StatusCode)
+	assert.Empty(api.paths)
+
+	// Wrong passwords are rejected
+	assert.Equal("/login?failed", login(client, server, "hunter3"))
+	res, _ = client.Get(server.URL + "/login?failed")
+	body := readBody(res)
+	assert.Contains(body, "Wrong password")
+
+	// Logged in browsers see the chat page and can use the API
+	assert.Equal("/", login(client, server, "hunter2"))
+	res, _ = client.Get(server.URL + "/")
+	body = readBody(res)
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.Contains(body, `data-bot="horus-main"`)
+	assert.Contains(body, `data-stream="web"`)
+
+	res, _ = client.Get(server.URL + "/api/bots/horus-main/conversations")
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.Equal([]string{"GET /api/bots/horus-main/conversations"}, api.paths)
+
+	// Logging out ends the session
+	res, _ = client.Post(server.URL+"/logout", "", nil)
+	assert.Equal("/login", res.Header.Get("Location"))
+	res, _ = client.Get(server.URL + "/api/bots/horus-main/conversations")
+	assert.Equal(http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestRequestHeader(t *testing.T) {
+	assert := assert.New(t)
+	api, server, client := setup(t)
+	login(client, server, "hunter2")
+
+	// Requests that change state must come from the page's scripts
+	res, _ := client.Post(server.URL+"/api/bots/horus-main/conversations", "application/json", strings.NewReader(`{"key":"x"}`))
+	assert.Equal(http.StatusForbidden, res.StatusCode)
+	assert.Empty(api.paths)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/bots/horus-main/conversations", strings.NewReader(`{"key":"x"}`))
+	req.Header.Set(REQUEST_HEADER, "1")
+	res, _ = client.Do(req)
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.Equal([]string{"POST /api/bots/horus-main/conversations"}, api.paths)
+}
+
+func TestRender(t *testing.T) {
+	assert := assert.New(t)
+	_, server, client := setup(t)
+	login(client, server, "hunter2")
+
+	body := `[{"markup":"<STRONG>hi<STRONG> <script>"},{"blocks":[{"type":"list","items":["a"]}]}]`
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/render", strings.NewReader(body))
+	req.Header.Set(REQUEST_HEADER, "1")
+	res, _ := client.Do(req)
+	assert.Equal(http.StatusOK, res.StatusCode)
+
+	var html []string
+	json.NewDecoder(res.Body).Decode(&html)
+	assert.Equal([]string{"<strong>hi</strong> &lt;script&gt;", `<div class="block">• a</div>`}, html)
+}
+
+func TestStatic(t *testing.T) {
+	assert := assert.New(t)
+	_, server, client := setup(t)
+
+	res, _ := client.Get(server.URL + "/static/app.js")
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.Contains(readBody(res), "EventSource")
+}
+
+// readBody reads and closes a response body
+func readBody(res *http.Response) string {
+	var sb strings.Builder
+	buf := make([]byte, 4096)
+	for {
+		n, err := res.Body.Read(buf)
+		sb.Write(buf[:n])
+		if err != nil {
+			break
+		}
+	}
+	res.Body.Close()
+	return sb.String()
+}
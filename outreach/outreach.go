@@ -2,12 +2,14 @@ package outreach
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/ethanbaker/horus/outreach/dynamic"
 	"github.com/ethanbaker/horus/outreach/static"
 	"github.com/ethanbaker/horus/utils/types"
 	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -98,3 +100,47 @@ func New(module string, keys []types.OutreachMethod, data any) (types.OutreachMe
 
 	return f(&services, chans, data)
 }
+
+// AddConfig adds the messages in an outreach config file that are sent to a method. The method's
+// channel must already be added
+func AddConfig(method types.OutreachMethod, path string) error {
+	yamlFile, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var outreachConfig types.OutreachConfig
+	if err = yaml.Unmarshal(yamlFile, &outreachConfig); err != nil {
+		return err
+	}
+
+	for _, msg := range outreachConfig.Static {
+		if types.OutreachMethod(msg.Key) != method {
+			continue
+		}
+
+		_, err = New("static", []types.OutreachMethod{method}, types.StaticOutreach{
+			Function: msg.Name,
+			Repeat:   msg.Repeat,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, msg := range outreachConfig.Dynamic {
+		if types.OutreachMethod(msg.Key) != method {
+			continue
+		}
+
+		_, err = New("dynamic", []types.OutreachMethod{method}, types.DynamicOutreach{
+			Function:        msg.Name,
+			IntervalMinutes: time.Minute * time.Duration(msg.IntervalMinutes),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
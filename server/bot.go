@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+
+	horus "github.com/ethanbaker/horus/bot"
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+// Bot is the part of a Horus bot the server needs. *horus.Bot is adapted with FromHorus, and
+// tests can serve fakes
+type Bot interface {
+	Info() types.APIBot                                                // Describe the bot
+	ListConversations() ([]types.APIConversation, error)               // List the bot's conversations
+	IsConversation(key string) bool                                    // Check if a conversation exists
+	AddConversation(key string) error                                  // Create a conversation
+	DeleteConversation(key string) error                               // Delete a conversation
+	RenameConversation(key string, newKey string) error                // Change the key of a conversation
+	ForkConversation(key string, newKey string) error                  // Copy a conversation into a new one
+	History(key string) ([]types.APIMessage, error)                    // Get the messages in a conversation
+	SendMessage(key string, input *types.Input) (*types.Output, error) // Send a message to a conversation
+}
+
+// horusBot adapts a *horus.Bot to the Bot interface
+type horusBot struct {
+	*horus.Bot
+}
+
+// FromHorus adapts a Horus bot so it can be served
+func FromHorus(b *horus.Bot) Bot {
+	return horusBot{Bot: b}
+}
+
+// Info describes the bot
+func (b horusBot) Info() types.APIBot {
+	return types.APIBot{
+		Name:        b.Name,
+		Permissions: b.Permissions,
+	}
+}
+
+// ListConversations lists the bot's conversations
+func (b horusBot) ListConversations() ([]types.APIConversation, error) {
+	conversations := make([]types.APIConversation, len(b.Conversations))
+	for i, c := range b.Conversations {
+		conversations[i] = types.APIConversation{
+			Key:        c.Name,
+			Messages:   len(c.Messages),
+			CreatedAt:  c.CreatedAt,
+			ArchivedAt: c.ArchivedAt,
+		}
+	}
+
+	return conversations, nil
+}
+
+// History gets the messages in a conversation
+func (b horusBot) History(key string) ([]types.APIMessage, error) {
+	for _, c := range b.Conversations {
+		if c.Name != key {
+			continue
+		}
+
+		messages := make([]types.APIMessage, len(c.Messages))
+		for i, m := range c.Messages {
+			messages[i] = types.APIMessage{
+				Index:     m.Idx,
+				Role:      m.Role,
+				Name:      m.Name,
+				Content:   m.Content,
+				CreatedAt: m.CreatedAt,
+			}
+			for _, call := range m.ToolCalls {
+				messages[i].ToolCalls = append(messages[i].ToolCalls, types.APIToolCall{
+					Name:      call.CallName,
+					Arguments: call.CallArguments,
+				})
+			}
+		}
+		return messages, nil
+	}
+
+	return nil, fmt.Errorf("conversation with given key does not exist")
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	horus "github.com/ethanbaker/horus/bot"
+	"github.com/ethanbaker/horus/server"
+)
+
+// The usage of the keys command
+const KEYS_USAGE = `Usage: horus-server keys <command> [options]
+
+Commands:
+  create <name> [-bots a,b] [-permissions gpt,public] [-streams discord]
+                   Issue a key for an implementation and print it
+  rotate <name>    Replace an implementation's key and print the new one
+  revoke <name>    Stop an implementation's key from working
+  list             List every key
+`
+
+// Permission names accepted by the keys command
+var PERMISSION_NAMES = map[string]byte{
+	"none":    horus.PERMISSIONS_NONE,
+	"gpt":     horus.PERMISSIONS_GPT,
+	"private": horus.PERMISSIONS_PRVMODULES,
+	"public":  horus.PERMISSIONS_PUBMODULES,
+	"modules": horus.PERMISSIONS_ALLMODULES,
+	"all":     horus.PERMISSIONS_ALL,
+}
+
+// runKeys manages API keys from the command line
+func runKeys(store server.KeyStore, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%v", KEYS_USAGE)
+	}
+
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("create", flag.ContinueOnError)
+		bots := flags.String("bots", server.KEY_WILDCARD, "the bots the key can use (comma separated, * for all)")
+		permissions := flags.String("permissions", "all", "the permissions the key has (comma separated names or a number)")
+		streams := flags.String("streams", "", "the outreach methods the key can stream (comma separated, * for all)")
+
+		name, err := parseNamed(flags, args[1:])
+		if err != nil {
+			return err
+		}
+
+		p, err := parsePermissions(*permissions)
+		if err != nil {
+			return err
+		}
+
+		secret, err := server.CreateKey(store, name, splitList(*bots), p, splitList(*streams))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Created key for '%v' (it will not be shown again):\n%v\n", name, secret)
+
+	case "rotate":
+		name, err := parseNamed(flag.NewFlagSet("rotate", flag.ContinueOnError), args[1:])
+		if err != nil {
+			return err
+		}
+
+		secret, err := server.RotateKey(store, name)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Rotated key for '%v' (it will not be shown again):\n%v\n", name, secret)
+
+	case "revoke":
+		name, err := parseNamed(flag.NewFlagSet("revoke", flag.ContinueOnError), args[1:])
+		if err != nil {
+			return err
+		}
+
+		if err := server.RevokeKey(store, name); err != nil {
+			return err
+		}
+
+		fmt.Printf("Revoked key for '%v'\n", name)
+
+	case "list":
+		keys, err := store.ListKeys()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tKEY\tBOTS\tPERMISSIONS\tSTREAMS\tSTATUS")
+		for _, k := range keys {
+			status := "active"
+			if k.RevokedAt != nil {
+				status = "revoked " + k.RevokedAt.Format("2006-01-02")
+			}
+
+			fmt.Fprintf(w, "%v\t%v...\t%v\t%08b\t%v\t%v\n", k.Name, k.Hint, strings.Join(k.Bots, ","), k.Permissions, strings.Join(k.Streams, ","), status)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown command '%v'\n\n%v", args[0], KEYS_USAGE)
+	}
+
+	return nil
+}
+
+// parseNamed parses a command's flags, which can come before or after its name argument
+func parseNamed(flags *flag.FlagSet, args []string) (string, error) {
+	name := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	if name == "" {
+		name = flags.Arg(0)
+	}
+	if name == "" {
+		return "", fmt.Errorf("a key name is required\n\n%v", KEYS_USAGE)
+	}
+
+	return name, nil
+}
+
+// parsePermissions parses a permission byte from a number or a list of permission names
+func parsePermissions(value string) (byte, error) {
+	if n, err := strconv.ParseUint(value, 0, 8); err == nil {
+		return byte(n), nil
+	}
+
+	var permissions byte
+	for _, name := range splitList(value) {
+		p, ok := PERMISSION_NAMES[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown permission '%v'", name)
+		}
+		permissions |= p
+	}
+
+	return permissions, nil
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,178 @@
+// This binary serves Horus bots over the Horus API
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	horus "github.com/ethanbaker/horus/bot"
+	module_ambient "github.com/ethanbaker/horus/bot/module_ambient"
+	module_config "github.com/ethanbaker/horus/bot/module_config"
+	module_keepass "github.com/ethanbaker/horus/bot/module_keepass"
+	"github.com/ethanbaker/horus/outreach"
+	"github.com/ethanbaker/horus/server"
+	"github.com/ethanbaker/horus/utils/types"
+	mysql_driver "github.com/go-sql-driver/mysql"
+	"github.com/sashabaranov/go-openai"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+/* -------- CONSTANTS -------- */
+
+// Server config
+var (
+	ADDR     string   = envOr("HORUS_SERVER_ADDR", ":8080")
+	BOTS     []string = strings.Split(envOr("HORUS_SERVER_BOTS", "horus-main"), ",")
+	OUTREACH []string = strings.Split(os.Getenv("HORUS_SERVER_OUTREACH"), ",") // Outreach methods streamed to implementations
+	NO_AUTH  bool     = os.Getenv("HORUS_SERVER_NO_AUTH") == "true"            // Serve without API keys (for local development only)
+)
+
+// SQL config
+var config = mysql_driver.Config{
+	User:      os.Getenv("SQL_USER"),
+	Passwd:    os.Getenv("SQL_PASSWD"),
+	Net:       os.Getenv("SQL_NET"),
+	Addr:      os.Getenv("SQL_ADDR"),
+	DBName:    os.Getenv("SQL_DBNAME"),
+	ParseTime: true,
+	Loc:       time.Local,
+}
+
+// How long tool audit records are kept
+const AUDIT_RETENTION = 30 * 24 * time.Hour
+
+// How often audit retention is applied
+const MAINTENANCE_INTERVAL = time.Hour
+
+/* ------------------ FUNCTIONS ------------------ */
+
+// main starts the Horus API server, or manages API keys with the "keys" command
+func main() {
+	// Open the API key store
+	db, err := gorm.Open(mysql.Open(config.FormatDSN()), &gorm.Config{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keys, err := server.NewSQLKeyStore(db)
+	if err != nil {
+		log.Fatalf("[ERROR]: In server, error opening key store (err: %v)\n", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		if err := runKeys(keys, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Initialize the SQL
+	if err := horus.InitSQL(config.FormatDSN()); err != nil {
+		log.Fatal(err)
+	}
+
+	// Create the OpenAI client
+	client := openai.NewClient(os.Getenv("OPENAI_TOKEN"))
+
+	// Load or create each bot and serve it
+	s := server.New()
+	bots := map[string]*horus.Bot{}
+	if !NO_AUTH {
+		s.RequireKeys(keys)
+	}
+	for _, name := range BOTS {
+		bot, err := horus.GetBotByName(name)
+		if err != nil {
+			log.Fatalf("[ERROR]: In server, error getting horus bot '%v' (err: %v)\n", name, err)
+		}
+
+		if bot == nil {
+			bot, err = horus.NewBot(name, horus.PERMISSIONS_ALL)
+			if err != nil {
+				log.Fatalf("[ERROR]: In server, error making horus bot '%v' (err: %v)\n", name, err)
+			}
+		}
+
+		// Setup the bot
+		module_ambient.NewModule(bot, true)
+		module_config.NewModule(bot, true)
+		module_keepass.NewModule(bot, true)
+		bot.Setup(client)
+
+		bot.SetAuditRetention(AUDIT_RETENTION)
+		s.AddBot(server.FromHorus(bot))
+		bots[name] = bot
+	}
+
+	// Stream outreach to implementations
+	if err := setupOutreach(s); err != nil {
+		log.Fatalf("[ERROR]: In server, error setting up outreach (err: %v)\n", err)
+	}
+
+	// Maintain bots between requests, since bots cannot be used concurrently
+	go maintain(s, bots)
+
+	log.Printf("Horus server listening on %v\n", ADDR)
+	if err := http.ListenAndServe(ADDR, s); err != nil {
+		log.Fatalf("[ERROR]: In server, error serving (err: %v)\n", err)
+	}
+}
+
+// setupOutreach adds a channel for each outreach method served and starts its outreach messages
+func setupOutreach(s *server.Server) error {
+	methods := []types.OutreachMethod{}
+	for _, m := range OUTREACH {
+		if m = strings.TrimSpace(m); m != "" {
+			methods = append(methods, types.OutreachMethod(m))
+		}
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+
+	if err := outreach.Setup(config.FormatDSN()); err != nil {
+		return err
+	}
+
+	for _, method := range methods {
+		ch, err := outreach.AddChannel(method)
+		if err != nil {
+			return err
+		}
+		s.AddOutreach(method, ch)
+
+		// Add the outreach messages sent to this method
+		if err = outreach.AddConfig(method, os.Getenv("BASE_PATH")+os.Getenv("OUTREACH_CONFIG")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// maintain applies each bot's session policy and audit retention on an interval
+func maintain(s *server.Server, bots map[string]*horus.Bot) {
+	for range time.Tick(MAINTENANCE_INTERVAL) {
+		for name, bot := range bots {
+			s.Do(name, func(server.Bot) {
+				if err := bot.Maintain(); err != nil {
+					log.Printf("[ERROR]: In server, error maintaining bot '%v' (err: %v)\n", name, err)
+				}
+			})
+		}
+	}
+}
+
+// envOr returns an environment variable or a default value if it is unset
+func envOr(key string, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+// storedFile is a file output waiting to be downloaded
+type storedFile struct {
+	file    types.FileOutput
+	expires time.Time
+}
+
+// fileStore keeps file outputs in memory until they are downloaded or expire. The oldest files
+// are dropped when the store is full
+type fileStore struct {
+	mu       sync.Mutex
+	files    map[string]storedFile
+	order    []string      // File IDs from oldest to newest
+	capacity int           // The most files kept at once
+	ttl      time.Duration // How long files are kept
+}
+
+// newFileStore creates a new file store
+func newFileStore(capacity int, ttl time.Duration) *fileStore {
+	return &fileStore{
+		files:    map[string]storedFile{},
+		capacity: capacity,
+		ttl:      ttl,
+	}
+}
+
+// put stores a file and returns its ID
+func (s *fileStore) put(file types.FileOutput) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(raw)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(time.Now())
+	for len(s.order) >= s.capacity {
+		delete(s.files, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	s.files[id] = storedFile{file: file, expires: time.Now().Add(s.ttl)}
+	s.order = append(s.order, id)
+	return id, nil
+}
+
+// get returns a stored file
+func (s *fileStore) get(id string) (types.FileOutput, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(time.Now())
+	f, ok := s.files[id]
+	return f.file, ok
+}
+
+// prune removes expired files
+func (s *fileStore) prune(now time.Time) {
+	for len(s.order) > 0 {
+		f, ok := s.files[s.order[0]]
+		if ok && now.Before(f.expires) {
+			return
+		}
+
+		delete(s.files, s.order[0])
+		s.order = s.order[1:]
+	}
+}
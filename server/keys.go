@@ -0,0 +1,281 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/* ---- CONSTANTS ---- */
+
+// The prefix of every API key, so keys are easy to recognize
+const KEY_PREFIX = "horus_"
+
+// How many characters of a key are kept in plain text to identify it
+const KEY_HINT_LENGTH = len(KEY_PREFIX) + 6
+
+// Matches every bot or stream key in a key's allowed lists
+const KEY_WILDCARD = "*"
+
+/* ---- API KEYS ---- */
+
+// APIKey is the record of a key issued to an implementation. Only a hash of the key is stored
+type APIKey struct {
+	gorm.Model
+
+	Name        string     `gorm:"uniqueIndex;size:191"` // The implementation the key was issued to
+	Hint        string     // The start of the key, to tell keys apart
+	Hash        string     `gorm:"uniqueIndex;size:64"` // The SHA-256 hash of the key
+	Bots        []string   `gorm:"serializer:json"`     // The bots the key can use (or "*")
+	Permissions byte       // The most permissions messages sent with the key can have
+	Streams     []string   `gorm:"serializer:json"` // The outreach methods the key can stream (or "*")
+	RevokedAt   *time.Time // When the key was revoked, if it has been
+}
+
+// AllowsBot returns true if the key can use a bot
+func (k *APIKey) AllowsBot(name string) bool {
+	return allows(k.Bots, name)
+}
+
+// AllowsStream returns true if the key can subscribe to a stream key or stream replies to it
+func (k *APIKey) AllowsStream(key string) bool {
+	return allows(k.Streams, key)
+}
+
+// allows returns true if a value is in a list of allowed values
+func allows(allowed []string, value string) bool {
+	for _, a := range allowed {
+		if a == KEY_WILDCARD || a == value {
+			return true
+		}
+	}
+	return false
+}
+
+// HashKey hashes an API key for storage
+func HashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateKey creates a new random API key
+func generateKey() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	return KEY_PREFIX + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+/* ---- KEY MANAGEMENT ---- */
+
+// Errors returned for keys that cannot be used
+var (
+	ErrKeyNotFound = errors.New("api key does not exist")
+	ErrKeyRevoked  = errors.New("api key has been revoked")
+)
+
+// KeyStore stores API keys
+type KeyStore interface {
+	FindKey(hash string) (*APIKey, error) // Find a key by its hash
+	GetKey(name string) (*APIKey, error)  // Find a key by its name
+	ListKeys() ([]APIKey, error)          // List every key
+	SaveKey(key *APIKey) error            // Create or update a key
+}
+
+// CreateKey issues a new key for an implementation. The key is returned once and cannot be
+// recovered afterwards
+func CreateKey(store KeyStore, name string, bots []string, permissions byte, streams []string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("key name cannot be empty")
+	}
+	if _, err := store.GetKey(name); err == nil {
+		return "", fmt.Errorf("key with name '%v' already exists", name)
+	} else if !errors.Is(err, ErrKeyNotFound) {
+		return "", err
+	}
+
+	secret, err := generateKey()
+	if err != nil {
+		return "", err
+	}
+
+	return secret, store.SaveKey(&APIKey{
+		Name:        name,
+		Hint:        secret[:KEY_HINT_LENGTH],
+		Hash:        HashKey(secret),
+		Bots:        bots,
+		Permissions: permissions,
+		Streams:     streams,
+	})
+}
+
+// RotateKey replaces an implementation's key with a new one that has the same access. The old
+// key stops working immediately. Revoked keys are restored by rotating them
+func RotateKey(store KeyStore, name string) (string, error) {
+	key, err := store.GetKey(name)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := generateKey()
+	if err != nil {
+		return "", err
+	}
+
+	key.Hint = secret[:KEY_HINT_LENGTH]
+	key.Hash = HashKey(secret)
+	key.RevokedAt = nil
+	return secret, store.SaveKey(key)
+}
+
+// RevokeKey stops an implementation's key from working
+func RevokeKey(store KeyStore, name string) error {
+	key, err := store.GetKey(name)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+	return store.SaveKey(key)
+}
+
+// keyContext is the context key holding the API key of a request
+type keyContext struct{}
+
+// requestKey returns the API key a request was authenticated with, or nil if the API is open
+func requestKey(r *http.Request) *APIKey {
+	key, _ := r.Context().Value(keyContext{}).(*APIKey)
+	return key
+}
+
+// authenticate finds the active key matching a secret
+func authenticate(store KeyStore, secret string) (*APIKey, error) {
+	if !strings.HasPrefix(secret, KEY_PREFIX) {
+		return nil, ErrKeyNotFound
+	}
+
+	key, err := store.FindKey(HashKey(secret))
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrKeyRevoked
+	}
+
+	return key, nil
+}
+
+/* ---- STORES ---- */
+
+// SQLKeyStore stores keys in a SQL database
+type SQLKeyStore struct {
+	db *gorm.DB
+}
+
+// NewSQLKeyStore creates a key store in a database, creating its table if needed
+func NewSQLKeyStore(db *gorm.DB) (*SQLKeyStore, error) {
+	return &SQLKeyStore{db: db}, db.AutoMigrate(&APIKey{})
+}
+
+// FindKey finds a key by its hash
+func (s *SQLKeyStore) FindKey(hash string) (*APIKey, error) {
+	return s.first(map[string]any{"hash": hash})
+}
+
+// GetKey finds a key by its name
+func (s *SQLKeyStore) GetKey(name string) (*APIKey, error) {
+	return s.first(map[string]any{"name": name})
+}
+
+// ListKeys lists every key
+func (s *SQLKeyStore) ListKeys() ([]APIKey, error) {
+	keys := []APIKey{}
+	return keys, s.db.Order("name").Find(&keys).Error
+}
+
+// SaveKey creates or updates a key
+func (s *SQLKeyStore) SaveKey(key *APIKey) error {
+	return s.db.Save(key).Error
+}
+
+// first finds the first key matching the conditions
+func (s *SQLKeyStore) first(conditions map[string]any) (*APIKey, error) {
+	var key APIKey
+	err := s.db.Where(conditions).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrKeyNotFound
+	}
+
+	return &key, err
+}
+
+// MemoryKeyStore stores keys in memory. It is meant for tests and development
+type MemoryKeyStore struct {
+	mu   sync.Mutex
+	keys map[string]APIKey
+}
+
+// NewMemoryKeyStore creates an empty in-memory key store
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: map[string]APIKey{}}
+}
+
+// FindKey finds a key by its hash
+func (s *MemoryKeyStore) FindKey(hash string) (*APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.keys {
+		if k.Hash == hash {
+			return &k, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// GetKey finds a key by its name
+func (s *MemoryKeyStore) GetKey(name string) (*APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[name]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return &k, nil
+}
+
+// ListKeys lists every key
+func (s *MemoryKeyStore) ListKeys() ([]APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := []APIKey{}
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys, nil
+}
+
+// SaveKey creates or updates a key
+func (s *MemoryKeyStore) SaveKey(key *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.Name] = *key
+	return nil
+}
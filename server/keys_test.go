@@ -0,0 +1,105 @@
+package server_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethanbaker/horus/server"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// doWithKey sends a request with an API key
+func doWithKey(s http.Handler, key string, method string, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+// permissionBot records the input of the last message sent to it
+type permissionBot struct {
+	*fakeBot
+	last *types.Input
+}
+
+func (b *permissionBot) SendMessage(key string, input *types.Input) (*types.Output, error) {
+	b.last = input
+	return b.fakeBot.SendMessage(key, input)
+}
+
+func TestKeyManagement(t *testing.T) {
+	assert := assert.New(t)
+	store := server.NewMemoryKeyStore()
+
+	secret, err := server.CreateKey(store, "discord", []string{"horus"}, 0x01, []string{"discord"})
+	assert.Nil(err)
+	assert.True(strings.HasPrefix(secret, server.KEY_PREFIX))
+
+	// Only the hash is stored
+	key, err := store.GetKey("discord")
+	assert.Nil(err)
+	assert.Equal(server.HashKey(secret), key.Hash)
+	assert.NotContains(key.Hash, secret)
+	assert.Equal(secret[:len(key.Hint)], key.Hint)
+
+	_, err = server.CreateKey(store, "discord", nil, 0, nil)
+	assert.NotNil(err)
+
+	// Rotating keeps the key's access with a new secret
+	rotated, err := server.RotateKey(store, "discord")
+	assert.Nil(err)
+	assert.NotEqual(secret, rotated)
+
+	key, _ = store.GetKey("discord")
+	assert.Equal(server.HashKey(rotated), key.Hash)
+	assert.Equal([]string{"horus"}, key.Bots)
+
+	assert.Nil(server.RevokeKey(store, "discord"))
+	key, _ = store.GetKey("discord")
+	assert.NotNil(key.RevokedAt)
+
+	_, err = server.RotateKey(store, "missing")
+	assert.True(errors.Is(err, server.ErrKeyNotFound))
+}
+
+func TestKeyMiddleware(t *testing.T) {
+	assert := assert.New(t)
+
+	bot := &permissionBot{fakeBot: newFakeBot("horus")}
+	bot.AddConversation("test")
+
+	s := server.New(bot, newFakeBot("private"))
+	store := server.NewMemoryKeyStore()
+	s.RequireKeys(store)
+
+	secret, _ := server.CreateKey(store, "discord", []string{"horus"}, 0x01, []string{"discord"})
+
+	// Requests need a valid key
+	assert.Equal(http.StatusUnauthorized, doWithKey(s, "", "GET", "/api/bots", "").Code)
+	assert.Equal(http.StatusUnauthorized, doWithKey(s, "horus_wrong", "GET", "/api/bots", "").Code)
+	assert.Equal(http.StatusOK, doWithKey(s, secret, "GET", "/api/bots", "").Code)
+
+	// Keys only see their bots and streams
+	assert.Equal(`[{"name":"horus","permissions":255}]`+"\n", doWithKey(s, secret, "GET", "/api/bots", "").Body.String())
+	assert.Equal(http.StatusForbidden, doWithKey(s, secret, "GET", "/api/bots/private/conversations", "").Code)
+	assert.Equal(http.StatusForbidden, doWithKey(s, secret, "GET", "/api/stream/telegram", "").Code)
+	assert.Equal(http.StatusForbidden, doWithKey(s, secret, "POST", "/api/bots/horus/conversations/test/messages", `{"message":"hi","stream":"telegram"}`).Code)
+
+	// Messages are limited to the key's permissions and recorded under its name
+	rec := doWithKey(s, secret, "POST", "/api/bots/horus/conversations/test/messages", `{"message":"hi","caller":"user-1"}`)
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal(byte(0x01), bot.last.Permissions)
+	assert.Equal("discord:user-1", bot.last.Caller)
+
+	// Revoked keys stop working
+	server.RevokeKey(store, "discord")
+	assert.Equal(http.StatusUnauthorized, doWithKey(s, secret, "GET", "/api/bots", "").Code)
+}
@@ -0,0 +1,581 @@
+// Package server exposes Horus bots over a REST API so implementations can run as separate
+// processes against one Horus core.
+//
+// Routes (all bodies are JSON):
+//
+//	GET    /api/bots                                         List bots
+//	GET    /api/bots/{bot}                                   Describe a bot
+//	GET    /api/bots/{bot}/conversations                     List conversations
+//	POST   /api/bots/{bot}/conversations                     Create a conversation
+//	GET    /api/bots/{bot}/conversations/{key}               Describe a conversation
+//	PATCH  /api/bots/{bot}/conversations/{key}               Rename a conversation
+//	DELETE /api/bots/{bot}/conversations/{key}               Delete a conversation
+//	GET    /api/bots/{bot}/conversations/{key}/messages      Get a conversation's history
+//	POST   /api/bots/{bot}/conversations/{key}/messages      Send a message
+//	GET    /api/files/{id}                                   Download a file output
+//	GET    /api/stream/{method}                              Stream outreach and reply events (server-sent events)
+//
+// When API keys are required (see RequireKeys), requests authenticate with an
+// "Authorization: Bearer <key>" header
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* ---- CONSTANTS ---- */
+
+// The prefix of every API route
+const API_PREFIX = "/api/"
+
+// The largest request body accepted
+const MAX_BODY_SIZE = 1 << 20
+
+// How many file outputs are kept for download and for how long
+const (
+	FILE_CAPACITY = 256
+	FILE_TTL      = time.Hour
+)
+
+/* ---- SERVER ---- */
+
+// servedBot is a bot along with the lock that serializes requests to it (bots are not safe for
+// concurrent use)
+type servedBot struct {
+	mu  sync.Mutex
+	bot Bot
+}
+
+// Server serves Horus bots over HTTP
+type Server struct {
+	mu    sync.RWMutex
+	bots  map[string]*servedBot
+	files *fileStore
+	hubs  map[types.OutreachMethod]*hub
+	keys  KeyStore // The API keys requests must use (nil if the API is open)
+}
+
+// New creates a server for the given bots
+func New(bots ...Bot) *Server {
+	s := &Server{
+		bots:  map[string]*servedBot{},
+		files: newFileStore(FILE_CAPACITY, FILE_TTL),
+		hubs:  map[types.OutreachMethod]*hub{},
+	}
+
+	for _, b := range bots {
+		s.AddBot(b)
+	}
+	return s
+}
+
+// AddBot serves a bot under its name, replacing any bot with the same name
+func (s *Server) AddBot(b Bot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bots[b.Info().Name] = &servedBot{bot: b}
+}
+
+// Do calls a function with a served bot while no requests are using it. It returns false if the
+// bot does not exist
+func (s *Server) Do(name string, fn func(b Bot)) bool {
+	b := s.bot(name)
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	fn(b.bot)
+	return true
+}
+
+// Every calls a function with a served bot every interval, like Do. It returns immediately
+func (s *Server) Every(name string, interval time.Duration, fn func(b Bot)) {
+	go func() {
+		for range time.Tick(interval) {
+			s.Do(name, fn)
+		}
+	}()
+}
+
+// RequireKeys makes every request authenticate with an API key from the store. Each key limits
+// the bots, permissions and streams its requests can use
+func (s *Server) RequireKeys(store KeyStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = store
+}
+
+// ServeHTTP authenticates and routes an API request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	store := s.keys
+	s.mu.RUnlock()
+
+	if store != nil {
+		secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing api key"))
+			return
+		}
+
+		key, err := authenticate(store, secret)
+		if errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrKeyRevoked) {
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), keyContext{}, key))
+	}
+
+	s.serve(w, r)
+}
+
+// serve routes an API request
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	path, ok := strings.CutPrefix(r.URL.EscapedPath(), API_PREFIX)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+		return
+	}
+
+	// Split the path into unescaped segments
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range segments {
+		unescaped, err := url.PathUnescape(seg)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid path"))
+			return
+		}
+		segments[i] = unescaped
+	}
+
+	switch {
+	case len(segments) == 2 && segments[0] == "files":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) { s.getFile(w, segments[1]) },
+		})
+
+	case len(segments) == 2 && segments[0] == "stream":
+		if key := requestKey(r); key != nil && !key.AllowsStream(segments[1]) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("api key cannot use stream '%v'", segments[1]))
+			return
+		}
+
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+				s.stream(w, r, types.OutreachMethod(segments[1]))
+			},
+		})
+
+	case len(segments) == 1 && segments[0] == "bots":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: s.listBots,
+		})
+
+	case len(segments) >= 2 && segments[0] == "bots":
+		if key := requestKey(r); key != nil && !key.AllowsBot(segments[1]) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("api key cannot use bot '%v'", segments[1]))
+			return
+		}
+
+		b := s.bot(segments[1])
+		if b == nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("bot '%v' does not exist", segments[1]))
+			return
+		}
+
+		// Requests to a bot are handled one at a time
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		s.routeBot(w, r, b.bot, segments[2:])
+
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+	}
+}
+
+// routeBot routes a request for a specific bot
+func (s *Server) routeBot(w http.ResponseWriter, r *http.Request, b Bot, segments []string) {
+	switch {
+	case len(segments) == 0:
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) { writeJSON(w, http.StatusOK, b.Info()) },
+		})
+
+	case len(segments) == 1 && segments[0] == "conversations":
+		s.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:  func(w http.ResponseWriter, r *http.Request) { s.listConversations(w, b) },
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) { s.createConversation(w, r, b) },
+		})
+
+	case len(segments) >= 2 && segments[0] == "conversations":
+		key := segments[1]
+		if !b.IsConversation(key) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("conversation '%v' does not exist", key))
+			return
+		}
+
+		switch {
+		case len(segments) == 2:
+			s.route(w, r, map[string]http.HandlerFunc{
+				http.MethodGet:    func(w http.ResponseWriter, r *http.Request) { s.getConversation(w, b, key) },
+				http.MethodPatch:  func(w http.ResponseWriter, r *http.Request) { s.renameConversation(w, r, b, key) },
+				http.MethodDelete: func(w http.ResponseWriter, r *http.Request) { s.deleteConversation(w, b, key) },
+			})
+
+		case len(segments) == 3 && segments[2] == "messages":
+			s.route(w, r, map[string]http.HandlerFunc{
+				http.MethodGet:  func(w http.ResponseWriter, r *http.Request) { s.getHistory(w, b, key) },
+				http.MethodPost: func(w http.ResponseWriter, r *http.Request) { s.sendMessage(w, r, b, key) },
+			})
+
+		default:
+			writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+		}
+
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+	}
+}
+
+// route calls the handler for the request's method
+func (s *Server) route(w http.ResponseWriter, r *http.Request, handlers map[string]http.HandlerFunc) {
+	if h, ok := handlers[r.Method]; ok {
+		h(w, r)
+		return
+	}
+
+	allowed := []string{}
+	for method := range handlers {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+}
+
+// bot returns a served bot by name
+func (s *Server) bot(name string) *servedBot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.bots[name]
+}
+
+/* ---- HANDLERS ---- */
+
+// listBots lists every served bot
+func (s *Server) listBots(w http.ResponseWriter, r *http.Request) {
+	key := requestKey(r)
+
+	s.mu.RLock()
+	bots := []types.APIBot{}
+	for name, b := range s.bots {
+		if key == nil || key.AllowsBot(name) {
+			bots = append(bots, b.bot.Info())
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(bots, func(i, j int) bool { return bots[i].Name < bots[j].Name })
+	writeJSON(w, http.StatusOK, bots)
+}
+
+// listConversations lists a bot's conversations
+func (s *Server) listConversations(w http.ResponseWriter, b Bot) {
+	conversations, err := b.ListConversations()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, conversations)
+}
+
+// createConversation creates a conversation
+func (s *Server) createConversation(w http.ResponseWriter, r *http.Request, b Bot) {
+	var req types.APIConversationRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Key == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("conversation key cannot be empty"))
+		return
+	}
+	if b.IsConversation(req.Key) {
+		writeError(w, http.StatusConflict, fmt.Errorf("conversation '%v' already exists", req.Key))
+		return
+	}
+
+	// Conversations are either new or copies of another conversation
+	if req.Fork != "" {
+		if !b.IsConversation(req.Fork) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("conversation '%v' does not exist", req.Fork))
+			return
+		}
+		if err := b.ForkConversation(req.Fork, req.Key); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	} else if err := b.AddConversation(req.Key); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	c, err := findConversation(b, req.Key)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, c)
+}
+
+// renameConversation changes the key of a conversation
+func (s *Server) renameConversation(w http.ResponseWriter, r *http.Request, b Bot, key string) {
+	var req types.APIConversationUpdate
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Key == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("conversation key cannot be empty"))
+		return
+	}
+	if req.Key != key && b.IsConversation(req.Key) {
+		writeError(w, http.StatusConflict, fmt.Errorf("conversation '%v' already exists", req.Key))
+		return
+	}
+
+	if req.Key != key {
+		if err := b.RenameConversation(key, req.Key); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	s.getConversation(w, b, req.Key)
+}
+
+// getConversation describes a conversation
+func (s *Server) getConversation(w http.ResponseWriter, b Bot, key string) {
+	c, err := findConversation(b, key)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, c)
+}
+
+// deleteConversation deletes a conversation
+func (s *Server) deleteConversation(w http.ResponseWriter, b Bot, key string) {
+	if err := b.DeleteConversation(key); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getHistory gets the messages in a conversation
+func (s *Server) getHistory(w http.ResponseWriter, b Bot, key string) {
+	messages, err := b.History(key)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, messages)
+}
+
+// sendMessage sends a message to a conversation and returns the bot's response
+func (s *Server) sendMessage(w http.ResponseWriter, r *http.Request, b Bot, key string) {
+	var req types.APIMessageRequest
+	if err := readJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Messages are sent with every permission unless the caller limits them (the bot's own
+	// permissions still apply)
+	permissions := req.Permissions
+	if permissions == 0 {
+		permissions = 0xFF
+	}
+	caller := req.Caller
+
+	// Requests with a key are limited to the key's permissions and streams, and are recorded
+	// under the key's name
+	if key := requestKey(r); key != nil {
+		if req.Stream != "" && !key.AllowsStream(string(req.Stream)) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("api key cannot use stream '%v'", req.Stream))
+			return
+		}
+
+		permissions &= key.Permissions
+		if caller == "" {
+			caller = key.Name
+		} else {
+			caller = key.Name + ":" + caller
+		}
+	}
+
+	input := &types.Input{
+		Message:     req.Message,
+		Permissions: permissions,
+		Caller:      caller,
+	}
+
+	// Stream the reply to subscribers of the requested key
+	name := b.Info().Name
+	if req.Stream != "" {
+		input.OnToken = func(token string) {
+			s.publish(req.Stream, types.EVENT_TOKEN, types.APIToken{Bot: name, Conversation: key, Token: token})
+		}
+	}
+
+	// Errors in the output (such as a module's error) are returned with the output
+	output, err := b.SendMessage(key, input)
+	if err != nil && (output == nil || output.Error == nil) {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res, err := s.toAPIOutput(output)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if req.Stream != "" {
+		s.publish(req.Stream, types.EVENT_REPLY, types.APIReply{Bot: name, Conversation: key, Output: res})
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// getFile downloads a stored file output
+func (s *Server) getFile(w http.ResponseWriter, id string) {
+	file, ok := s.files.get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("file '%v' does not exist", id))
+		return
+	}
+
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(file.Content)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(file.Content)
+}
+
+/* ---- HELPERS ---- */
+
+// toAPIOutput converts a bot's output to its wire format, storing files for download
+func (s *Server) toAPIOutput(output *types.Output) (types.APIOutput, error) {
+	res := types.APIOutput{
+		Message: output.Message,
+		Blocks:  output.Blocks,
+		Actions: output.Actions,
+	}
+	if output.Error != nil {
+		res.Error = output.Error.Error()
+	}
+
+	// Files are sent separately, so they are not repeated in the data
+	if _, ok := output.Data.(types.FileOutput); !ok {
+		res.Data = output.Data
+	}
+
+	for _, file := range output.Files() {
+		id, err := s.files.put(file)
+		if err != nil {
+			return res, err
+		}
+
+		res.Files = append(res.Files, types.APIFile{
+			ID:          id,
+			Filename:    file.Filename,
+			ContentType: file.ContentType,
+			Size:        len(file.Content),
+			URL:         API_PREFIX + "files/" + id,
+		})
+	}
+
+	return res, nil
+}
+
+// findConversation describes one of a bot's conversations
+func findConversation(b Bot, key string) (types.APIConversation, error) {
+	conversations, err := b.ListConversations()
+	if err != nil {
+		return types.APIConversation{}, err
+	}
+
+	for _, c := range conversations {
+		if c.Key == key {
+			return c, nil
+		}
+	}
+	return types.APIConversation{}, fmt.Errorf("conversation '%v' does not exist", key)
+}
+
+// readJSON decodes a request's JSON body
+func readJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_BODY_SIZE))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("[ERROR]: In server, error writing response (err: %v)\n", err)
+	}
+}
+
+// writeError writes an error response. Server errors are logged
+func writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		log.Printf("[ERROR]: In server, error handling request (err: %v)\n", err)
+	}
+
+	writeJSON(w, status, types.APIError{Error: err.Error()})
+}
@@ -0,0 +1,360 @@
+package server_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethanbaker/horus/server"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBot is an in-memory bot that echoes messages
+type fakeBot struct {
+	name          string
+	conversations map[string][]types.APIMessage
+}
+
+func newFakeBot(name string) *fakeBot {
+	return &fakeBot{name: name, conversations: map[string][]types.APIMessage{}}
+}
+
+func (b *fakeBot) Info() types.APIBot {
+	return types.APIBot{Name: b.name, Permissions: 0xFF}
+}
+
+func (b *fakeBot) ListConversations() ([]types.APIConversation, error) {
+	conversations := []types.APIConversation{}
+	for key, messages := range b.conversations {
+		conversations = append(conversations, types.APIConversation{Key: key, Messages: len(messages), CreatedAt: time.Unix(0, 0)})
+	}
+	return conversations, nil
+}
+
+func (b *fakeBot) IsConversation(key string) bool {
+	_, ok := b.conversations[key]
+	return ok
+}
+
+func (b *fakeBot) AddConversation(key string) error {
+	b.conversations[key] = []types.APIMessage{}
+	return nil
+}
+
+func (b *fakeBot) DeleteConversation(key string) error {
+	delete(b.conversations, key)
+	return nil
+}
+
+func (b *fakeBot) RenameConversation(key string, newKey string) error {
+	b.conversations[newKey] = b.conversations[key]
+	delete(b.conversations, key)
+	return nil
+}
+
+func (b *fakeBot) ForkConversation(key string, newKey string) error {
+	b.conversations[newKey] = append([]types.APIMessage{}, b.conversations[key]...)
+	return nil
+}
+
+func (b *fakeBot) History(key string) ([]types.APIMessage, error) {
+	return b.conversations[key], nil
+}
+
+func (b *fakeBot) SendMessage(key string, input *types.Input) (*types.Output, error) {
+	if input.Message == "fail" {
+		return nil, fmt.Errorf("failed")
+	}
+
+	b.conversations[key] = append(b.conversations[key], types.APIMessage{Role: "user", Content: input.Message})
+	if input.OnToken != nil {
+		for _, word := range strings.SplitAfter(input.Message, " ") {
+			input.OnToken(word)
+		}
+	}
+
+	output := &types.Output{Message: "echo: " + input.Message}
+	switch input.Message {
+	case "file":
+		output.Attachments = []types.FileOutput{{Filename: "a.txt", ContentType: "text/plain", Content: []byte("hello")}}
+	case "output error":
+		output.Error = fmt.Errorf("module failed")
+		output.Actions = types.ConfirmActions
+	}
+	return output, output.Error
+}
+
+// do sends a request to the server and decodes the JSON response
+func do(s http.Handler, method string, path string, body string, v any) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if v != nil {
+		json.Unmarshal(rec.Body.Bytes(), v)
+	}
+	return rec
+}
+
+func TestConversations(t *testing.T) {
+	assert := assert.New(t)
+	s := server.New(newFakeBot("horus"))
+
+	var bots []types.APIBot
+	assert.Equal(http.StatusOK, do(s, "GET", "/api/bots", "", &bots).Code)
+	assert.Equal([]types.APIBot{{Name: "horus", Permissions: 0xFF}}, bots)
+
+	// Create a conversation
+	var c types.APIConversation
+	assert.Equal(http.StatusCreated, do(s, "POST", "/api/bots/horus/conversations", `{"key":"test"}`, &c).Code)
+	assert.Equal("test", c.Key)
+
+	// Duplicate and invalid conversations are rejected
+	assert.Equal(http.StatusConflict, do(s, "POST", "/api/bots/horus/conversations", `{"key":"test"}`, nil).Code)
+	assert.Equal(http.StatusBadRequest, do(s, "POST", "/api/bots/horus/conversations", `{"key":""}`, nil).Code)
+	assert.Equal(http.StatusBadRequest, do(s, "POST", "/api/bots/horus/conversations", `{"name":"x"}`, nil).Code)
+
+	var conversations []types.APIConversation
+	assert.Equal(http.StatusOK, do(s, "GET", "/api/bots/horus/conversations", "", &conversations).Code)
+	assert.Equal(1, len(conversations))
+
+	// Delete the conversation
+	assert.Equal(http.StatusNoContent, do(s, "DELETE", "/api/bots/horus/conversations/test", "", nil).Code)
+	assert.Equal(http.StatusNotFound, do(s, "GET", "/api/bots/horus/conversations/test", "", nil).Code)
+}
+
+func TestForkAndRename(t *testing.T) {
+	assert := assert.New(t)
+	s := server.New(newFakeBot("horus"))
+
+	do(s, "POST", "/api/bots/horus/conversations", `{"key":"a"}`, nil)
+	do(s, "POST", "/api/bots/horus/conversations/a/messages", `{"message":"hi"}`, nil)
+
+	// Forks copy the conversation's messages
+	var c types.APIConversation
+	assert.Equal(http.StatusCreated, do(s, "POST", "/api/bots/horus/conversations", `{"key":"b","fork":"a"}`, &c).Code)
+	assert.Equal("b", c.Key)
+	assert.Equal(1, c.Messages)
+	assert.Equal(http.StatusNotFound, do(s, "POST", "/api/bots/horus/conversations", `{"key":"c","fork":"x"}`, nil).Code)
+
+	// Renaming moves the conversation to its new key
+	assert.Equal(http.StatusOK, do(s, "PATCH", "/api/bots/horus/conversations/b", `{"key":"c"}`, &c).Code)
+	assert.Equal("c", c.Key)
+	assert.Equal(http.StatusNotFound, do(s, "GET", "/api/bots/horus/conversations/b", "", nil).Code)
+	assert.Equal(http.StatusConflict, do(s, "PATCH", "/api/bots/horus/conversations/c", `{"key":"a"}`, nil).Code)
+	assert.Equal(http.StatusBadRequest, do(s, "PATCH", "/api/bots/horus/conversations/c", `{"key":""}`, nil).Code)
+}
+
+func TestMessages(t *testing.T) {
+	assert := assert.New(t)
+	s := server.New(newFakeBot("horus"))
+	do(s, "POST", "/api/bots/horus/conversations", `{"key":"a b"}`, nil)
+
+	// Keys are unescaped from the path
+	var output types.APIOutput
+	assert.Equal(http.StatusOK, do(s, "POST", "/api/bots/horus/conversations/a%20b/messages", `{"message":"hi"}`, &output).Code)
+	assert.Equal("echo: hi", output.Message)
+
+	var history []types.APIMessage
+	assert.Equal(http.StatusOK, do(s, "GET", "/api/bots/horus/conversations/a%20b/messages", "", &history).Code)
+	assert.Equal("hi", history[0].Content)
+
+	// Errors from the bot are returned as API errors
+	var apiErr types.APIError
+	assert.Equal(http.StatusInternalServerError, do(s, "POST", "/api/bots/horus/conversations/a%20b/messages", `{"message":"fail"}`, &apiErr).Code)
+	assert.Equal("failed", apiErr.Error)
+
+	// Errors in the output are returned with the rest of the output
+	output = types.APIOutput{}
+	assert.Equal(http.StatusOK, do(s, "POST", "/api/bots/horus/conversations/a%20b/messages", `{"message":"output error"}`, &output).Code)
+	assert.Equal("echo: output error", output.Message)
+	assert.Equal("module failed", output.Error)
+	assert.Equal(types.ConfirmActions, output.Actions)
+}
+
+func TestFiles(t *testing.T) {
+	assert := assert.New(t)
+	s := server.New(newFakeBot("horus"))
+	do(s, "POST", "/api/bots/horus/conversations", `{"key":"test"}`, nil)
+
+	var output types.APIOutput
+	do(s, "POST", "/api/bots/horus/conversations/test/messages", `{"message":"file"}`, &output)
+	assert.Equal(1, len(output.Files))
+	assert.Equal("a.txt", output.Files[0].Filename)
+	assert.Equal(5, output.Files[0].Size)
+
+	// Download the file
+	rec := do(s, "GET", output.Files[0].URL, "", nil)
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("text/plain", rec.Header().Get("Content-Type"))
+	body, _ := io.ReadAll(rec.Body)
+	assert.Equal("hello", string(body))
+
+	assert.Equal(http.StatusNotFound, do(s, "GET", "/api/files/missing", "", nil).Code)
+}
+
+func TestRouting(t *testing.T) {
+	assert := assert.New(t)
+	s := server.New(newFakeBot("horus"))
+
+	assert.Equal(http.StatusNotFound, do(s, "GET", "/api/bots/missing", "", nil).Code)
+	assert.Equal(http.StatusNotFound, do(s, "GET", "/other", "", nil).Code)
+	assert.Equal(http.StatusNotFound, do(s, "GET", "/api/bots/horus/conversations/missing/messages", "", nil).Code)
+
+	rec := do(s, "PUT", "/api/bots/horus/conversations", "", nil)
+	assert.Equal(http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal("GET, POST", rec.Header().Get("Allow"))
+}
+
+// readEvent reads a server-sent event from a stream, skipping comments
+func readEvent(reader *bufio.Reader) (id string, name string, data string) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// subscribe opens a stream, resuming after lastID if it is set
+func subscribe(t *testing.T, url string, lastID string) (*http.Response, *bufio.Reader) {
+	req, _ := http.NewRequest("GET", url, nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	return res, bufio.NewReader(res.Body)
+}
+
+func TestStreamOutreach(t *testing.T) {
+	assert := assert.New(t)
+	s := server.New()
+
+	ch := make(chan string)
+	s.AddOutreach(types.Discord, ch)
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, reader := subscribe(t, ts.URL+"/api/stream/discord", "")
+	assert.Equal("text/event-stream", res.Header.Get("Content-Type"))
+
+	ch <- "first"
+	id, name, data := readEvent(reader)
+	assert.Equal("1", id)
+	assert.Equal(types.EVENT_OUTREACH, name)
+
+	var message types.APIOutreach
+	assert.Nil(json.Unmarshal([]byte(data), &message))
+	assert.Equal("first", message.Message)
+	assert.Equal(types.Discord, message.Method)
+	res.Body.Close()
+
+	// Events sent while disconnected are replayed after the last event received
+	ch <- "second"
+	ch <- "third"
+
+	res, reader = subscribe(t, ts.URL+"/api/stream/discord", "1")
+	defer res.Body.Close()
+
+	id, _, data = readEvent(reader)
+	assert.Equal("2", id)
+	assert.Contains(data, "second")
+
+	id, _, data = readEvent(reader)
+	assert.Equal("3", id)
+	assert.Contains(data, "third")
+}
+
+func TestStreamNoBacklog(t *testing.T) {
+	assert := assert.New(t)
+	s := server.New()
+
+	ch := make(chan string)
+	s.AddOutreach(types.Discord, ch)
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// Send events before anyone is subscribed
+	res, reader := subscribe(t, ts.URL+"/api/stream/discord", "")
+	ch <- "old"
+	readEvent(reader)
+	res.Body.Close()
+
+	// New subscribers and stale IDs only get new events
+	for _, lastID := range []string{"", "100"} {
+		res, reader = subscribe(t, ts.URL+"/api/stream/discord", lastID)
+		ch <- "new " + lastID
+
+		_, _, data := readEvent(reader)
+		assert.Contains(data, "new "+lastID)
+		res.Body.Close()
+	}
+}
+
+func TestStreamReply(t *testing.T) {
+	assert := assert.New(t)
+	s := server.New(newFakeBot("horus"))
+	do(s, "POST", "/api/bots/horus/conversations", `{"key":"test"}`, nil)
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, reader := subscribe(t, ts.URL+"/api/stream/terminal", "")
+	defer res.Body.Close()
+
+	var output types.APIOutput
+	do(s, "POST", "/api/bots/horus/conversations/test/messages", `{"message":"hi there","stream":"terminal"}`, &output)
+	assert.Equal("echo: hi there", output.Message)
+
+	// Each token is streamed, followed by the complete reply
+	tokens := []string{}
+	for {
+		_, name, data := readEvent(reader)
+		if name == types.EVENT_REPLY {
+			var reply types.APIReply
+			assert.Nil(json.Unmarshal([]byte(data), &reply))
+			assert.Equal(output, reply.Output)
+			assert.Equal("test", reply.Conversation)
+			break
+		}
+
+		var token types.APIToken
+		assert.Nil(json.Unmarshal([]byte(data), &token))
+		tokens = append(tokens, token.Token)
+	}
+	assert.Equal([]string{"hi ", "there"}, tokens)
+}
+
+func TestEvery(t *testing.T) {
+	assert := assert.New(t)
+	s := server.New(newFakeBot("horus"))
+
+	// The function is called with the bot every interval
+	called := make(chan string)
+	s.Every("horus", time.Millisecond, func(b server.Bot) { called <- b.Info().Name })
+	assert.Equal("horus", <-called)
+	assert.Equal("horus", <-called)
+}
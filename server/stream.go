@@ -0,0 +1,197 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+/* ---- CONSTANTS ---- */
+
+// How many events are kept for clients resuming a stream
+const STREAM_BUFFER_SIZE = 256
+
+// How often a comment is sent on idle streams to keep connections open
+const STREAM_HEARTBEAT = 15 * time.Second
+
+// How many events can wait for a slow subscriber before it is disconnected
+const STREAM_SUBSCRIBER_BUFFER = 64
+
+/* ---- HUB ---- */
+
+// streamEvent is an event sent to stream subscribers
+type streamEvent struct {
+	id   uint64
+	name string
+	data []byte
+}
+
+// hub delivers the events for one stream key (an outreach method) to its subscribers. Recent
+// events are kept in a ring buffer so clients can resume after reconnecting
+type hub struct {
+	mu          sync.Mutex
+	lastID      uint64                    // The ID of the newest event
+	buffer      []streamEvent             // Recent events (a ring buffer once full)
+	start       int                       // The index of the oldest event in the buffer
+	subscribers map[chan streamEvent]bool // Channels of connected clients
+}
+
+// newHub creates an empty hub
+func newHub() *hub {
+	return &hub{subscribers: map[chan streamEvent]bool{}}
+}
+
+// publish sends an event to every subscriber and keeps it for resuming clients
+func (h *hub) publish(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	e := streamEvent{id: h.lastID, name: name, data: data}
+
+	// Keep the event
+	if len(h.buffer) < STREAM_BUFFER_SIZE {
+		h.buffer = append(h.buffer, e)
+	} else {
+		h.buffer[h.start] = e
+		h.start = (h.start + 1) % len(h.buffer)
+	}
+
+	// Subscribers that fall too far behind are disconnected (their channel is closed) and can
+	// resume from the buffer
+	for sub := range h.subscribers {
+		select {
+		case sub <- e:
+		default:
+			delete(h.subscribers, sub)
+			close(sub)
+		}
+	}
+
+	return nil
+}
+
+// subscribe adds a subscriber. Clients resuming a stream get the buffered events after lastID
+// to replay first. New clients and IDs newer than any sent (such as ones from before a restart)
+// only get new events. The returned function removes the subscriber
+func (h *hub) subscribe(lastID uint64, resume bool) ([]streamEvent, chan streamEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	replay := []streamEvent{}
+	for i := 0; resume && lastID <= h.lastID && i < len(h.buffer); i++ {
+		e := h.buffer[(h.start+i)%len(h.buffer)]
+		if e.id > lastID {
+			replay = append(replay, e)
+		}
+	}
+
+	sub := make(chan streamEvent, STREAM_SUBSCRIBER_BUFFER)
+	h.subscribers[sub] = true
+
+	return replay, sub, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if h.subscribers[sub] {
+			delete(h.subscribers, sub)
+			close(sub)
+		}
+	}
+}
+
+/* ---- SERVER ---- */
+
+// hub returns the hub for a stream key, creating it if needed
+func (s *Server) hub(key types.OutreachMethod) *hub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.hubs[key]
+	if !ok {
+		h = newHub()
+		s.hubs[key] = h
+	}
+	return h
+}
+
+// publish sends an event to the subscribers of a stream key
+func (s *Server) publish(key types.OutreachMethod, name string, v any) {
+	if err := s.hub(key).publish(name, v); err != nil {
+		log.Printf("[ERROR]: In server, error publishing %v event (err: %v)\n", name, err)
+	}
+}
+
+// AddOutreach streams messages from an outreach channel (see outreach.AddChannel) to clients
+// subscribed to the method
+func (s *Server) AddOutreach(method types.OutreachMethod, ch <-chan string) {
+	go func() {
+		for message := range ch {
+			s.publish(method, types.EVENT_OUTREACH, types.APIOutreach{
+				Method:  method,
+				Message: message,
+				SentAt:  time.Now(),
+			})
+		}
+	}()
+}
+
+// stream sends the events for a stream key as server-sent events until the client disconnects.
+// Clients resume by sending the ID of the last event they received in the Last-Event-ID header
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, key types.OutreachMethod) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	lastID, err := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	replay, sub, unsubscribe := s.hub(key).subscribe(lastID, err == nil)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for _, e := range replay {
+		writeEvent(w, e)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(STREAM_HEARTBEAT)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case e, ok := <-sub:
+			if !ok {
+				return
+			}
+			writeEvent(w, e)
+			flusher.Flush()
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeEvent writes a server-sent event
+func writeEvent(w http.ResponseWriter, e streamEvent) {
+	fmt.Fprintf(w, "id: %d\nevent: %v\ndata: %s\n\n", e.id, e.name, e.data)
+}
@@ -1,22 +1,12 @@
+// Package format parses the markup used in Horus responses and renders it for different
+// implementations.
+//
+// Horus markup wraps text in tags such as <STRONG>, <EM>, <INS>, <DEL>, <SPOILER>, <CODE_IN>,
+// <CODE>, <BLOCKQUOTE_IN> and <BLOCKQUOTE>. See Parse for the full syntax and Escape for
+// embedding user content
 package format
 
-import "strings"
-
 // FormatDiscord turns a library response from Horus into one for Discord
 func FormatDiscord(res string) string {
-	output := res
-
-	output = strings.ReplaceAll(output, "<STRONG>", "**")
-	output = strings.ReplaceAll(output, "<EM>", "*")
-	output = strings.ReplaceAll(output, "<EM>", "*")
-	output = strings.ReplaceAll(output, "<INS>", "__")
-	output = strings.ReplaceAll(output, "<DEL>", "~~")
-	output = strings.ReplaceAll(output, "<DEL>", "~~")
-	output = strings.ReplaceAll(output, "<BLOCKQUOTE_IN>", "> ")
-	output = strings.ReplaceAll(output, "<BLOCKQUOTE>", ">>> ")
-	output = strings.ReplaceAll(output, "<CODE_IN>", "`")
-	output = strings.ReplaceAll(output, "<CODE>", "```")
-	output = strings.ReplaceAll(output, "<SPOILER>", "||")
-
-	return output
+	return Render(res, Discord)
 }
@@ -0,0 +1,270 @@
+package format
+
+import (
+	"regexp"
+	"strings"
+)
+
+/* ---- AST ---- */
+
+// NodeType represents the kind of a node in a markup tree
+type NodeType string
+
+const (
+	DocumentNode      NodeType = "document"      // The root of a tree
+	TextNode          NodeType = "text"          // Plain text
+	StrongNode        NodeType = "strong"        // <STRONG>
+	EmphasisNode      NodeType = "emphasis"      // <EM>
+	UnderlineNode     NodeType = "underline"     // <INS>
+	StrikethroughNode NodeType = "strikethrough" // <DEL>
+	SpoilerNode       NodeType = "spoiler"       // <SPOILER>
+	InlineCodeNode    NodeType = "inline_code"   // <CODE_IN>
+	CodeBlockNode     NodeType = "code_block"    // <CODE>
+	QuoteNode         NodeType = "quote"         // <BLOCKQUOTE_IN> (ends at the end of the line)
+	BlockQuoteNode    NodeType = "block_quote"   // <BLOCKQUOTE> (ends at the end of the message)
+)
+
+// Node is a node in a markup tree
+type Node struct {
+	Type     NodeType // The kind of node
+	Text     string   // The content of text and code nodes
+	Language string   // The language of a code block, if one was given
+	Literal  bool     // Whether a text node came from escaped user content
+	Children []*Node  // The children of formatting nodes
+}
+
+/* ---- PARSER ---- */
+
+// The tag used to wrap escaped user content
+const literalTag = "LITERAL"
+
+// Tags recognized in Horus markup and the nodes they create
+var tags = map[string]NodeType{
+	"STRONG":        StrongNode,
+	"EM":            EmphasisNode,
+	"INS":           UnderlineNode,
+	"DEL":           StrikethroughNode,
+	"SPOILER":       SpoilerNode,
+	"CODE_IN":       InlineCodeNode,
+	"CODE":          CodeBlockNode,
+	"BLOCKQUOTE_IN": QuoteNode,
+	"BLOCKQUOTE":    BlockQuoteNode,
+}
+
+// Matches a markup tag at the start of a string
+var tagRegex = regexp.MustCompile(`^<(/?)([A-Z_]+)>`)
+
+// Matches the language at the start of a code block
+var languageRegex = regexp.MustCompile(`^([A-Za-z0-9_+#-]+)\n`)
+
+// Escape makes user content safe to embed in Horus markup. The content is shown exactly as
+// written: tags inside of it are not parsed and target syntax (such as markdown) is escaped
+func Escape(content string) string {
+	content = strings.ReplaceAll(content, `\`, `\\`)
+	content = strings.ReplaceAll(content, "<", `\<`)
+	return "<" + literalTag + ">" + content + "</" + literalTag + ">"
+}
+
+// Parse parses Horus markup into a tree. Tags toggle their formatting (<STRONG>a<STRONG>) and
+// can also be closed explicitly (<STRONG>a</STRONG>). Text inside code tags is not parsed,
+// quotes started with <BLOCKQUOTE_IN> end at the end of the line, and tags that are left open
+// are closed at the end of the message. Unknown tags are kept as text, and "\<" can be used to
+// write a literal "<"
+func Parse(markup string) *Node {
+	p := parser{
+		root: &Node{Type: DocumentNode},
+	}
+	p.stack = []*Node{p.root}
+
+	for i := 0; i < len(markup); {
+		top := p.top()
+
+		// Code is not parsed until its closing tag
+		if top.Type == InlineCodeNode || top.Type == CodeBlockNode {
+			i = p.parseCode(markup, i)
+			continue
+		}
+
+		switch {
+		// Escaped user content
+		case strings.HasPrefix(markup[i:], "<"+literalTag+">"):
+			p.flush()
+			i = p.parseLiteral(markup, i+len(literalTag)+2)
+
+		// An escaped '<'
+		case strings.HasPrefix(markup[i:], `\<`):
+			p.text.WriteByte('<')
+			i += 2
+
+		// Line quotes end at the end of the line
+		case markup[i] == '\n' && p.find(QuoteNode) != -1:
+			p.flush()
+			p.close(QuoteNode, false)
+			p.text.WriteByte('\n')
+			i++
+
+		case markup[i] == '<':
+			m := tagRegex.FindStringSubmatch(markup[i:])
+			nodeType, ok := tags[safeIndex(m, 2)]
+			if !ok {
+				p.text.WriteByte('<')
+				i++
+				continue
+			}
+
+			p.flush()
+			explicitClose := m[1] == "/"
+			if p.find(nodeType) != -1 {
+				p.close(nodeType, true)
+			} else if explicitClose {
+				// Closing a tag that isn't open is kept as text
+				p.text.WriteString(m[0])
+			} else {
+				p.open(nodeType)
+			}
+			i += len(m[0])
+
+		default:
+			p.text.WriteByte(markup[i])
+			i++
+		}
+	}
+
+	p.flush()
+	prune(p.root)
+	return p.root
+}
+
+// parser holds the state of a parse
+type parser struct {
+	root  *Node
+	stack []*Node         // Open nodes, starting with the root
+	text  strings.Builder // Text that has not been added to the tree yet
+}
+
+// top returns the innermost open node
+func (p *parser) top() *Node {
+	return p.stack[len(p.stack)-1]
+}
+
+// find returns the index of the innermost open node of a type, or -1 if none is open
+func (p *parser) find(t NodeType) int {
+	for i := len(p.stack) - 1; i > 0; i-- {
+		if p.stack[i].Type == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// flush adds buffered text to the innermost open node
+func (p *parser) flush() {
+	if p.text.Len() == 0 {
+		return
+	}
+
+	top := p.top()
+	top.Children = append(top.Children, &Node{Type: TextNode, Text: p.text.String()})
+	p.text.Reset()
+}
+
+// open starts a new node of a type inside of the innermost open node
+func (p *parser) open(t NodeType) {
+	n := &Node{Type: t}
+	top := p.top()
+	top.Children = append(top.Children, n)
+	p.stack = append(p.stack, n)
+}
+
+// close closes the innermost open node of a type. Nodes opened inside of it are closed as
+// well, and reopened afterwards if reopen is true so overlapping formatting is kept
+func (p *parser) close(t NodeType, reopen bool) {
+	idx := p.find(t)
+	if idx == -1 {
+		return
+	}
+
+	inner := p.stack[idx+1:]
+	p.stack = p.stack[:idx]
+
+	if reopen {
+		for _, n := range inner {
+			p.open(n.Type)
+		}
+	}
+}
+
+// parseCode adds text to the open code node until its closing tag and returns the new position
+func (p *parser) parseCode(markup string, i int) int {
+	top := p.top()
+	tag := "CODE"
+	if top.Type == InlineCodeNode {
+		tag = "CODE_IN"
+	}
+
+	// Find the closing tag (either toggled or explicitly closed)
+	end, length := len(markup), 0
+	for _, closing := range []string{"<" + tag + ">", "</" + tag + ">"} {
+		if j := strings.Index(markup[i:], closing); j != -1 && i+j < end {
+			end, length = i+j, len(closing)
+		}
+	}
+
+	top.Text += markup[i:end]
+	if top.Type == CodeBlockNode {
+		if m := languageRegex.FindStringSubmatch(top.Text); m != nil {
+			top.Language = m[1]
+			top.Text = top.Text[len(m[0]):]
+		}
+	}
+
+	p.stack = p.stack[:len(p.stack)-1]
+	return end + length
+}
+
+// parseLiteral adds escaped user content until its closing tag and returns the new position
+func (p *parser) parseLiteral(markup string, i int) int {
+	var sb strings.Builder
+
+	for i < len(markup) {
+		if strings.HasPrefix(markup[i:], "</"+literalTag+">") {
+			i += len(literalTag) + 3
+			break
+		}
+		if markup[i] == '\\' && i+1 < len(markup) && (markup[i+1] == '\\' || markup[i+1] == '<') {
+			sb.WriteByte(markup[i+1])
+			i += 2
+			continue
+		}
+
+		sb.WriteByte(markup[i])
+		i++
+	}
+
+	if sb.Len() > 0 {
+		top := p.top()
+		top.Children = append(top.Children, &Node{Type: TextNode, Text: sb.String(), Literal: true})
+	}
+	return i
+}
+
+// prune removes formatting nodes with no content
+func prune(n *Node) {
+	children := n.Children[:0]
+	for _, c := range n.Children {
+		prune(c)
+		if c.Type != TextNode && c.Type != InlineCodeNode && c.Type != CodeBlockNode && len(c.Children) == 0 {
+			continue
+		}
+		children = append(children, c)
+	}
+	n.Children = children
+}
+
+// safeIndex returns a value from a list of regex matches or an empty string
+func safeIndex(m []string, i int) string {
+	if i < len(m) {
+		return m[i]
+	}
+	return ""
+}
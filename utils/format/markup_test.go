@@ -0,0 +1,112 @@
+package format_test
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethanbaker/horus/utils/format"
+	"github.com/stretchr/testify/assert"
+)
+
+// The path of the golden file holding expected output for each test and target
+const EXPECTED_PATH = "./testing/expected.json"
+
+// Rewrite the golden file with the current output instead of comparing against it
+var update = flag.Bool("update", false, "update the golden file")
+
+// All targets that are rendered in each test
+var TARGETS = []format.Target{format.Discord, format.CommonMark, format.HTML, format.ANSI, format.Telegram, format.Plain}
+
+// test type holds a given test and test name to find and match expected output
+type test struct {
+	name   string // The name of the test
+	markup string // The markup being rendered in the test
+}
+
+// Compile a list of all tests
+var TESTS = []test{
+	{name: "Plain text", markup: "Hello, world!"},
+	{name: "Toggled tags", markup: "<STRONG>bold<STRONG> <EM>italic<EM> <INS>underline<INS> <DEL>strike<DEL> <SPOILER>hidden<SPOILER>"},
+	{name: "Explicitly closed tags", markup: "<STRONG>bold</STRONG> and <EM>italic</EM>"},
+	{name: "Nested tags", markup: "<STRONG>bold <EM>both<EM> bold<STRONG>"},
+	{name: "Overlapping tags", markup: "<STRONG>a <EM>b<STRONG> c<EM>"},
+	{name: "Unclosed tag", markup: "<STRONG>runs to the end"},
+	{name: "Unknown tags", markup: "a <div> b </STRONG> 1 < 2"},
+	{name: "Inline code", markup: "Run <CODE_IN>echo <STRONG>x</STRONG> `y`<CODE_IN> now"},
+	{name: "Code block", markup: "<CODE>go\nfmt.Println(\"<hi>\")\n<CODE>"},
+	{name: "Line quote", markup: "<BLOCKQUOTE_IN>quoted <STRONG>line\nnot quoted"},
+	{name: "Block quote", markup: "Intro\n<BLOCKQUOTE>first\nsecond"},
+	{name: "Escaped user content", markup: "Profile: " + format.Escape("my_pass*word <STRONG> \\ (1.0)!") + " saved"},
+	{name: "Markdown passthrough", markup: "Some **markdown** from the model & <EM>tags<EM>"},
+	{name: "Escaped tag", markup: `\<STRONG> is literal`},
+	{name: "Control characters", markup: "bell\x07 and \x1b[31mred"},
+	{name: "C1 control characters", markup: "csi\u009b2J and del\x7f"},
+}
+
+func TestRender(t *testing.T) {
+	assert := assert.New(t)
+
+	expected := map[string]map[format.Target]string{}
+	if !*update {
+		data, err := os.ReadFile(EXPECTED_PATH)
+		assert.Nil(err)
+		assert.Nil(json.Unmarshal(data, &expected))
+	}
+
+	got := map[string]map[format.Target]string{}
+	for _, test := range TESTS {
+		got[test.name] = map[format.Target]string{}
+		for _, target := range TARGETS {
+			got[test.name][target] = format.Render(test.markup, target)
+			if !*update {
+				assert.Equal(expected[test.name][target], got[test.name][target], "%v (%v)", test.name, target)
+			}
+		}
+	}
+
+	if *update {
+		var sb strings.Builder
+		encoder := json.NewEncoder(&sb)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "    ")
+		assert.Nil(encoder.Encode(got))
+		assert.Nil(os.WriteFile(EXPECTED_PATH, []byte(sb.String()), 0644))
+	}
+}
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	root := format.Parse("<STRONG>a <EM>b<STRONG> c<EM>")
+	assert.Equal(format.DocumentNode, root.Type)
+	assert.Equal(2, len(root.Children))
+
+	// The emphasis is closed with the strong text and reopened after it
+	strong := root.Children[0]
+	assert.Equal(format.StrongNode, strong.Type)
+	assert.Equal(format.EmphasisNode, strong.Children[1].Type)
+	assert.Equal(format.EmphasisNode, root.Children[1].Type)
+	assert.Equal(" c", root.Children[1].Children[0].Text)
+
+	// Code blocks keep their language and raw content
+	code := format.Parse("<CODE>go\n<STRONG>x<CODE>").Children[0]
+	assert.Equal(format.CodeBlockNode, code.Type)
+	assert.Equal("go", code.Language)
+	assert.Equal("<STRONG>x", code.Text)
+
+	// Escaped content is literal text
+	text := format.Parse(format.Escape("<EM>\\")).Children[0]
+	assert.Equal(format.TextNode, text.Type)
+	assert.True(text.Literal)
+	assert.Equal("<EM>\\", text.Text)
+}
+
+func TestFormatDiscord(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("**bold** ```\ncode\n```", format.FormatDiscord("<STRONG>bold<STRONG> <CODE>\ncode\n<CODE>"))
+	assert.Equal("Intro\n>>> quoted\ntext", format.FormatDiscord("Intro\n<BLOCKQUOTE>quoted\ntext"))
+}
@@ -0,0 +1,123 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethanbaker/horus/utils/types"
+)
+
+// RenderText renders an output's message, blocks and actions as a single Horus markup string.
+// This is the fallback for implementations that cannot render blocks natively
+func RenderText(o *types.Output) string {
+	parts := []string{}
+	if o.Message != "" {
+		parts = append(parts, o.Message)
+	}
+
+	for _, b := range o.Blocks {
+		if text := RenderBlock(b); text != "" {
+			parts = append(parts, text)
+		}
+	}
+
+	if len(o.Actions) > 0 {
+		labels := make([]string, len(o.Actions))
+		for i, a := range o.Actions {
+			labels[i] = a.Label
+		}
+		parts = append(parts, fmt.Sprintf("<EM>Reply with: %v<EM>", strings.Join(labels, " / ")))
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+// RenderBlock renders a single block as Horus markup
+func RenderBlock(b types.Block) string {
+	lines := []string{}
+	if b.Title != "" {
+		lines = append(lines, "<STRONG>"+b.Title+"<STRONG>")
+	}
+
+	switch b.Type {
+	case types.TextBlock:
+		lines = append(lines, b.Text)
+
+	case types.CardBlock:
+		for _, f := range b.Fields {
+			lines = append(lines, fmt.Sprintf("<STRONG>%v<STRONG>: %v", f.Name, f.Value))
+		}
+
+	case types.ListBlock:
+		for i, item := range b.Items {
+			if b.Ordered {
+				lines = append(lines, fmt.Sprintf("%v. %v", i+1, item))
+			} else {
+				lines = append(lines, "• "+item)
+			}
+		}
+
+	case types.TableBlock:
+		if len(b.Columns) > 0 || len(b.Rows) > 0 {
+			lines = append(lines, "<CODE>\n"+RenderTable(b.Columns, b.Rows)+"\n<CODE>")
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// RenderTable renders a table as aligned plain text columns
+func RenderTable(columns []string, rows [][]string) string {
+	widths := ColumnWidths(columns, rows)
+
+	// Write a single padded line
+	line := func(cells []string) string {
+		padded := make([]string, len(widths))
+		for i := range widths {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			padded[i] = cell + strings.Repeat(" ", widths[i]-len([]rune(cell)))
+		}
+		return strings.TrimRight(strings.Join(padded, "  "), " ")
+	}
+
+	separators := make([]string, len(widths))
+	for i, w := range widths {
+		separators[i] = strings.Repeat("-", w)
+	}
+
+	// Tables without column names only show their rows
+	lines := []string{}
+	if len(columns) > 0 {
+		lines = append(lines, line(columns), line(separators))
+	}
+	for _, row := range rows {
+		lines = append(lines, line(row))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ColumnWidths finds the width of each column in a table, in runes. Rows can have fewer or more
+// cells than there are columns, and extra cells get columns without a name
+func ColumnWidths(columns []string, rows [][]string) []int {
+	widths := make([]int, len(columns))
+	for i, c := range columns {
+		widths[i] = len([]rune(c))
+	}
+
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if len([]rune(cell)) > widths[i] {
+				widths[i] = len([]rune(cell))
+			}
+		}
+	}
+
+	return widths
+}
@@ -0,0 +1,121 @@
+package format_test
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethanbaker/horus/utils/format"
+	"github.com/ethanbaker/horus/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// The path of the golden file holding expected output for each output test
+const OUTPUT_EXPECTED_PATH = "./testing/output.json"
+
+// outputTest holds an output and test name to find and match expected output
+type outputTest struct {
+	name   string        // The name of the test
+	output *types.Output // The output being rendered in the test
+}
+
+// Compile a list of all output tests
+var OUTPUT_TESTS = []outputTest{
+	{name: "Message only", output: &types.Output{Message: "Hello, <STRONG>world<STRONG>!"}},
+	{name: "Text block", output: &types.Output{Blocks: []types.Block{
+		{Type: types.TextBlock, Title: "Note", Text: "Some <EM>text<EM>"},
+	}}},
+	{name: "Card block", output: &types.Output{Message: "Entry found", Blocks: []types.Block{
+		{Type: types.CardBlock, Title: "github", Fields: []types.Field{
+			{Name: "Username", Value: "horus", Inline: true},
+			{Name: "URL", Value: "https://github.com"},
+		}},
+	}}},
+	{name: "Lists", output: &types.Output{Blocks: []types.Block{
+		{Type: types.ListBlock, Title: "Steps", Items: []string{"First", "Second"}, Ordered: true},
+		{Type: types.ListBlock, Items: []string{"Apples", "Pears"}},
+	}}},
+	{name: "Table", output: &types.Output{Blocks: []types.Block{
+		{Type: types.TableBlock, Title: "Forecast", Columns: []string{"Day", "High", "Low"}, Rows: [][]string{
+			{"Monday", "21°", "12°"},
+			{"Tuesday", "19°", "11°"},
+		}},
+	}}},
+	{name: "Table with uneven rows", output: &types.Output{Blocks: []types.Block{
+		{Type: types.TableBlock, Columns: []string{"Name", "Value"}, Rows: [][]string{
+			{"short"},
+			{"a", "b", "extra cell"},
+			{},
+			{"éèê", "日本"},
+		}},
+	}}},
+	{name: "Table without columns", output: &types.Output{Blocks: []types.Block{
+		{Type: types.TableBlock, Rows: [][]string{{"a", "b"}, {"c"}}},
+	}}},
+	{name: "Empty blocks", output: &types.Output{Message: "Nothing to show", Blocks: []types.Block{
+		{Type: types.TextBlock},
+		{Type: types.CardBlock},
+		{Type: types.ListBlock},
+		{Type: types.TableBlock},
+		{Type: types.TableBlock, Title: "Empty table"},
+	}}},
+	{name: "Actions", output: &types.Output{Message: "Should I go ahead?", Actions: types.ConfirmActions}},
+	{name: "Many actions", output: &types.Output{Message: "Pick a day", Actions: []types.Action{
+		{Label: "Mon", Value: "monday"}, {Label: "Tue", Value: "tuesday"}, {Label: "Wed", Value: "wednesday"},
+		{Label: "Thu", Value: "thursday"}, {Label: "Fri", Value: "friday"}, {Label: "Sat", Value: "saturday"},
+	}}},
+	{name: "Form", output: &types.Output{
+		Message: "What should the entry be called?",
+		Actions: []types.Action{{Label: "Cancel", Value: "cancel", Style: types.DangerAction}},
+		Form: &types.Form{ID: "entry", Title: "New entry", Fields: []types.FormField{
+			{Name: "title", Label: "Title", Required: true},
+			{Name: "password", Label: "Password", Secret: true},
+		}},
+	}},
+	{name: "Everything", output: &types.Output{
+		Message: "Here is the summary",
+		Blocks: []types.Block{
+			{Type: types.TextBlock, Text: "Intro"},
+			{Type: types.TableBlock, Columns: []string{"A"}, Rows: [][]string{{"1"}}},
+		},
+		Actions: []types.Action{{Label: "More", Value: "more", Style: types.PrimaryAction}},
+	}},
+}
+
+func TestRenderText(t *testing.T) {
+	assert := assert.New(t)
+
+	expected := map[string]string{}
+	if !*update {
+		data, err := os.ReadFile(OUTPUT_EXPECTED_PATH)
+		assert.Nil(err)
+		assert.Nil(json.Unmarshal(data, &expected))
+	}
+
+	got := map[string]string{}
+	for _, test := range OUTPUT_TESTS {
+		got[test.name] = format.RenderText(test.output)
+		if !*update {
+			assert.Equal(expected[test.name], got[test.name], test.name)
+		}
+	}
+
+	if *update {
+		var sb strings.Builder
+		encoder := json.NewEncoder(&sb)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "    ")
+		assert.Nil(encoder.Encode(got))
+		assert.Nil(os.WriteFile(OUTPUT_EXPECTED_PATH, []byte(sb.String()), 0644))
+	}
+}
+
+func TestColumnWidths(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]int{4, 5}, format.ColumnWidths([]string{"Name", "Value"}, nil))
+	assert.Equal([]int{5, 1, 10}, format.ColumnWidths([]string{"Name", ""}, [][]string{{"short"}, {"a", "b", "extra cell"}}))
+	assert.Equal([]int{3, 2}, format.ColumnWidths(nil, [][]string{{"éèê", "日本"}}))
+	assert.Empty(format.ColumnWidths(nil, nil))
+}
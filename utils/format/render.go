@@ -0,0 +1,304 @@
+package format
+
+import (
+	"html"
+	"strings"
+)
+
+// Target represents an output format that markup can be rendered to
+type Target string
+
+const (
+	Discord    Target = "discord"    // Discord markdown
+	CommonMark Target = "commonmark" // CommonMark (with inline HTML for formatting markdown lacks)
+	HTML       Target = "html"       // HTML fragments
+	ANSI       Target = "ansi"       // Terminal text with ANSI escape codes
+	Telegram   Target = "telegram"   // Telegram MarkdownV2
+	Plain      Target = "plain"      // Plain text with all formatting removed
+)
+
+// Render parses Horus markup and renders it for a target
+func Render(markup string, target Target) string {
+	return Parse(markup).Render(target)
+}
+
+// Render renders a markup tree for a target. Unknown targets are rendered as plain text
+func (n *Node) Render(target Target) string {
+	r, ok := renderers[target]
+	if !ok {
+		r = renderers[Plain]
+	}
+
+	var sb strings.Builder
+	r.render(&sb, n, nil, true)
+	return sb.String()
+}
+
+/* ---- RENDERERS ---- */
+
+// renderer describes how each kind of node is written for a target
+type renderer struct {
+	wrap       map[NodeType][2]string                             // Strings written around inline formatting
+	text       func(text string, literal bool) string             // Escapes text
+	inlineCode func(code string) string                           // Writes inline code
+	codeBlock  func(language string, code string) string          // Writes a code block
+	quote      func(content string, block bool, last bool) string // Writes a quote around rendered content
+
+	// styles are written when formatting starts on targets where formatting cannot be closed
+	// individually (such as ANSI). A reset is written when formatting ends, followed by the
+	// styles that are still active
+	styles map[NodeType]string
+	reset  string
+}
+
+// render writes a node and its children. Active holds the styles of enclosing nodes and last
+// is whether the node is the final one in the message
+func (r renderer) render(sb *strings.Builder, n *Node, active []string, last bool) {
+	switch n.Type {
+	case TextNode:
+		sb.WriteString(r.text(n.Text, n.Literal))
+
+	case InlineCodeNode:
+		sb.WriteString(r.inlineCode(n.Text))
+
+	case CodeBlockNode:
+		sb.WriteString(r.codeBlock(n.Language, n.Text))
+
+	case QuoteNode, BlockQuoteNode:
+		var inner strings.Builder
+		r.renderChildren(&inner, n, active, last)
+		sb.WriteString(r.quote(inner.String(), n.Type == BlockQuoteNode, last))
+
+	case DocumentNode:
+		r.renderChildren(sb, n, active, last)
+
+	default:
+		if style, ok := r.styles[n.Type]; ok {
+			active = append(active, style)
+			sb.WriteString(style)
+			r.renderChildren(sb, n, active, last)
+			sb.WriteString(r.reset + strings.Join(active[:len(active)-1], ""))
+			return
+		}
+
+		wrap := r.wrap[n.Type]
+		sb.WriteString(wrap[0])
+		r.renderChildren(sb, n, active, last)
+		sb.WriteString(wrap[1])
+	}
+}
+
+// renderChildren writes the children of a node
+func (r renderer) renderChildren(sb *strings.Builder, n *Node, active []string, last bool) {
+	for i, c := range n.Children {
+		r.render(sb, c, active, last && i == len(n.Children)-1)
+	}
+}
+
+// Renderers for each target
+var renderers = map[Target]renderer{
+	Discord: {
+		wrap: map[NodeType][2]string{
+			StrongNode:        {"**", "**"},
+			EmphasisNode:      {"*", "*"},
+			UnderlineNode:     {"__", "__"},
+			StrikethroughNode: {"~~", "~~"},
+			SpoilerNode:       {"||", "||"},
+		},
+		text: func(text string, literal bool) string {
+			if !literal {
+				return text
+			}
+			return escapeChars(text, "\\*_~|`>#[]")
+		},
+		inlineCode: backtickCode,
+		codeBlock:  fencedCode(false),
+		quote: func(content string, block bool, last bool) string {
+			// Discord's block quotes always run to the end of the message
+			if block && last {
+				return ">>> " + content
+			}
+			return prefixLines(content, "> ")
+		},
+	},
+
+	CommonMark: {
+		wrap: map[NodeType][2]string{
+			StrongNode:        {"**", "**"},
+			EmphasisNode:      {"*", "*"},
+			UnderlineNode:     {"<ins>", "</ins>"},
+			StrikethroughNode: {"<del>", "</del>"},
+			SpoilerNode:       {`<span class="spoiler">`, "</span>"},
+		},
+		text: func(text string, literal bool) string {
+			if !literal {
+				return text
+			}
+			return escapeChars(text, "\\`*_{}[]()<>#+-.!|~&")
+		},
+		inlineCode: backtickCode,
+		codeBlock:  fencedCode(true),
+		quote: func(content string, block bool, last bool) string {
+			return "\n" + prefixLines(strings.Trim(content, "\n"), "> ") + "\n"
+		},
+	},
+
+	HTML: {
+		wrap: map[NodeType][2]string{
+			StrongNode:        {"<strong>", "</strong>"},
+			EmphasisNode:      {"<em>", "</em>"},
+			UnderlineNode:     {"<ins>", "</ins>"},
+			StrikethroughNode: {"<del>", "</del>"},
+			SpoilerNode:       {`<span class="spoiler">`, "</span>"},
+		},
+		text: func(text string, literal bool) string {
+			return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>\n")
+		},
+		inlineCode: func(code string) string {
+			return "<code>" + html.EscapeString(code) + "</code>"
+		},
+		codeBlock: func(language string, code string) string {
+			class := ""
+			if language != "" {
+				class = ` class="language-` + html.EscapeString(language) + `"`
+			}
+			return "<pre><code" + class + ">" + html.EscapeString(strings.Trim(code, "\n")) + "</code></pre>"
+		},
+		quote: func(content string, block bool, last bool) string {
+			return "<blockquote>" + content + "</blockquote>"
+		},
+	},
+
+	ANSI: {
+		styles: map[NodeType]string{
+			StrongNode:        "\x1b[1m",
+			EmphasisNode:      "\x1b[3m",
+			UnderlineNode:     "\x1b[4m",
+			StrikethroughNode: "\x1b[9m",
+			SpoilerNode:       "\x1b[7m",
+		},
+		reset: "\x1b[0m",
+		text: func(text string, literal bool) string {
+			return StripControl(text)
+		},
+		inlineCode: func(code string) string {
+			return "\x1b[36m" + StripControl(code) + "\x1b[39m"
+		},
+		codeBlock: func(language string, code string) string {
+			return "\x1b[36m" + prefixLines(StripControl(strings.Trim(code, "\n")), "  ") + "\x1b[39m"
+		},
+		quote: func(content string, block bool, last bool) string {
+			return prefixLines(content, "\x1b[2m│\x1b[22m ")
+		},
+	},
+
+	Telegram: {
+		wrap: map[NodeType][2]string{
+			StrongNode:        {"*", "*"},
+			EmphasisNode:      {"_", "_"},
+			UnderlineNode:     {"__", "__"},
+			StrikethroughNode: {"~", "~"},
+			SpoilerNode:       {"||", "||"},
+		},
+		text: func(text string, literal bool) string {
+			// MarkdownV2 requires every special character to be escaped
+			return escapeChars(text, "\\_*[]()~`>#+-=|{}.!")
+		},
+		inlineCode: func(code string) string {
+			return "`" + escapeChars(code, "\\`") + "`"
+		},
+		codeBlock: func(language string, code string) string {
+			return "```" + language + "\n" + escapeChars(strings.Trim(code, "\n"), "\\`") + "\n```"
+		},
+		quote: func(content string, block bool, last bool) string {
+			return prefixLines(content, ">")
+		},
+	},
+
+	Plain: {
+		text: func(text string, literal bool) string {
+			return text
+		},
+		inlineCode: func(code string) string {
+			return code
+		},
+		codeBlock: func(language string, code string) string {
+			return strings.Trim(code, "\n")
+		},
+		quote: func(content string, block bool, last bool) string {
+			return prefixLines(content, "> ")
+		},
+	},
+}
+
+/* ---- HELPERS ---- */
+
+// escapeChars escapes every character in chars with a backslash
+func escapeChars(text string, chars string) string {
+	var sb strings.Builder
+	for _, c := range text {
+		if strings.ContainsRune(chars, c) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(c)
+	}
+
+	return sb.String()
+}
+
+// backtickCode writes inline code with a fence long enough that backticks in the code are kept
+func backtickCode(code string) string {
+	fence := "`"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
+// fencedCode returns a function that writes a fenced code block. Standalone fences are placed on
+// their own lines, as CommonMark requires
+func fencedCode(standalone bool) func(language string, code string) string {
+	return func(language string, code string) string {
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+
+		if !standalone {
+			if language != "" {
+				language += "\n"
+			}
+			return fence + language + code + fence
+		}
+		return "\n" + fence + language + "\n" + strings.Trim(code, "\n") + "\n" + fence + "\n"
+	}
+}
+
+// prefixLines adds a prefix to every line of text
+func prefixLines(text string, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		// Keep a trailing newline outside of the prefixed text
+		if i == len(lines)-1 && l == "" && i > 0 {
+			continue
+		}
+		lines[i] = prefix + l
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// StripControl removes control characters other than newlines and tabs, so text cannot inject
+// terminal escape codes
+func StripControl(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\n' && r != '\t' || r >= 0x7f && r <= 0x9f {
+			return -1
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,130 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Reflect derives a definition from the type of a Go value. Structs become objects whose
+// properties are named after their json tags. The following struct tags are also used:
+//
+//	description:"The city to get the weather for"  // The property's description
+//	enum:"celsius,fahrenheit"                       // A comma separated list of allowed values
+//	required:"true"                                 // The property must be present
+func Reflect(v any) (Definition, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return Definition{}, fmt.Errorf("cannot reflect the definition of a nil value")
+	}
+
+	return ReflectType(t)
+}
+
+// ReflectType derives a definition from a Go type
+func ReflectType(t reflect.Type) (Definition, error) {
+	return reflectType(t, map[reflect.Type]bool{})
+}
+
+// reflectType recursively derives a definition, tracking visited structs to catch cycles
+func reflectType(t reflect.Type, visiting map[reflect.Type]bool) (Definition, error) {
+	// Pointers are described by the type they point to
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// Times are marshaled as strings
+	if t == reflect.TypeOf(time.Time{}) {
+		return Definition{Type: String}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return Definition{Type: String}, nil
+
+	case reflect.Bool:
+		return Definition{Type: Boolean}, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Definition{Type: Integer}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return Definition{Type: Number}, nil
+
+	case reflect.Slice, reflect.Array:
+		items, err := reflectType(t.Elem(), visiting)
+		if err != nil {
+			return Definition{}, err
+		}
+		return Definition{Type: Array, Items: &items}, nil
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return Definition{}, fmt.Errorf("map keys must be strings, got %v", t.Key())
+		}
+		return Definition{Type: Object}, nil
+
+	case reflect.Interface:
+		return Definition{}, nil
+
+	case reflect.Struct:
+		return reflectStruct(t, visiting)
+	}
+
+	return Definition{}, fmt.Errorf("cannot derive a definition for type %v", t)
+}
+
+// reflectStruct derives an object definition from a struct's fields
+func reflectStruct(t reflect.Type, visiting map[reflect.Type]bool) (Definition, error) {
+	if visiting[t] {
+		return Definition{}, fmt.Errorf("cannot derive a definition for recursive type %v", t)
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	def := Definition{
+		Type:       Object,
+		Properties: map[string]Definition{},
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		// Find the name of the property from its json tag
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		prop, err := reflectType(field.Type, visiting)
+		if err != nil {
+			return Definition{}, fmt.Errorf("field %v: %w", field.Name, err)
+		}
+
+		// Add information from the field's tags
+		prop.Description = field.Tag.Get("description")
+		if enum := field.Tag.Get("enum"); enum != "" {
+			for _, e := range strings.Split(enum, ",") {
+				prop.Enum = append(prop.Enum, strings.TrimSpace(e))
+			}
+		}
+		if field.Tag.Get("required") == "true" {
+			def.Required = append(def.Required, name)
+		}
+
+		def.Properties[name] = prop
+	}
+
+	return def, nil
+}
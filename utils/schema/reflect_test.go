@@ -0,0 +1,77 @@
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/ethanbaker/horus/utils/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// Types used to test reflection
+type address struct {
+	City    string `json:"city" description:"The city" required:"true"`
+	Country string `json:"country,omitempty"`
+}
+
+type user struct {
+	Name     string    `json:"name" required:"true"`
+	Age      int       `json:"age"`
+	Height   float64   `json:"height"`
+	Admin    bool      `json:"admin"`
+	Unit     string    `json:"unit" enum:"celsius, fahrenheit"`
+	Tags     []string  `json:"tags"`
+	Address  *address  `json:"address"`
+	Ignored  string    `json:"-"`
+	internal string    // Unexported fields are skipped
+	Friends  []address `json:"friends"`
+}
+
+type node struct {
+	Next *node `json:"next"`
+}
+
+func TestReflect(t *testing.T) {
+	assert := assert.New(t)
+
+	got, err := schema.Reflect(user{})
+	assert.Nil(err)
+
+	addressDef := schema.Definition{
+		Type: schema.Object,
+		Properties: map[string]schema.Definition{
+			"city":    {Type: schema.String, Description: "The city"},
+			"country": {Type: schema.String},
+		},
+		Required: []string{"city"},
+	}
+
+	want := schema.Definition{
+		Type: schema.Object,
+		Properties: map[string]schema.Definition{
+			"name":    {Type: schema.String},
+			"age":     {Type: schema.Integer},
+			"height":  {Type: schema.Number},
+			"admin":   {Type: schema.Boolean},
+			"unit":    {Type: schema.String, Enum: []string{"celsius", "fahrenheit"}},
+			"tags":    {Type: schema.Array, Items: &schema.Definition{Type: schema.String}},
+			"address": addressDef,
+			"friends": {Type: schema.Array, Items: &addressDef},
+		},
+		Required: []string{"name"},
+	}
+
+	assert.Equal(want, got)
+}
+
+func TestReflectErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := schema.Reflect(nil)
+	assert.NotNil(err)
+
+	_, err = schema.Reflect(node{})
+	assert.NotNil(err)
+
+	_, err = schema.Reflect(map[int]string{})
+	assert.NotNil(err)
+}
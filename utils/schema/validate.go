@@ -0,0 +1,199 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ValidationError describes a single value that does not match its definition
+type ValidationError struct {
+	Path    string `json:"path"`    // The path to the invalid value (ex: user.address.city)
+	Message string `json:"message"` // Why the value is invalid
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%v: %v", e.Path, e.Message)
+}
+
+// ValidationErrors is a list of every invalid value found during validation
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i := range e {
+		messages[i] = e[i].Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ValidateJSON validates a raw JSON string against the definition. An empty string is treated
+// as an empty object
+func (d Definition) ValidateJSON(raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		raw = "{}"
+	}
+
+	// Keep numbers intact so integers can be distinguished from floats
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return ValidationErrors{{Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+
+	return d.Validate(value)
+}
+
+// Validate validates a decoded JSON value against the definition's types, required properties
+// and enums. A nil error is returned if the value is valid, otherwise ValidationErrors is returned
+func (d Definition) Validate(value any) error {
+	errs := d.validate("", value)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validate recursively validates a value at a given path
+func (d Definition) validate(path string, value any) ValidationErrors {
+	errs := ValidationErrors{}
+
+	// Check the value's type
+	if d.Type != "" && !matchesType(d.Type, value) {
+		return append(errs, ValidationError{Path: path, Message: fmt.Sprintf("must be of type %v, got %v", d.Type, typeOf(value))})
+	}
+
+	// Check the value is in the enum
+	if len(d.Enum) > 0 {
+		found := false
+		for _, e := range d.Enum {
+			if fmt.Sprint(value) == e {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("must be one of [%v]", strings.Join(d.Enum, ", "))})
+		}
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		// Check required properties are present
+		for _, key := range d.Required {
+			if _, ok := val[key]; !ok {
+				errs = append(errs, ValidationError{Path: join(path, key), Message: "is required"})
+			}
+		}
+
+		// Check each defined property (in order so errors are stable)
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if def, ok := d.Properties[key]; ok {
+				errs = append(errs, def.validate(join(path, key), val[key])...)
+			}
+		}
+
+	case []any:
+		// Check each item in the array
+		if d.Items != nil {
+			for i, item := range val {
+				errs = append(errs, d.Items.validate(fmt.Sprintf("%v[%v]", path, i), item)...)
+			}
+		}
+	}
+
+	return errs
+}
+
+// matchesType returns true if a decoded JSON value matches a data type
+func matchesType(t DataType, value any) bool {
+	switch t {
+	case Object:
+		_, ok := value.(map[string]any)
+		return ok
+
+	case Array:
+		_, ok := value.([]any)
+		return ok
+
+	case String:
+		_, ok := value.(string)
+		return ok
+
+	case Boolean:
+		_, ok := value.(bool)
+		return ok
+
+	case Null:
+		return value == nil
+
+	case Number:
+		_, ok := toFloat(value)
+		return ok
+
+	case Integer:
+		f, ok := toFloat(value)
+		return ok && f == math.Trunc(f)
+	}
+
+	// Unknown types are not validated
+	return true
+}
+
+// toFloat converts a decoded JSON number into a float
+func toFloat(value any) (float64, bool) {
+	switch val := value.(type) {
+	case json.Number:
+		f, err := val.Float64()
+		return f, err == nil
+	case float64:
+		return val, true
+	case int:
+		return float64(val), true
+	}
+
+	return 0, false
+}
+
+// typeOf returns the JSON type name of a decoded value
+func typeOf(value any) DataType {
+	switch value.(type) {
+	case map[string]any:
+		return Object
+	case []any:
+		return Array
+	case string:
+		return String
+	case bool:
+		return Boolean
+	case nil:
+		return Null
+	}
+
+	if f, ok := toFloat(value); ok && f == math.Trunc(f) {
+		return Integer
+	}
+	return Number
+}
+
+// join adds a key to a path
+func join(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,88 @@
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/ethanbaker/horus/utils/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// A definition similar to the ones modules register
+var weatherDefinition = schema.Definition{
+	Type: schema.Object,
+	Properties: map[string]schema.Definition{
+		"location": {
+			Type: schema.String,
+		},
+		"unit": {
+			Type: schema.String,
+			Enum: []string{"celsius", "fahrenheit"},
+		},
+		"days": {
+			Type: schema.Integer,
+		},
+		"tags": {
+			Type:  schema.Array,
+			Items: &schema.Definition{Type: schema.String},
+		},
+	},
+	Required: []string{"location"},
+}
+
+func TestValidateJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name string
+		raw  string
+		want schema.ValidationErrors
+	}{
+		{
+			name: "Test with valid arguments",
+			raw:  `{"location": "Raleigh", "unit": "celsius", "days": 3, "tags": ["a", "b"]}`,
+		},
+		{
+			name: "Test with extra properties",
+			raw:  `{"location": "Raleigh", "extra": true}`,
+		},
+		{
+			name: "Test with missing required property",
+			raw:  `{"unit": "celsius"}`,
+			want: schema.ValidationErrors{{Path: "location", Message: "is required"}},
+		},
+		{
+			name: "Test with empty arguments",
+			raw:  ``,
+			want: schema.ValidationErrors{{Path: "location", Message: "is required"}},
+		},
+		{
+			name: "Test with invalid enum",
+			raw:  `{"location": "Raleigh", "unit": "kelvin"}`,
+			want: schema.ValidationErrors{{Path: "unit", Message: "must be one of [celsius, fahrenheit]"}},
+		},
+		{
+			name: "Test with invalid types",
+			raw:  `{"location": 5, "days": 1.5, "tags": ["a", 2]}`,
+			want: schema.ValidationErrors{
+				{Path: "days", Message: "must be of type integer, got number"},
+				{Path: "location", Message: "must be of type string, got integer"},
+				{Path: "tags[1]", Message: "must be of type string, got integer"},
+			},
+		},
+		{
+			name: "Test with invalid JSON",
+			raw:  `{"location": `,
+			want: schema.ValidationErrors{{Message: "invalid JSON: unexpected EOF"}},
+		},
+	}
+
+	for _, test := range tests {
+		err := weatherDefinition.ValidateJSON(test.raw)
+		if test.want == nil {
+			assert.Nil(err, test.name)
+			continue
+		}
+
+		assert.Equal(test.want, err, test.name)
+	}
+}
@@ -0,0 +1,112 @@
+package types
+
+import "time"
+
+/* ---- API TYPES ---- */
+
+// APIBot describes a bot served by the Horus API
+type APIBot struct {
+	Name        string `json:"name"`        // The name of the bot
+	Permissions byte   `json:"permissions"` // The bot's permissions
+}
+
+// APIConversation describes a conversation served by the Horus API
+type APIConversation struct {
+	Key        string     `json:"key"`                   // The conversation's unique key
+	Messages   int        `json:"messages"`              // The number of messages in the conversation
+	CreatedAt  time.Time  `json:"created_at"`            // When the conversation was created
+	ArchivedAt *time.Time `json:"archived_at,omitempty"` // When the conversation was archived, if it has been
+}
+
+// APIMessage is a message in a conversation's history
+type APIMessage struct {
+	Index     uint          `json:"index"`                // The position of the message in the conversation
+	Role      string        `json:"role"`                 // Who sent the message (system, user, assistant or tool)
+	Name      string        `json:"name,omitempty"`       // The name of the function for tool messages
+	Content   string        `json:"content"`              // The message's content
+	ToolCalls []APIToolCall `json:"tool_calls,omitempty"` // The tools the assistant called in this message
+	CreatedAt time.Time     `json:"created_at"`           // When the message was sent
+}
+
+// APIToolCall is a tool called by the assistant
+type APIToolCall struct {
+	Name      string `json:"name"`      // The name of the function
+	Arguments string `json:"arguments"` // The function's arguments as JSON
+}
+
+// APIConversationRequest is the body used to create a conversation
+type APIConversationRequest struct {
+	Key  string `json:"key"`            // The key of the new conversation
+	Fork string `json:"fork,omitempty"` // The conversation to copy messages from, if any
+}
+
+// APIConversationUpdate is the body used to rename a conversation
+type APIConversationUpdate struct {
+	Key string `json:"key"` // The conversation's new key
+}
+
+// APIMessageRequest is the body used to send a message to a conversation
+type APIMessageRequest struct {
+	Message     string `json:"message"`               // The user's message
+	Permissions byte   `json:"permissions,omitempty"` // The permissions of the message (defaults to all)
+	Caller      string `json:"caller,omitempty"`      // Who sent the message
+
+	// The stream key (see /api/stream) that the reply is streamed to as it is generated. The
+	// reply is still returned in full when it is complete
+	Stream OutreachMethod `json:"stream,omitempty"`
+}
+
+// APIOutput is a bot's response to a message sent through the API. Files are stored by the
+// server and downloaded separately
+type APIOutput struct {
+	Message string    `json:"message"`
+	Data    any       `json:"data,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	Blocks  []Block   `json:"blocks,omitempty"`
+	Files   []APIFile `json:"files,omitempty"`
+	Actions []Action  `json:"actions,omitempty"`
+}
+
+// APIFile describes a file output that can be downloaded from the API
+type APIFile struct {
+	ID          string `json:"id"`                     // The ID used to download the file
+	Filename    string `json:"filename"`               // The file's name
+	ContentType string `json:"content_type,omitempty"` // The file's MIME type
+	Size        int    `json:"size"`                   // The size of the file in bytes
+	URL         string `json:"url"`                    // The path the file can be downloaded from
+}
+
+// APIError is returned by the API when a request fails
+type APIError struct {
+	Error string `json:"error"` // A description of the error
+}
+
+// APIOutreach is an outreach message streamed to implementations
+type APIOutreach struct {
+	Method  OutreachMethod `json:"method"`  // The outreach method the message was sent to
+	Message string         `json:"message"` // The message's content
+	SentAt  time.Time      `json:"sent_at"` // When the message was sent
+}
+
+/* ---- API STREAM EVENTS ---- */
+
+// The names of events sent on API streams
+const (
+	EVENT_OUTREACH = "outreach" // An outreach message (APIOutreach)
+	EVENT_TOKEN    = "token"    // A piece of a reply as it is generated (APIToken)
+	EVENT_REPLY    = "reply"    // A complete reply (APIReply)
+)
+
+// APIToken is a piece of a bot's reply, streamed as the model generates it
+type APIToken struct {
+	Bot          string `json:"bot"`          // The bot replying
+	Conversation string `json:"conversation"` // The conversation being replied to
+	Token        string `json:"token"`        // The generated text
+}
+
+// APIReply is a bot's complete reply to a streamed message
+type APIReply struct {
+	Bot          string    `json:"bot"`          // The bot that replied
+	Conversation string    `json:"conversation"` // The conversation replied to
+	Output       APIOutput `json:"output"`       // The reply
+}
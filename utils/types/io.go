@@ -11,8 +11,13 @@ import (
 type Input struct {
 	Message     string // The user's message in plaintext
 	Permissions byte   // The permissions this input send has
+	Caller      string // Who sent the input (ex: an implementation and user ID)
 	Data        any    // Any external program data from implementations
 
+	Attachments []Attachment // Files sent with the message (ex: a screenshot or a log file)
+
+	OnToken func(token string) // Called with each piece of the model's reply as it is generated (optional)
+
 	Parameters objx.Map // Function parameters given in a function call by the model
 }
 
@@ -38,6 +43,20 @@ type Output struct {
 	Message string `json:"message"` // The library's message in plaintext
 	Data    any    `json:"data"`    // Any external program data returned by the library
 	Error   error  `json:"error"`   // Any error present in finding the output
+
+	Blocks      []Block      `json:"blocks,omitempty"`      // Structured content shown after the message
+	Attachments []FileOutput `json:"attachments,omitempty"` // Files sent with the message
+	Actions     []Action     `json:"actions,omitempty"`     // Suggested quick replies
+	Form        *Form        `json:"form,omitempty"`        // Values that can be asked for all at once
+}
+
+// Confirmation is returned in an Output's data when a side-effecting tool is waiting for the
+// user to approve it. The next input sent to the conversation is treated as the user's decision
+type Confirmation struct {
+	Function  string         `json:"function"`  // The function waiting to be called
+	Module    string         `json:"module"`    // The module the function belongs to
+	Arguments map[string]any `json:"arguments"` // The call's arguments with secrets redacted
+	Summary   string         `json:"summary"`   // A summary of the call to show the user
 }
 
 /* ---- I/O INTERFACE TYPES ---- */
@@ -47,8 +66,17 @@ type DiscordInput struct {
 	Message *discordgo.MessageCreate
 }
 
+// Attachment is a file sent to the bot with an input
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"` // The file's MIME type (detected from its content if empty)
+	Content     []byte `json:"content"`
+	Size        int    `json:"size,omitempty"` // The file's size, for files too large to send their content
+}
+
 // Output data going to a local file
 type FileOutput struct {
-	Filename string
-	Content  []byte
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Content     []byte `json:"content"`
 }
@@ -0,0 +1,113 @@
+package types
+
+/* ---- RICH OUTPUT TYPES ---- */
+
+// BlockType represents the kind of content a block holds
+type BlockType string
+
+const (
+	TextBlock  BlockType = "text"  // A paragraph of Horus markup
+	CardBlock  BlockType = "card"  // A titled set of key/value fields
+	TableBlock BlockType = "table" // A table with named columns
+	ListBlock  BlockType = "list"  // An ordered or unordered list
+)
+
+// Block is a piece of structured content in an output. Implementations render each block type
+// natively where possible (ex: Discord embeds, terminal tables) and fall back to plain text
+type Block struct {
+	Type    BlockType  `json:"type"`              // The kind of content the block holds
+	Title   string     `json:"title,omitempty"`   // An optional title for the block
+	Text    string     `json:"text,omitempty"`    // The content of a text block
+	Fields  []Field    `json:"fields,omitempty"`  // The fields of a card block
+	Columns []string   `json:"columns,omitempty"` // The column names of a table block
+	Rows    [][]string `json:"rows,omitempty"`    // The rows of a table block
+	Items   []string   `json:"items,omitempty"`   // The items of a list block
+	Ordered bool       `json:"ordered,omitempty"` // Whether a list block is numbered
+}
+
+// Field is a key/value pair in a card block
+type Field struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"` // Whether the field can be shown next to other fields
+}
+
+// ActionStyle represents how an action should be emphasized
+type ActionStyle string
+
+const (
+	PrimaryAction   ActionStyle = "primary"
+	SecondaryAction ActionStyle = "secondary"
+	DangerAction    ActionStyle = "danger"
+)
+
+// Action is a suggested quick reply. Choosing an action sends its value to the conversation as
+// the user's next message
+type Action struct {
+	Label string      `json:"label"`           // The text shown to the user
+	Value string      `json:"value"`           // The message sent when the action is chosen
+	Style ActionStyle `json:"style,omitempty"` // How the action should be emphasized
+}
+
+// Form asks the user for several values at once. Implementations that can show forms (ex:
+// Discord modals) offer it alongside the message and send the answers back as the next input's
+// data (a FormResponse). The message should still ask for the first value so users of other
+// implementations can answer step by step. Forms should have at most 5 fields
+type Form struct {
+	ID     string      `json:"id"`     // Identifies the form in its response
+	Title  string      `json:"title"`  // The form's title
+	Fields []FormField `json:"fields"` // The values to ask for
+}
+
+// FormField is a value asked for in a form
+type FormField struct {
+	Name        string `json:"name"`                  // The key of the value in the response
+	Label       string `json:"label"`                 // The text shown to the user
+	Placeholder string `json:"placeholder,omitempty"` // An example value
+	Required    bool   `json:"required,omitempty"`    // Whether the value can be left empty
+	Multiline   bool   `json:"multiline,omitempty"`   // Whether the value can span multiple lines
+	Secret      bool   `json:"secret,omitempty"`      // Whether the value must never be shown back to the user
+}
+
+// FormResponse is the user's answers to a form. It is sent as an input's data, and the input's
+// message is left empty so the answers are never shown or added to a conversation
+type FormResponse struct {
+	ID     string            `json:"id"`     // The ID of the form answered
+	Values map[string]string `json:"values"` // The answers keyed by field name
+}
+
+// Text creates a text block
+func Text(text string) Block {
+	return Block{Type: TextBlock, Text: text}
+}
+
+// Card creates a card block
+func Card(title string, fields ...Field) Block {
+	return Block{Type: CardBlock, Title: title, Fields: fields}
+}
+
+// Table creates a table block
+func Table(title string, columns []string, rows [][]string) Block {
+	return Block{Type: TableBlock, Title: title, Columns: columns, Rows: rows}
+}
+
+// List creates a list block
+func List(title string, ordered bool, items ...string) Block {
+	return Block{Type: ListBlock, Title: title, Ordered: ordered, Items: items}
+}
+
+// ConfirmActions are the actions used to answer a yes or no question
+var ConfirmActions = []Action{
+	{Label: "Yes", Value: "yes", Style: PrimaryAction},
+	{Label: "No", Value: "no", Style: SecondaryAction},
+}
+
+// Files returns every file attached to the output, including a FileOutput stored in Data
+func (o *Output) Files() []FileOutput {
+	files := append([]FileOutput{}, o.Attachments...)
+	if file, ok := o.Data.(FileOutput); ok {
+		files = append(files, file)
+	}
+
+	return files
+}
@@ -34,6 +34,10 @@ type OutreachMethod string
 const (
 	Discord  OutreachMethod = "discord"
 	Telegram OutreachMethod = "telegram"
+	Matrix   OutreachMethod = "matrix"
+	Email    OutreachMethod = "email"
+	Terminal OutreachMethod = "terminal"
+	Web      OutreachMethod = "web"
 )
 
 /* ---- OUTREACH INPUT ---- */
@@ -1,7 +1,10 @@
 // validation validates given messages for a given intent
 package validation
 
-import "strings"
+import (
+	"strings"
+	"unicode"
+)
 
 // Validate an intent for confirmation
 func ValidateConfirmation(message string) bool {
@@ -14,6 +17,22 @@ func ValidateConfirmation(message string) bool {
 	return false
 }
 
+// Validate an intent for confirmation where the whole message must be an affirmative (ex: "yes"
+// or "Sure thing!"). This is used when a loose match could approve something by accident
+func ValidateStrictConfirmation(message string) bool {
+	message = strings.ToLower(strings.TrimFunc(message, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}))
+
+	for _, word := range yesWords {
+		if message == word {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Validate an intent for denial
 func ValidateDenial(message string) bool {
 	for _, word := range noWords {
@@ -0,0 +1,36 @@
+package validation_test
+
+import (
+	"testing"
+
+	"github.com/ethanbaker/horus/utils/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStrictConfirmation(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		message  string
+		expected bool
+	}{
+		// Affirmatives approve, ignoring case, spacing and punctuation
+		{"yes", true},
+		{"Yes!", true},
+		{"  YES.  ", true},
+		{"Sure thing!", true},
+		{"ok", true},
+
+		// Anything else denies, even if it contains an affirmative
+		{"yes but not now", false},
+		{"no", false},
+		{"", false},
+		{"!?", false},
+		{"yesterday", false},
+		{"not sure", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(test.expected, validation.ValidateStrictConfirmation(test.message), test.message)
+	}
+}